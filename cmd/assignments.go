@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseAssignments reads variable values written as "a=1 b=0" (commas are also accepted as separators)
+func parseAssignments(input string) (map[string]bool, error) {
+	result := map[string]bool{}
+	fields := strings.FieldsFunc(input, func(r rune) bool {
+		return r == ' ' || r == ',' || r == '\t'
+	})
+	for _, field := range fields {
+		name, value, ok := strings.Cut(field, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid assignment %q, expected name=0 or name=1", field)
+		}
+		switch value {
+		case "0":
+			result[name] = false
+		case "1":
+			result[name] = true
+		default:
+			return nil, fmt.Errorf("invalid value %q for %v, expected 0 or 1", value, name)
+		}
+	}
+	return result, nil
+}
+
+// cutTrailingAssignments splits "and(a, b) a=1 b=0" into the expression and the assignments that follow it
+func cutTrailingAssignments(input string) (string, string) {
+	fields := strings.Fields(input)
+	i := len(fields)
+	for i > 0 && isAssignment(fields[i-1]) {
+		i--
+	}
+	return strings.Join(fields[:i], " "), strings.Join(fields[i:], " ")
+}
+
+func isAssignment(field string) bool {
+	name, value, ok := strings.Cut(strings.TrimSuffix(field, ","), "=")
+	return ok && name != "" && (value == "0" || value == "1")
+}
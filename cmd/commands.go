@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/VladMinzatu/bool-calculator/evaluation"
+)
+
+// replCommand is a REPL command, entered as :name followed by its arguments
+type replCommand struct {
+	name    string
+	usage   string                                    // the arguments, in brackets if they are optional
+	help    string                                    // one line shown by :help
+	choices func(r *repl, previous []string) []string // completions of an argument, nil to complete expressions
+	run     func(r *repl, args string) error
+}
+
+// replCommands holds the commands by name. Features add their commands with registerCommand.
+var replCommands = map[string]replCommand{}
+
+func registerCommand(c replCommand) {
+	replCommands[c.name] = c
+}
+
+// runCommand runs a line starting with a colon
+func (r *repl) runCommand(input string) error {
+	name, args, _ := strings.Cut(strings.TrimPrefix(input, ":"), " ")
+	c, ok := replCommands[name]
+	if !ok {
+		return fmt.Errorf("unknown command :%s, :help lists the commands", name)
+	}
+	args = strings.TrimSpace(args)
+	if args == "" && c.usage != "" && !strings.HasPrefix(c.usage, "[") {
+		return fmt.Errorf("usage: :%s %s", c.name, c.usage)
+	}
+	return c.run(r, args)
+}
+
+func init() {
+	registerCommand(replCommand{
+		name:  "help",
+		usage: "[command]",
+		help:  "list the commands, or describe one",
+		run:   runHelp,
+	})
+	registerCommand(replCommand{
+		name: "gates",
+		help: "list the gates with their number of inputs and their signature",
+		run:  runGates,
+	})
+	registerCommand(replCommand{
+		name: "vars",
+		help: "list the inputs and wires of the last expression",
+		run:  runVars,
+	})
+	registerCommand(replCommand{
+		name: "outputs",
+		help: "list the outputs of the last expression",
+		run:  runOutputs,
+	})
+	registerCommand(replCommand{
+		name:  "format",
+		usage: "[text|json]",
+		help:  "print truth tables as text or JSON",
+		choices: func(r *repl, previous []string) []string {
+			return []string{"text", "json"}
+		},
+		run: runFormat,
+	})
+	registerCommand(replCommand{
+		name:  "order",
+		usage: "[a,b,c]",
+		help:  "put these inputs first in truth tables, or go back to the usual order",
+		run:   runOrder,
+	})
+	registerCommand(replCommand{
+		name: "time",
+		help: "turn printing the evaluation time of expressions on or off",
+		run: func(r *repl, args string) error {
+			r.timing = !r.timing
+			fmt.Printf("Timing %s\n", onOff(r.timing))
+			return nil
+		},
+	})
+	registerCommand(replCommand{
+		name: "clear",
+		help: "clear the screen",
+		run: func(r *repl, args string) error {
+			fmt.Print("\x1b[H\x1b[2J")
+			return nil
+		},
+	})
+}
+
+func runHelp(r *repl, args string) error {
+	names := []string{}
+	if args != "" {
+		name := strings.TrimPrefix(args, ":")
+		if _, ok := replCommands[name]; !ok {
+			return fmt.Errorf("unknown command :%s", name)
+		}
+		names = append(names, name)
+	} else {
+		for name := range replCommands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	width := 0
+	for _, name := range names {
+		width = max(width, len(commandSyntax(replCommands[name])))
+	}
+	for _, name := range names {
+		c := replCommands[name]
+		fmt.Printf("%-*s  %s\n", width, commandSyntax(c), c.help)
+	}
+	if args == "" {
+		fmt.Println("Other lines are evaluated as expressions, or as 'expr where a=1, c=0'. Tab completes names.")
+	}
+	return nil
+}
+
+func commandSyntax(c replCommand) string {
+	if c.usage == "" {
+		return ":" + c.name
+	}
+	return ":" + c.name + " " + c.usage
+}
+
+func runGates(r *repl, args string) error {
+	for _, name := range evaluation.Gates() {
+		signature, _ := evaluation.GateSignature(name)
+		inputs, _ := evaluation.GateInputs(name)
+		fmt.Printf("%-10s %3d %-6s  %s\n", name, inputs, plural(inputs, "input"), signature)
+	}
+	return nil
+}
+
+func runVars(r *repl, args string) error {
+	if r.last == nil {
+		return fmt.Errorf("no expression was evaluated yet")
+	}
+	if len(r.last.Variables) == 0 {
+		fmt.Println("The expression has no inputs")
+	} else {
+		fmt.Println("Inputs: " + strings.Join(r.last.Variables, " "))
+	}
+	if len(r.last.Wires) > 0 {
+		fmt.Println("Wires: " + strings.Join(r.last.Wires, " "))
+	}
+	return nil
+}
+
+func runOutputs(r *repl, args string) error {
+	if r.last == nil {
+		return fmt.Errorf("no expression was evaluated yet")
+	}
+	widths := r.last.OutputWidths
+	if widths == nil {
+		for range r.last.Outputs[0] {
+			widths = append(widths, 1)
+		}
+	}
+	fmt.Printf("%d %s\n", len(widths), plural(len(widths), "output"))
+	for i, width := range widths {
+		fmt.Printf("%d: %d %s\n", i, width, plural(width, "bit"))
+	}
+	return nil
+}
+
+func runFormat(r *repl, args string) error {
+	switch args {
+	case "":
+		fmt.Println("Truth tables are printed as " + r.format)
+	case "text", "json":
+		r.format = args
+	default:
+		return fmt.Errorf("unknown format %q, use text or json", args)
+	}
+	return nil
+}
+
+func runOrder(r *repl, args string) error {
+	order := strings.FieldsFunc(args, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+	if r.last != nil {
+		// check the order against the last expression and show its truth table again
+		last := r.last
+		if _, err := last.Reorder(order); err != nil {
+			return err
+		}
+		r.order = order
+		return r.printResult(last)
+	}
+	r.order = order
+	return nil
+}
+
+func onOff(value bool) string {
+	if value {
+		return "on"
+	}
+	return "off"
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
@@ -0,0 +1,301 @@
+package cmd
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/VladMinzatu/bool-calculator/evaluation"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	gateStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("#5FAFFF")).Bold(true)
+	keywordStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#D787FF")).Bold(true)
+	variableStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FFD75F"))
+	literalStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#00D787"))
+	punctStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#8A8A8A"))
+	matchStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFFFF")).Background(lipgloss.Color("#5F5FAF")).Bold(true)
+	cursorStyle   = lipgloss.NewStyle().Reverse(true)
+	hintStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("#767676"))
+	paramStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFFFF")).Bold(true)
+)
+
+// editorView draws the expression with syntax highlighting, the bracket next to the cursor and its match, and
+// a line with gate completions or the signature of the gate whose inputs are being typed
+func (m model) editorView() string {
+	text, cursor := m.editorText()
+	if text == "" && !m.multiline {
+		return "> " + cursorStyle.Render(" ") + hintStyle.Render(m.input.Placeholder)
+	}
+	view := "> " + highlight(text, cursor)
+	if m.multiline {
+		view = strings.ReplaceAll(view, "\n", "\n  ")
+	}
+	if hint := editorHint(text, cursor); hint != "" {
+		view += "\n" + hint
+	}
+	return view
+}
+
+// editorText is the expression being edited and the position of the cursor in runes
+func (m model) editorText() (string, int) {
+	if !m.multiline {
+		return m.input.Value(), m.input.Position()
+	}
+	text := m.editor.Value()
+	cursor := 0
+	for _, line := range strings.Split(text, "\n")[:m.editor.Line()] {
+		cursor += utf8.RuneCountInString(line) + 1
+	}
+	info := m.editor.LineInfo()
+	return text, cursor + info.StartColumn + info.ColumnOffset
+}
+
+// highlight styles every token of the text and shows the cursor, which can be right after the last character
+func highlight(text string, cursor int) string {
+	runes := []rune(text)
+	styles := textStyles(text, cursor)
+	var sb strings.Builder
+	for i := 0; i < len(runes); {
+		switch {
+		case i == cursor && runes[i] == '\n':
+			sb.WriteString(cursorStyle.Render(" ") + "\n")
+		case i == cursor:
+			sb.WriteString(cursorStyle.Render(string(runes[i])))
+		case runes[i] == '\n' || styles[i] == nil:
+			sb.WriteRune(runes[i])
+		default:
+			// a run of characters with the same style, up to the cursor or the end of the line
+			end := i + 1
+			for end < len(runes) && end != cursor && runes[end] != '\n' && styles[end] == styles[i] {
+				end++
+			}
+			sb.WriteString(styles[i].Render(string(runes[i:end])))
+			i = end
+			continue
+		}
+		i++
+	}
+	if cursor >= len(runes) {
+		sb.WriteString(cursorStyle.Render(" "))
+	}
+	return sb.String()
+}
+
+// textStyles is the style of every rune of the text, nil for spaces. What follows a lexing error and unbalanced
+// brackets are shown as errors, and when the cursor is on or right after a bracket, that bracket and its match
+// stand out.
+func textStyles(text string, cursor int) []*lipgloss.Style {
+	runes := []rune(text)
+	styles := make([]*lipgloss.Style, len(runes))
+	tokens, err := evaluation.ParseTokensWithPositions(text)
+
+	// lexing stops at the first character that isn't ASCII, so byte offsets are rune offsets up to there
+	let := false
+	for i, tok := range tokens {
+		inputOfGate := i > 0 && (tokens[i-1].Type() == evaluation.TokenLparan || tokens[i-1].Type() == evaluation.TokenComma)
+		style := tokenStyle(tok, let && !inputOfGate)
+		let = let || tok.Type() == evaluation.TokenLet
+		for j := tok.Start; j < tok.End; j++ {
+			styles[j] = style
+		}
+	}
+	if err != nil {
+		start := 0
+		if len(tokens) > 0 {
+			start = tokens[len(tokens)-1].End
+		}
+		for j := utf8.RuneCountInString(text[:start]); j < len(runes); j++ {
+			styles[j] = &errorStyle
+		}
+	}
+
+	pairs := matchBrackets(tokens)
+	for _, tok := range tokens {
+		if _, ok := pairs[tok.Start]; !ok && isBracket(tok) {
+			styles[tok.Start] = &errorStyle
+		}
+	}
+	for _, at := range []int{cursor, cursor - 1} {
+		if other, ok := pairs[at]; ok {
+			styles[at], styles[other] = &matchStyle, &matchStyle
+			break
+		}
+	}
+	return styles
+}
+
+// tokenStyle picks the style of a token. In a let, "in" is a keyword unless it's the input of a gate.
+func tokenStyle(tok evaluation.TokenPosition, afterLet bool) *lipgloss.Style {
+	switch t := tok.Type(); {
+	case t.IsGate():
+		return &gateStyle
+	case t == evaluation.TokenLet || (afterLet && t == evaluation.TokenVariable && tok.Literal() == "in"):
+		return &keywordStyle
+	case t == evaluation.TokenVariable || t == evaluation.TokenSlice:
+		return &variableStyle
+	case t == evaluation.TokenValue:
+		return &literalStyle
+	default:
+		return &punctStyle
+	}
+}
+
+func isBracket(tok evaluation.TokenPosition) bool {
+	return tok.Type() == evaluation.TokenLparan || tok.Type() == evaluation.TokenRparan
+}
+
+// matchBrackets pairs the offsets of matching brackets, in both directions. Unbalanced brackets are left out.
+func matchBrackets(tokens []evaluation.TokenPosition) map[int]int {
+	pairs := map[int]int{}
+	open := []int{}
+	for _, tok := range tokens {
+		switch tok.Type() {
+		case evaluation.TokenLparan:
+			open = append(open, tok.Start)
+		case evaluation.TokenRparan:
+			if len(open) > 0 {
+				start := open[len(open)-1]
+				open = open[:len(open)-1]
+				pairs[start], pairs[tok.Start] = tok.Start, start
+			}
+		}
+	}
+	return pairs
+}
+
+// gateCompletions are the gates whose names start with the word before the cursor. Single letters are more
+// likely to be variables, so they aren't completed.
+func gateCompletions(text string, cursor int) (string, []string) {
+	runes := []rune(text)
+	start := cursor
+	for start > 0 && isWordRune(runes[start-1]) {
+		start--
+	}
+	word := string(runes[start:cursor])
+	if len(word) < 2 || (start > 0 && runes[start-1] == '[') || (cursor < len(runes) && isWordRune(runes[cursor])) {
+		return word, nil
+	}
+	completions := []string{}
+	for _, name := range evaluation.Gates() {
+		if strings.HasPrefix(name, word) && name != word {
+			completions = append(completions, name)
+		}
+	}
+	return word, completions
+}
+
+// enclosingGate finds the gate call the cursor is in and which of its inputs is being typed
+func enclosingGate(text string, cursor int) (string, int, bool) {
+	tokens, _ := evaluation.ParseTokensWithPositions(text)
+	type call struct {
+		gate string // "" for brackets that don't belong to a gate
+		arg  int
+	}
+	calls := []call{}
+	for i, tok := range tokens {
+		if tok.Start >= cursor {
+			break
+		}
+		switch tok.Type() {
+		case evaluation.TokenLparan:
+			c := call{}
+			if i > 0 && tokens[i-1].Type().IsGate() {
+				c.gate = tokens[i-1].Literal()
+			} else if i > 2 && tokens[i-2].Type() == evaluation.TokenColon && tokens[i-3].Type().IsGate() {
+				c.gate = tokens[i-3].Literal() // a named sequential gate, like dff:q(...)
+			}
+			calls = append(calls, c)
+		case evaluation.TokenComma:
+			if len(calls) > 0 {
+				calls[len(calls)-1].arg++
+			}
+		case evaluation.TokenRparan:
+			if len(calls) > 0 {
+				calls = calls[:len(calls)-1]
+			}
+		}
+	}
+	if len(calls) == 0 || calls[len(calls)-1].gate == "" {
+		return "", 0, false
+	}
+	c := calls[len(calls)-1]
+	return c.gate, c.arg, true
+}
+
+// editorHint lists the gates the word before the cursor can be completed to, with Tab taking the first one, or
+// shows the inputs of the gate being called with the current input highlighted
+func editorHint(text string, cursor int) string {
+	if _, completions := gateCompletions(text, cursor); len(completions) > 0 {
+		signatures := []string{}
+		for i, name := range completions {
+			signature, _ := evaluation.GateSignature(name)
+			if i == 0 {
+				signature = paramStyle.Render(signature)
+			} else {
+				signature = hintStyle.Render(signature)
+			}
+			signatures = append(signatures, signature)
+		}
+		return hintStyle.Render("Tab: ") + strings.Join(signatures, hintStyle.Render("  "))
+	}
+	gate, arg, ok := enclosingGate(text, cursor)
+	if !ok {
+		return ""
+	}
+	parameters, _ := evaluation.GateParameters(gate)
+	var sb strings.Builder
+	sb.WriteString(hintStyle.Render(gate + "("))
+	for i, p := range parameters {
+		if i > 0 {
+			sb.WriteString(hintStyle.Render(", "))
+		}
+		if i == arg {
+			sb.WriteString(paramStyle.Render(p))
+		} else {
+			sb.WriteString(hintStyle.Render(p))
+		}
+	}
+	sb.WriteString(hintStyle.Render(")"))
+	return sb.String()
+}
+
+// completeGate replaces the word before the cursor with the first gate it can be completed to, followed by a bracket
+func (m model) completeGate() (tea.Model, tea.Cmd) {
+	text, cursor := m.editorText()
+	word, completions := gateCompletions(text, cursor)
+	if len(completions) == 0 {
+		return m, nil
+	}
+	before := m.input.Value()
+	rest := strings.TrimPrefix(completions[0], word)
+	if runes := []rune(text); cursor >= len(runes) || runes[cursor] != '(' {
+		rest += "("
+	}
+	if m.multiline {
+		m.editor.InsertString(rest)
+	} else {
+		runes := []rune(text)
+		m.input.SetValue(string(runes[:cursor]) + rest + string(runes[cursor:]))
+		m.input.SetCursor(cursor + utf8.RuneCountInString(rest))
+	}
+	m.inputChanged(before)
+	return m, nil
+}
+
+// toggleMultiline switches between the single line input and a text area for long expressions, where Enter
+// starts a new line
+func (m model) toggleMultiline() (tea.Model, tea.Cmd) {
+	if m.multiline {
+		m.multiline = false
+		m.editor.Blur()
+		m.input.SetValue(m.editor.Value())
+		m.input.CursorEnd()
+		return m, m.input.Focus()
+	}
+	m.multiline = true
+	m.input.Blur()
+	m.editor.SetValue(m.input.Value())
+	return m, m.editor.Focus()
+}
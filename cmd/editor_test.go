@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"reflect"
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/VladMinzatu/bool-calculator/evaluation"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// styleCodes names the styles of the editor with one letter each, so the styles of a text can be written as a string
+var styleCodes = map[*lipgloss.Style]rune{
+	&gateStyle:     'g',
+	&keywordStyle:  'k',
+	&variableStyle: 'v',
+	&literalStyle:  'l',
+	&punctStyle:    'p',
+	&matchStyle:    'm',
+	&errorStyle:    'e',
+	nil:            '.',
+}
+
+func TestTextStyles(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		cursor int
+		styles string
+	}{
+		{name: "gate", text: "and(a, 1)", cursor: 0, styles: "gggpvp.lp"},
+		{name: "cursor on a bracket", text: "and(a, 1)", cursor: 3, styles: "gggmvp.lm"},
+		{name: "cursor at the end", text: "and(a, 1)", cursor: 9, styles: "gggmvp.lm"},
+		{name: "nested brackets", text: "not(or(a, b))", cursor: 13, styles: "gggmggpvp.vpm"},
+		{name: "unbalanced", text: "and(a, b", cursor: 8, styles: "gggevp.v"},
+		{name: "extra bracket", text: "a)", cursor: 0, styles: "ve"},
+		{name: "bus slice", text: "not16(a[0..15])", cursor: 0, styles: "gggggpvvvvvvvvp"},
+		{name: "let", text: "let h = a in not(h)", cursor: 0, styles: "kkk.v.p.v.kk.gggpvp"},
+		{name: "in as an input", text: "let h = and(a, in) in h", cursor: 0, styles: "kkk.v.p.gggpvp.vvp.kk.v"},
+		{name: "named sequential gate", text: "dff:q(a)", cursor: 8, styles: "gggpvmvm"},
+		{name: "lexing error", text: "and(a, $b)", cursor: 0, styles: "gggevpeeee"},
+		{name: "non-ASCII", text: "or(é, b)", cursor: 8, styles: "ggeeeeee"},
+		{name: "cursor after non-ASCII", text: "and(a, é", cursor: 8, styles: "gggevpee"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			codes := []rune{}
+			for _, style := range textStyles(test.text, test.cursor) {
+				codes = append(codes, styleCodes[style])
+			}
+			if string(codes) != test.styles {
+				t.Errorf("got styles %q, expected %q", string(codes), test.styles)
+			}
+		})
+	}
+}
+
+func TestHighlightKeepsText(t *testing.T) {
+	escapes := regexp.MustCompile("\x1b\\[[0-9;]*m")
+	tests := []struct {
+		text   string
+		cursor int
+		plain  string
+	}{
+		{text: "and(a, b)", cursor: 4, plain: "and(a, b)"},
+		{text: "and(a, b)", cursor: 9, plain: "and(a, b) "},
+		{text: "or(é, ✓)", cursor: 3, plain: "or(é, ✓)"},
+		{text: "or(é, ✓)", cursor: 8, plain: "or(é, ✓) "},
+		{text: "and(a,\nb)", cursor: 6, plain: "and(a, \nb)"},
+		{text: "", cursor: 0, plain: " "},
+	}
+	for _, test := range tests {
+		if plain := escapes.ReplaceAllString(highlight(test.text, test.cursor), ""); plain != test.plain {
+			t.Errorf("highlight(%q, %d) shows %q, expected %q", test.text, test.cursor, plain, test.plain)
+		}
+	}
+}
+
+func TestMatchBrackets(t *testing.T) {
+	tests := []struct {
+		text  string
+		pairs map[int]int
+	}{
+		{text: "and(a, b)", pairs: map[int]int{3: 8, 8: 3}},
+		{text: "not(or(a, b))", pairs: map[int]int{3: 12, 12: 3, 6: 11, 11: 6}},
+		{text: "and(or(a, b)", pairs: map[int]int{6: 11, 11: 6}},
+		{text: "a) or(b)", pairs: map[int]int{5: 7, 7: 5}},
+		{text: "a", pairs: map[int]int{}},
+	}
+	for _, test := range tests {
+		tokens, err := evaluation.ParseTokensWithPositions(test.text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pairs := matchBrackets(tokens); !reflect.DeepEqual(pairs, test.pairs) {
+			t.Errorf("%q: got pairs %v, expected %v", test.text, pairs, test.pairs)
+		}
+	}
+}
+
+func TestGateCompletions(t *testing.T) {
+	tests := []struct {
+		name        string
+		text        string
+		cursor      int
+		word        string
+		completions []string
+	}{
+		{name: "cursor at the end", text: "and(a, xo", cursor: 9, word: "xo", completions: []string{"xor", "xor16"}},
+		{name: "one gate", text: "mux4", cursor: 4, word: "mux4", completions: []string{"mux4way16"}},
+		{name: "single letter", text: "and(a, x", cursor: 8, word: "x"},
+		{name: "whole name", text: "xor16", cursor: 5, word: "xor16"},
+		{name: "inside a word", text: "xor(a, b)", cursor: 2, word: "xo"},
+		{name: "bus index", text: "a[xo", cursor: 4, word: "xo"},
+		{name: "nothing typed", text: "and(", cursor: 4, word: ""},
+		{name: "after non-ASCII", text: "é, xo", cursor: 5, word: "xo", completions: []string{"xor", "xor16"}},
+		{name: "non-ASCII word", text: "or(éx", cursor: 5, word: "éx"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			word, completions := gateCompletions(test.text, test.cursor)
+			if word != test.word || !slices.Equal(completions, test.completions) {
+				t.Errorf("got %q and %q, expected %q and %q", word, completions, test.word, test.completions)
+			}
+		})
+	}
+}
+
+func TestEnclosingGate(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		cursor int
+		gate   string
+		arg    int
+		ok     bool
+	}{
+		{name: "first input", text: "and(", cursor: 4, gate: "and", arg: 0, ok: true},
+		{name: "second input", text: "and(a, ", cursor: 7, gate: "and", arg: 1, ok: true},
+		{name: "cursor before the bracket", text: "and(a, b)", cursor: 3},
+		{name: "closed", text: "and(a, b)", cursor: 9},
+		{name: "cursor on the closing bracket", text: "and(a, b)", cursor: 8, gate: "and", arg: 1, ok: true},
+		{name: "nested", text: "and(a, or(b, ", cursor: 13, gate: "or", arg: 1, ok: true},
+		{name: "after a nested call", text: "and(or(a, b), ", cursor: 14, gate: "and", arg: 1, ok: true},
+		{name: "brackets without a gate", text: "and((a, ", cursor: 8},
+		{name: "named sequential gate", text: "dff:q(", cursor: 6, gate: "dff", arg: 0, ok: true},
+		{name: "named sequential gate input", text: "dff:q(a", cursor: 7, gate: "dff", arg: 0, ok: true},
+		{name: "non-ASCII input", text: "and(é", cursor: 5, gate: "and", arg: 0, ok: true},
+		{name: "no gate", text: "a", cursor: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gate, arg, ok := enclosingGate(test.text, test.cursor)
+			if gate != test.gate || arg != test.arg || ok != test.ok {
+				t.Errorf("got %q, %d and %v, expected %q, %d and %v", gate, arg, ok, test.gate, test.arg, test.ok)
+			}
+		})
+	}
+}
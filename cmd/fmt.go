@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/VladMinzatu/bool-calculator/evaluation"
+)
+
+// RunFmt formats expression files (one expression per file), or stdin if no files are given.
+// It returns the process exit code.
+func RunFmt(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	write := flags.Bool("w", false, "write result to (source) file instead of stdout")
+	list := flags.Bool("l", false, "list files whose formatting differs")
+	notation := flags.String("notation", "prefix", "output notation: prefix, infix or sexpr")
+	width := flags.Int("width", 80, "maximum line width before gate arguments are wrapped")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	opts := evaluation.FormatOptions{Width: *width}
+	var err error
+	if opts.Notation, err = evaluation.ParseNotation(*notation); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if *write && opts.Notation != evaluation.NotationPrefix {
+		fmt.Fprintln(os.Stderr, "-w can only be used with the prefix notation, the others can't be parsed back")
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		if *write {
+			fmt.Fprintln(os.Stderr, "cannot use -w with standard input")
+			return 2
+		}
+		if err := formatFile(os.Stdin, "<stdin>", opts, false, *list); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+
+	exitCode := 0
+	for _, path := range flags.Args() {
+		f, err := os.Open(path)
+		if err == nil {
+			err = formatFile(f, path, opts, *write, *list)
+			f.Close()
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exitCode = 1
+		}
+	}
+	return exitCode
+}
+
+func formatFile(r io.Reader, path string, opts evaluation.FormatOptions, write, list bool) error {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	expr, _, wires, err := evaluation.ParseExpressionWithWires(string(src))
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	formatted := []byte(evaluation.FormatWithWires(expr, wires, opts) + "\n")
+
+	if list {
+		if !bytes.Equal(src, formatted) {
+			fmt.Println(path)
+		}
+		return nil
+	}
+	if write {
+		if bytes.Equal(src, formatted) {
+			return nil
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(path, formatted, info.Mode().Perm())
+	}
+	_, err = os.Stdout.Write(formatted)
+	return err
+}
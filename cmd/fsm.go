@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/VladMinzatu/bool-calculator/evaluation"
+)
+
+// RunFSM prints the state table of a state machine description and the logic implementing it.
+// It returns the process exit code.
+func RunFSM(args []string) int {
+	flags := flag.NewFlagSet("fsm", flag.ContinueOnError)
+	encoding := flags.String("encoding", "binary", "state encoding: binary, onehot or gray")
+	minimize := flags.Bool("minimize", false, "merge equivalent states and drop unreachable ones")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	enc, err := evaluation.ParseEncoding(*encoding)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if flags.NArg() > 1 {
+		fmt.Fprintln(os.Stderr, "expected at most one state machine file")
+		return 2
+	}
+
+	path, r := "<stdin>", io.Reader(os.Stdin)
+	if flags.NArg() == 1 {
+		path = flags.Arg(0)
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		r = f
+	}
+	if err := printFSM(r, enc, *minimize); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+		return 1
+	}
+	return 0
+}
+
+func printFSM(r io.Reader, encoding evaluation.Encoding, minimize bool) error {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	fsm, err := evaluation.ParseFSM(string(src))
+	if err != nil {
+		return err
+	}
+	table, err := fsm.StateTable()
+	if err != nil {
+		return err
+	}
+	if minimize {
+		table = table.Minimize()
+	}
+	synthesis, err := table.Synthesize(encoding)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("State table (%v):\n%v\n", table.Kind, table)
+	fmt.Printf("State encoding (%v):\n", encoding)
+	for _, state := range table.States {
+		var code strings.Builder
+		for i := len(synthesis.StateVariables) - 1; i >= 0; i-- {
+			code.WriteString(bit(synthesis.Codes[state][i]))
+		}
+		fmt.Printf("%v\t%v\n", state, code.String())
+	}
+	fmt.Println("\nNext state:")
+	for i, name := range synthesis.StateVariables {
+		fmt.Printf("%v = %v\n", name, synthesis.NextState[i])
+	}
+	fmt.Println("\nOutputs:")
+	for i, name := range table.Outputs {
+		fmt.Printf("%v = %v\n", name, synthesis.Outputs[i])
+	}
+	return nil
+}
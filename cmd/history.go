@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxHistory is the number of lines kept in the history file
+const maxHistory = 1000
+
+// history is the list of lines entered in the REPL, oldest first. Lines are appended to a file, so they are
+// available in the next run.
+type history struct {
+	entries  []string
+	path     string    // "" to keep the history in memory only
+	warnings io.Writer // where the first error writing the file is reported
+	failed   bool      // the file couldn't be written, which is only reported once
+}
+
+func loadHistory(path string, warnings io.Writer) *history {
+	h := &history{path: path, warnings: warnings}
+	if path == "" {
+		return h
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return h // no history yet
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		h.entries = append(h.entries, scanner.Text())
+	}
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[len(h.entries)-maxHistory:]
+		h.rewrite()
+	}
+	return h
+}
+
+// add appends a line, unless it's empty or repeats the previous one
+func (h *history) add(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.Contains(line, "\n") || (len(h.entries) > 0 && h.entries[len(h.entries)-1] == line) {
+		return
+	}
+	h.entries = append(h.entries, line)
+	if h.path == "" {
+		return
+	}
+	h.report(h.appendLine(line))
+}
+
+func (h *history) appendLine(line string) error {
+	if err := os.MkdirAll(filepath.Dir(h.path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(line + "\n")
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// rewrite replaces the file with the entries kept in memory
+func (h *history) rewrite() {
+	h.report(os.WriteFile(h.path, []byte(strings.Join(h.entries, "\n")+"\n"), 0o600))
+}
+
+// report warns about the first error writing the file. The history is still kept in memory for the session.
+func (h *history) report(err error) {
+	if err == nil || h.failed {
+		return
+	}
+	h.failed = true
+	fmt.Fprintf(h.warnings, "Warning: the history can't be saved: %v\n", err)
+}
+
+// search finds the latest entry before index that contains query, returning its index or -1
+func (h *history) search(query string, before int) int {
+	for i := min(before, len(h.entries)) - 1; i >= 0; i-- {
+		if strings.Contains(h.entries[i], query) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "history")
+	var warnings strings.Builder
+	h := loadHistory(path, &warnings)
+	for _, line := range []string{"and(a, b)", "  and(a, b) ", "", "or(a, b)"} {
+		h.add(line)
+	}
+	loaded := loadHistory(path, &warnings)
+	if expected := []string{"and(a, b)", "or(a, b)"}; !reflect.DeepEqual(loaded.entries, expected) {
+		t.Errorf("got entries %q, expected %q", loaded.entries, expected)
+	}
+	if warnings.Len() != 0 {
+		t.Errorf("unexpected warnings %q", warnings.String())
+	}
+}
+
+func TestHistoryWriteError(t *testing.T) {
+	// the directory of the history is a file, so it can't be written
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var warnings strings.Builder
+	h := loadHistory(filepath.Join(file, "history"), &warnings)
+	h.add("and(a, b)")
+	h.add("or(a, b)")
+	if n := strings.Count(warnings.String(), "\n"); n != 1 || !strings.Contains(warnings.String(), "history can't be saved") {
+		t.Errorf("expected one warning, got %q", warnings.String())
+	}
+	if len(h.entries) != 2 {
+		t.Errorf("the entries should be kept in memory, got %q", h.entries)
+	}
+}
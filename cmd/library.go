@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// previewRows is the number of rows of the truth table shown in the preview of a circuit
+const previewRows = 16
+
+var previewStyle = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).Padding(0, 1).MarginLeft(2)
+
+// openBrowser lists the circuits of the workspace, with the one selected previewed next to the list
+func (m model) openBrowser() (tea.Model, tea.Cmd) {
+	m.browsing = true
+	m.browseErr = nil
+	m.circuits = nil
+	m.circuitTable = newTruthTable()
+	m.circuitTable.SetHeight(max(m.height-8, 3))
+	m.input.Blur()
+	m.editor.Blur()
+
+	circuits, err := openWorkspace("").list()
+	if err != nil {
+		m.browseErr = err
+		return m, nil
+	}
+	library, err := newLibrary(circuits)
+	if err != nil {
+		m.browseErr = err
+		return m, nil
+	}
+	m.circuits, m.library = circuits, library
+
+	rows := []table.Row{}
+	widths := []int{len("Circuit"), len("Tags"), len("Description")}
+	for _, c := range circuits {
+		signature, err := library.Signature(c.Name)
+		if err != nil {
+			signature = c.Name + " (invalid)"
+		}
+		row := table.Row{signature, strings.Join(c.Tags, ","), c.Description}
+		for i, cell := range row {
+			widths[i] = max(widths[i], len(cell))
+		}
+		rows = append(rows, row)
+	}
+	m.circuitTable.SetColumns([]table.Column{
+		{Title: "Circuit", Width: min(widths[0], 32)},
+		{Title: "Tags", Width: min(widths[1], 16)},
+		{Title: "Description", Width: min(widths[2], 32)},
+	})
+	m.circuitTable.SetRows(rows)
+	return m, nil
+}
+
+func (m model) closeBrowser() (tea.Model, tea.Cmd) {
+	m.browsing = false
+	if m.multiline {
+		return m, m.editor.Focus()
+	}
+	return m, m.input.Focus()
+}
+
+func (m model) updateBrowser(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "down", "pgup", "pgdown":
+		var cmd tea.Cmd
+		m.circuitTable, cmd = m.circuitTable.Update(msg)
+		return m, cmd
+	case "enter":
+		c, ok := m.selectedCircuit()
+		if !ok {
+			return m, nil
+		}
+		// the circuits this one calls are expanded, for the playground and the clock, which don't know the workspace
+		expression, err := expand(m.library, c)
+		if err != nil {
+			m.browseErr = err
+			return m, nil
+		}
+		m.input.SetValue(expression)
+		m.input.CursorEnd()
+		m.editor.SetValue(expression)
+		if m.err = m.validateInput(); m.err != nil {
+			m.err = fmt.Errorf("*%v", m.err)
+		}
+		m.updateTable()
+		m.status = "Loaded circuit " + c.Name
+		return m.closeBrowser()
+	}
+	return m, nil
+}
+
+func (m model) selectedCircuit() (Circuit, bool) {
+	cursor := m.circuitTable.Cursor()
+	if cursor < 0 || cursor >= len(m.circuits) {
+		return Circuit{}, false
+	}
+	return m.circuits[cursor], true
+}
+
+func (m model) browserView() string {
+	var b strings.Builder
+
+	b.WriteString("Circuits of the workspace:")
+	b.WriteString(gap)
+	if m.browseErr != nil {
+		b.WriteString(errorStyle.Render(m.browseErr.Error()))
+		b.WriteString(gap)
+	}
+	if len(m.circuits) == 0 {
+		if m.browseErr == nil {
+			b.WriteString("The workspace has no circuits yet, add them with 'bool-calculator workspace add' or :ws add in the REPL")
+			b.WriteString(gap)
+		}
+	} else {
+		b.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, m.circuitTable.View(), m.previewView()))
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\nPress Up/Down to choose a circuit, Enter to use its expression, Ctrl+B or Esc to go back\n")
+	return b.String()
+}
+
+// previewView shows the selected circuit with the first rows of its truth table
+func (m model) previewView() string {
+	c, ok := m.selectedCircuit()
+	if !ok {
+		return ""
+	}
+	lines := []string{}
+	if signature, err := m.library.Signature(c.Name); err == nil {
+		lines = append(lines, gateStyle.Render(signature))
+	}
+	if c.Description != "" {
+		lines = append(lines, c.Description)
+	}
+	if len(c.Tags) > 0 {
+		lines = append(lines, hintStyle.Render("Tags: "+strings.Join(c.Tags, ", ")))
+	}
+	lines = append(lines, highlight(c.Expression, -1), "")
+
+	result, err := m.library.Compute(c.Expression)
+	if err != nil {
+		lines = append(lines, errorStyle.Render(err.Error()))
+	} else {
+		var sb strings.Builder
+		tw := tabwriter.NewWriter(&sb, 0, 4, 2, ' ', 0)
+		fmt.Fprint(tw, result.String())
+		tw.Flush()
+		table := strings.Split(strings.TrimRight(sb.String(), "\n"), "\n")
+		if rows := len(result.Outputs); rows > previewRows {
+			table = append(table[:len(table)-rows+previewRows], hintStyle.Render(fmt.Sprintf("... %d more rows", rows-previewRows)))
+		}
+		lines = append(lines, table...)
+	}
+	return previewStyle.Render(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,364 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/x/term"
+)
+
+// errInterrupted is returned by readLine when the line is abandoned with Ctrl+C
+var errInterrupted = errors.New("interrupted")
+
+// lineEditor reads lines with readline-style editing when the input is a terminal: the cursor moves with the
+// arrow keys, Ctrl+A/E, Alt+B/F, Up/Down go through the history, Ctrl+R searches it backwards and Tab completes
+// the word before the cursor. Other input, like a pipe, is read line by line.
+type lineEditor struct {
+	in       *os.File
+	reader   *bufio.Reader
+	out      *os.File
+	history  *history
+	complete completer // nil to insert tabs as they are
+}
+
+// completer finds the words that can complete the word ending at pos. It returns where that word starts.
+type completer func(line []rune, pos int) (int, []string)
+
+func newLineEditor(in, out *os.File, h *history) *lineEditor {
+	return &lineEditor{in: in, reader: bufio.NewReader(in), out: out, history: h}
+}
+
+func (e *lineEditor) readLine(prompt string) (string, error) {
+	if !term.IsTerminal(e.in.Fd()) {
+		return e.readPlainLine(prompt)
+	}
+	state, err := term.MakeRaw(e.in.Fd())
+	if err != nil {
+		return e.readPlainLine(prompt)
+	}
+	defer term.Restore(e.in.Fd(), state)
+
+	s := newEditState(prompt, e.history)
+	s.complete = e.complete
+	e.redraw(s)
+	for {
+		k, err := readKey(e.reader)
+		if err != nil {
+			return "", err
+		}
+		if k.name == "ctrl+l" {
+			fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+		}
+		done, err := s.handle(k)
+		if len(s.completions) > 0 {
+			// list the candidates below the line, which is drawn again after them
+			fmt.Fprint(e.out, "\r\n"+strings.Join(s.completions, "  ")+"\r\n")
+			s.completions = nil
+		}
+		e.redraw(s)
+		if done {
+			fmt.Fprint(e.out, "\r\n")
+			return string(s.line), err
+		}
+	}
+}
+
+func (e *lineEditor) readPlainLine(prompt string) (string, error) {
+	fmt.Fprint(e.out, prompt)
+	line, err := e.reader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// redraw rewrites the current line. Lines wider than the terminal scroll horizontally to keep the cursor visible.
+func (e *lineEditor) redraw(s *editState) {
+	text, cursor := s.display()
+	runes := []rune(text)
+	if width, _, err := term.GetSize(e.out.Fd()); err == nil && width > 1 && len(runes) >= width {
+		start := max(0, cursor-width+2)
+		runes = runes[start:min(len(runes), start+width-1)]
+		cursor -= start
+	}
+	fmt.Fprintf(e.out, "\r\x1b[K%s\r", string(runes))
+	if cursor > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dC", cursor)
+	}
+}
+
+// keyPress is a key press read from the terminal. Printable characters have no name.
+type keyPress struct {
+	name string
+	r    rune
+}
+
+// readKey decodes one key press from raw terminal input, including the escape sequences of the arrow keys
+func readKey(r *bufio.Reader) (keyPress, error) {
+	ch, _, err := r.ReadRune()
+	if err != nil {
+		return keyPress{}, err
+	}
+	switch {
+	case ch == 27:
+		return readEscapeSequence(r)
+	case ch == '\r' || ch == '\n':
+		return keyPress{name: "enter"}, nil
+	case ch == '\t':
+		return keyPress{name: "tab"}, nil
+	case ch == 127 || ch == 8:
+		return keyPress{name: "backspace"}, nil
+	case ch < 32:
+		return keyPress{name: "ctrl+" + string(rune('a'+ch-1))}, nil
+	default:
+		return keyPress{r: ch}, nil
+	}
+}
+
+var escapeSequences = map[string]string{
+	"[A": "up", "[B": "down", "[C": "right", "[D": "left",
+	"[H": "home", "[F": "end", "OH": "home", "OF": "end",
+	"[1~": "home", "[7~": "home", "[4~": "end", "[8~": "end",
+	"[3~": "delete",
+	"b":   "alt+b", "f": "alt+f",
+}
+
+func readEscapeSequence(r *bufio.Reader) (keyPress, error) {
+	if r.Buffered() == 0 {
+		return keyPress{name: "esc"}, nil
+	}
+	first, _ := r.ReadByte()
+	sequence := string(first)
+	if first == '[' || first == 'O' {
+		// parameters until the final byte of the sequence
+		for {
+			b, err := r.ReadByte()
+			if err != nil {
+				return keyPress{}, err
+			}
+			sequence += string(b)
+			if b >= 0x40 && b <= 0x7e {
+				break
+			}
+		}
+	}
+	if name, ok := escapeSequences[sequence]; ok {
+		return keyPress{name: name}, nil
+	}
+	return keyPress{name: "unknown"}, nil
+}
+
+// editState is the line being edited, separate from the terminal so the editing keys are easy to follow
+type editState struct {
+	prompt  string
+	line    []rune
+	pos     int
+	history *history
+	index   int    // history entry shown, len(history.entries) for the new line
+	draft   []rune // the new line while an older entry is shown
+
+	searching bool
+	query     []rune
+	match     int // history entry found by the search, -1 if none
+
+	complete    completer
+	completions []string // candidates to list after an ambiguous Tab
+}
+
+func newEditState(prompt string, h *history) *editState {
+	return &editState{prompt: prompt, history: h, index: len(h.entries), match: -1}
+}
+
+// handle applies a key press and tells whether the line is finished
+func (s *editState) handle(k keyPress) (bool, error) {
+	if s.searching {
+		return s.handleSearch(k)
+	}
+	switch k.name {
+	case "":
+		s.insert([]rune{k.r})
+	case "tab":
+		if s.complete == nil {
+			s.insert([]rune{'\t'})
+		} else {
+			s.completeWord()
+		}
+	case "enter":
+		return true, nil
+	case "ctrl+c":
+		s.line, s.pos = nil, 0
+		return true, errInterrupted
+	case "ctrl+d":
+		if len(s.line) == 0 {
+			return true, io.EOF
+		}
+		s.deleteRange(s.pos, min(s.pos+1, len(s.line)))
+	case "delete":
+		s.deleteRange(s.pos, min(s.pos+1, len(s.line)))
+	case "backspace":
+		s.deleteRange(max(s.pos-1, 0), s.pos)
+	case "left", "ctrl+b":
+		s.pos = max(s.pos-1, 0)
+	case "right", "ctrl+f":
+		s.pos = min(s.pos+1, len(s.line))
+	case "home", "ctrl+a":
+		s.pos = 0
+	case "end", "ctrl+e":
+		s.pos = len(s.line)
+	case "alt+b":
+		s.pos = s.wordStart()
+	case "alt+f":
+		s.pos = s.wordEnd()
+	case "ctrl+k":
+		s.line = s.line[:s.pos]
+	case "ctrl+u":
+		s.deleteRange(0, s.pos)
+	case "ctrl+w":
+		s.deleteRange(s.wordStart(), s.pos)
+	case "up", "ctrl+p":
+		s.recall(s.index - 1)
+	case "down", "ctrl+n":
+		s.recall(s.index + 1)
+	case "ctrl+r":
+		s.searching, s.query, s.match = true, nil, -1
+	}
+	return false, nil
+}
+
+func (s *editState) insert(text []rune) {
+	s.line = append(s.line[:s.pos], append(text, s.line[s.pos:]...)...)
+	s.pos += len(text)
+}
+
+// completeWord completes the word before the cursor. With several candidates, it adds their common prefix, and
+// lists them if that doesn't add anything.
+func (s *editState) completeWord() {
+	start, candidates := s.complete(s.line, s.pos)
+	if len(candidates) == 0 {
+		return
+	}
+	prefix := candidates[0]
+	for _, c := range candidates[1:] {
+		for !strings.HasPrefix(c, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	typed := s.pos - start
+	if rest := []rune(prefix)[min(typed, len([]rune(prefix))):]; len(rest) > 0 {
+		s.insert(rest)
+	} else if len(candidates) > 1 {
+		s.completions = candidates
+	}
+}
+
+func (s *editState) deleteRange(from, to int) {
+	s.line = append(s.line[:from], s.line[to:]...)
+	s.pos = from
+}
+
+// wordStart is the start of the word before the cursor, wordEnd the end of the word after it
+func (s *editState) wordStart() int {
+	i := s.pos
+	for i > 0 && !isWordRune(s.line[i-1]) {
+		i--
+	}
+	for i > 0 && isWordRune(s.line[i-1]) {
+		i--
+	}
+	return i
+}
+
+func (s *editState) wordEnd() int {
+	i := s.pos
+	for i < len(s.line) && !isWordRune(s.line[i]) {
+		i++
+	}
+	for i < len(s.line) && isWordRune(s.line[i]) {
+		i++
+	}
+	return i
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// recall shows history entry i, or the new line after the last entry
+func (s *editState) recall(i int) {
+	entries := s.history.entries
+	if i < 0 || i > len(entries) || i == s.index {
+		return
+	}
+	if s.index == len(entries) {
+		s.draft = s.line
+	}
+	s.index = i
+	if i == len(entries) {
+		s.line = s.draft
+	} else {
+		s.line = []rune(entries[i])
+	}
+	s.pos = len(s.line)
+}
+
+// handleSearch is the reverse search started with Ctrl+R. Typing extends the query, Ctrl+R finds an older
+// match, Enter runs the match and other keys edit it.
+func (s *editState) handleSearch(k keyPress) (bool, error) {
+	switch k.name {
+	case "":
+		s.query = append(s.query, k.r)
+		from := len(s.history.entries)
+		if s.match >= 0 {
+			from = s.match + 1 // the current match may still contain the longer query
+		}
+		s.match = s.history.search(string(s.query), from)
+		return false, nil
+	case "backspace":
+		if len(s.query) > 0 {
+			s.query = s.query[:len(s.query)-1]
+		}
+		s.match = s.history.search(string(s.query), len(s.history.entries))
+		return false, nil
+	case "ctrl+r":
+		if s.match > 0 {
+			if older := s.history.search(string(s.query), s.match); older >= 0 {
+				s.match = older
+			}
+		}
+		return false, nil
+	case "ctrl+g", "esc":
+		s.searching = false
+		return false, nil
+	}
+
+	s.searching = false
+	if s.match >= 0 {
+		s.line = []rune(s.history.entries[s.match])
+		s.pos = len(s.line)
+		s.index = len(s.history.entries)
+	}
+	if k.name == "enter" {
+		return true, nil
+	}
+	return s.handle(k)
+}
+
+// display is the text of the line and the column of the cursor
+func (s *editState) display() (string, int) {
+	if !s.searching {
+		return s.prompt + string(s.line), len([]rune(s.prompt)) + s.pos
+	}
+	prefix := "(reverse-i-search)`"
+	found := ""
+	if s.match >= 0 {
+		found = s.history.entries[s.match]
+	} else if len(s.query) > 0 {
+		prefix = "(failed reverse-i-search)`"
+	}
+	return prefix + string(s.query) + "': " + found, len([]rune(prefix)) + len(s.query)
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"bufio"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// typeKeys edits a new line with the keys of the input, decoded like terminal input, until the line is finished
+// or the input ends
+func typeKeys(t *testing.T, s *editState, input string) (bool, error) {
+	t.Helper()
+	reader := bufio.NewReader(strings.NewReader(input))
+	for {
+		k, err := readKey(reader)
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if done, err := s.handle(k); done {
+			return true, err
+		}
+	}
+}
+
+func TestEditKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		history []string
+		input   string
+		line    string
+		pos     int
+		done    bool
+		err     error
+	}{
+		{name: "insert", input: "and(a, b)", line: "and(a, b)", pos: 9},
+		{name: "insert before the cursor", input: "ac\x1b[Db", line: "abc", pos: 2},
+		{name: "home and end", input: "bc\x01a\x05d", line: "abcd", pos: 4},
+		{name: "non-ASCII", input: "é✓\x1b[Dx", line: "éx✓", pos: 2},
+		{name: "backspace", input: "é✓\x7f", line: "é", pos: 1},
+		{name: "delete", input: "abc\x01\x1b[3~", line: "bc", pos: 0},
+		{name: "word back", input: "and(ab, cd)\x1bb\x1bbX", line: "and(Xab, cd)", pos: 5},
+		{name: "word forward", input: "and(ab, cd)\x01\x1bf\x1bfX", line: "and(abX, cd)", pos: 7},
+		{name: "word back over non-ASCII", input: "or(éa, b)\x1bb\x1bbX", line: "or(Xéa, b)", pos: 4},
+		{name: "delete word", input: "and(ab\x17", line: "and(", pos: 4},
+		{name: "kill to the end", input: "and(a, b)\x01\x1bf\x0b", line: "and", pos: 3},
+		{name: "kill to the start", input: "and(a, b)\x1bb\x15", line: "b)", pos: 0},
+		{name: "enter", input: "a\rb", line: "a", pos: 1, done: true},
+		{name: "interrupt", input: "and(a\x03", line: "", pos: 0, done: true, err: errInterrupted},
+		{name: "end of input", input: "\x04", line: "", done: true, err: io.EOF},
+		{name: "ctrl+d deletes", input: "ab\x01\x04", line: "b", pos: 0},
+		{name: "tab without completion", input: "a\tb", line: "a\tb", pos: 3},
+
+		{name: "previous entry", history: []string{"or(a, b)", "and(a, b)"}, input: "x\x1b[A", line: "and(a, b)", pos: 9},
+		{name: "older entry", history: []string{"or(a, b)", "and(a, b)"}, input: "x\x1b[A\x10\x10", line: "or(a, b)", pos: 8},
+		{name: "back to the draft", history: []string{"or(a, b)", "and(a, b)"}, input: "x\x1b[A\x1b[A\x1b[B\x0e", line: "x", pos: 1},
+		{name: "past the newest", history: []string{"or(a, b)"}, input: "x\x1b[B", line: "x", pos: 1},
+
+		{name: "search", history: []string{"or(a, b)", "and(a, b)"}, input: "\x12or\r", line: "or(a, b)", pos: 8, done: true},
+		{name: "search older", history: []string{"and(a)", "and(b)"}, input: "\x12and\x12\r", line: "and(a)", pos: 6, done: true},
+		{name: "search and edit", history: []string{"or(a, b)", "and(a, b)"}, input: "\x12or\x01!", line: "!or(a, b)", pos: 1},
+		{name: "search backspace", history: []string{"or(c)", "and(b)"}, input: "\x12orz\x7f\r", line: "or(c)", pos: 5, done: true},
+		{name: "search failed", history: []string{"or(a, b)"}, input: "a\x12zz\r", line: "a", pos: 1, done: true},
+		{name: "search cancelled", history: []string{"or(a, b)"}, input: "a\x12or\x07b", line: "ab", pos: 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newEditState(">>> ", &history{entries: test.history})
+			done, err := typeKeys(t, s, test.input)
+			if done != test.done || err != test.err {
+				t.Errorf("got done %v and error %v, expected %v and %v", done, err, test.done, test.err)
+			}
+			if string(s.line) != test.line || s.pos != test.pos {
+				t.Errorf("got %q with the cursor at %d, expected %q at %d", string(s.line), s.pos, test.line, test.pos)
+			}
+		})
+	}
+}
+
+func TestSearchDisplay(t *testing.T) {
+	s := newEditState(">>> ", &history{entries: []string{"or(a, b)"}})
+	typeKeys(t, s, "\x12or")
+	if text, cursor := s.display(); text != "(reverse-i-search)`or': or(a, b)" || cursor != 21 {
+		t.Errorf("got %q with the cursor at %d", text, cursor)
+	}
+	typeKeys(t, s, "z")
+	if text, _ := s.display(); text != "(failed reverse-i-search)`orz': " {
+		t.Errorf("got %q", text)
+	}
+}
+
+func TestTabCompletion(t *testing.T) {
+	r := &repl{workspace: &workspace{dir: t.TempDir()}, seen: map[string]struct{}{"carry": {}, "cin": {}}}
+	tests := []struct {
+		name        string
+		input       string
+		line        string
+		completions []string
+	}{
+		{name: "gate", input: "and(a, xo\t", line: "and(a, xor"},
+		{name: "list the candidates", input: "and(a, xo\t\t", line: "and(a, xor", completions: []string{"xor", "xor16"}},
+		{name: "unique", input: "mux4\t", line: "mux4way16"},
+		{name: "seen variable", input: "and(ca\t", line: "and(carry"},
+		{name: "common prefix of variables", input: "and(c\t", line: "and(c", completions: []string{"carry", "cin"}},
+		{name: "no candidates", input: "and(zz\t", line: "and(zz"},
+		{name: "nothing typed", input: "and(\t", line: "and("},
+		{name: "in the middle", input: "xo(a, b)\x01\x1bf\t", line: "xor(a, b)"},
+		{name: "command", input: ":for\t", line: ":format"},
+		{name: "workspace command", input: ":ws up\t", line: ":ws update"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newEditState(">>> ", &history{})
+			s.complete = r.complete
+			typeKeys(t, s, test.input)
+			if string(s.line) != test.line {
+				t.Errorf("got %q, expected %q", string(s.line), test.line)
+			}
+			if !slices.Equal(s.completions, test.completions) {
+				t.Errorf("got completions %q, expected %q", s.completions, test.completions)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/VladMinzatu/bool-calculator/lsp"
+)
+
+// RunLSP runs the language server for .bool files on stdin and stdout. It returns the process exit code.
+func RunLSP(args []string) int {
+	flags := flag.NewFlagSet("lsp", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 {
+		fmt.Fprintln(os.Stderr, "lsp takes no arguments")
+		return 2
+	}
+	// stdout is for the client, so the circuits of the workspace that can't be used are reported on stderr
+	library := openWorkspace("").loadLibrary(os.Stderr)
+	if err := lsp.New(library).Serve(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	switchOnStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#00D787"))
+	switchOffStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#767676"))
+	selectedStyle  = lipgloss.NewStyle().Reverse(true)
+)
+
+// playgroundHeader is the number of lines above the switches in the playground view: the title and a blank line
+const playgroundHeader = 2
+
+// switchPosition is where a switch is drawn in the playground view, used to find the switch under the mouse
+type switchPosition struct {
+	line, start, end int
+}
+
+func (m model) togglePlayground() (tea.Model, tea.Cmd) {
+	if m.playground {
+		m.playground = false
+		return m, tea.Batch(tea.DisableMouse, m.input.Focus())
+	}
+	if m.result == nil {
+		return m, nil
+	}
+	m.playground = true
+	m.selectedSwitch = 0
+	m.values = map[string]bool{}
+	for _, name := range m.result.Variables {
+		m.values[name] = false
+	}
+	m.input.Blur()
+	return m, tea.EnableMouseCellMotion
+}
+
+func (m model) updatePlayground(msg tea.Msg) (tea.Model, tea.Cmd) {
+	variables := m.result.Variables
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if len(variables) == 0 {
+			return m, nil
+		}
+		switch msg.String() {
+		case "left", "shift+tab":
+			m.selectedSwitch = (m.selectedSwitch + len(variables) - 1) % len(variables)
+		case "right", "tab":
+			m.selectedSwitch = (m.selectedSwitch + 1) % len(variables)
+		case " ", "enter":
+			name := variables[m.selectedSwitch]
+			m.values[name] = !m.values[name]
+		}
+	case tea.MouseMsg:
+		if msg.Action != tea.MouseActionPress || msg.Button != tea.MouseButtonLeft {
+			return m, nil
+		}
+		for i, pos := range switchLayout(variables, m.width) {
+			if msg.Y == playgroundHeader+pos.line && msg.X >= pos.start && msg.X < pos.end {
+				m.selectedSwitch = i
+				m.values[variables[i]] = !m.values[variables[i]]
+			}
+		}
+	}
+	return m, nil
+}
+
+// switchLayout places the switches next to each other, wrapping them at the width of the window
+func switchLayout(variables []string, width int) []switchPosition {
+	result := []switchPosition{}
+	line, x := 0, 0
+	for _, name := range variables {
+		w := len(switchLabel(name, false))
+		if x > 0 && width > 0 && x+w > width {
+			line, x = line+1, 0
+		}
+		result = append(result, switchPosition{line: line, start: x, end: x + w})
+		x += w + 1
+	}
+	return result
+}
+
+func switchLabel(name string, value bool) string {
+	return fmt.Sprintf("[%s=%s]", name, bit(value))
+}
+
+func (m model) playgroundView() string {
+	var b strings.Builder
+
+	b.WriteString("Playground for: " + m.input.Value())
+	b.WriteString(gap)
+
+	variables := m.result.Variables
+	line := 0
+	for i, pos := range switchLayout(variables, m.width) {
+		if pos.line > line {
+			b.WriteString("\n")
+			line = pos.line
+		} else if i > 0 {
+			b.WriteString(" ")
+		}
+		name := variables[i]
+		style := switchOffStyle
+		if m.values[name] {
+			style = switchOnStyle
+		}
+		if i == m.selectedSwitch {
+			style = style.Inherit(selectedStyle)
+		}
+		b.WriteString(style.Render(switchLabel(name, m.values[name])))
+	}
+	if len(variables) == 0 {
+		b.WriteString("The expression has no inputs")
+	}
+	b.WriteString(gap)
+
+	trace, err := m.library.TraceExpression(m.input.Value(), m.values)
+	if err != nil {
+		b.WriteString(errorStyle.Render(err.Error()))
+		b.WriteString(gap)
+	} else {
+		outputs := make([]string, len(trace.Outputs))
+		for i, out := range trace.Outputs {
+			outputs[i] = bit(out)
+		}
+		b.WriteString("Output: " + strings.Join(outputs, "  "))
+		b.WriteString(gap)
+		b.WriteString(trace.Diagram())
+	}
+
+	b.WriteString("\nPress Left/Right to choose an input, Space to flip it (or click it), Ctrl+P to go back, Esc to quit\n")
+
+	return b.String()
+}
@@ -1,10 +1,13 @@
 package cmd
 
 import (
-	"bufio"
 	"fmt"
+	"io"
 	"os"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/VladMinzatu/bool-calculator/evaluation"
 )
@@ -13,17 +16,47 @@ const (
 	exitStr = "exit"
 )
 
+// repl is the state of a REPL session, shared by the commands
+type repl struct {
+	editor     *lineEditor
+	history    *history
+	simplifier *evaluation.Simplifier
+	session    Session
+	workspace  *workspace
+	library    *evaluation.Library // the circuits of the workspace, loaded again after :ws commands
+
+	last   *evaluation.Result  // truth table of the last expression, for :vars, :outputs and :order
+	format string              // how truth tables are printed, "text" or "json"
+	order  []string            // inputs listed first in truth tables, set with :order
+	timing bool                // print how long each expression took
+	seen   map[string]struct{} // variables and wires of the expressions so far, completed with Tab
+}
+
 func RunRepl() {
-	reader := bufio.NewReader(os.Stdin)
+	r := &repl{
+		history:    loadHistory(dataFile("history"), os.Stdout),
+		simplifier: evaluation.NewSimplifier(),
+		workspace:  openWorkspace(""),
+		format:     "text",
+		seen:       map[string]struct{}{},
+	}
+	r.library = r.workspace.loadLibrary(os.Stdout)
+	r.editor = newLineEditor(os.Stdin, os.Stdout, r.history)
+	r.editor.complete = r.complete
 	fmt.Println("Boolean Calculator REPL")
-	fmt.Printf("Enter expressions to evaluate (or '%s' to quit)\n", exitStr)
+	fmt.Printf("Enter expressions to evaluate (or '%s' to quit), :help lists the commands\n", exitStr)
 
 	for {
-		fmt.Print(">>> ")
-		input, err := reader.ReadString('\n')
+		input, err := r.editor.readLine(">>> ")
+		if err == io.EOF {
+			break
+		}
+		if err == errInterrupted {
+			continue
+		}
 		if err != nil {
 			fmt.Printf("Error reading input: %v\n", err)
-			continue
+			break
 		}
 
 		input = strings.TrimSpace(input)
@@ -34,13 +67,251 @@ func RunRepl() {
 		if input == "" {
 			continue
 		}
+		r.history.add(input)
 
-		result, err := evaluation.Compute(input)
+		if strings.HasPrefix(input, ":") {
+			err = r.runCommand(input)
+		} else {
+			err = r.evaluate(input)
+		}
 		if err != nil {
 			fmt.Printf("Error: %v\n", err)
-			continue
 		}
+	}
+}
+
+// evaluate prints the truth table of an expression, or of the residual expression for "expr where a=1, c=0"
+func (r *repl) evaluate(input string) error {
+	start := time.Now()
+	if expression, assignments, found := strings.Cut(input, " where "); found {
+		if err := r.printRestricted(expression, assignments); err != nil {
+			return err
+		}
+	} else {
+		expr, vars, wires, err := r.library.ParseExpression(input)
+		if err != nil {
+			return err
+		}
+		result, err := evaluation.ComputeWithWires(expr, vars, wires)
+		if err != nil {
+			return err
+		}
+		r.remember(vars, wires)
+		r.session.Expressions = append(r.session.Expressions, input)
+		if err := r.printResult(result); err != nil {
+			return err
+		}
+	}
+	if r.timing {
+		fmt.Printf("(%v)\n", time.Since(start).Round(time.Microsecond))
+	}
+	return nil
+}
+
+// remember keeps the names used in an expression for completion. Bus bits are completed by the name of the bus.
+func (r *repl) remember(vars evaluation.VariableSet, wires []evaluation.Wire) {
+	for name := range vars {
+		bus, _, _ := strings.Cut(name, "[")
+		r.seen[bus] = struct{}{}
+	}
+	for _, w := range wires {
+		r.seen[w.Name] = struct{}{}
+	}
+}
+
+// printResult prints a truth table in the format and input order chosen with :format and :order
+func (r *repl) printResult(result *evaluation.Result) error {
+	r.last = result
+	result, err := result.Reorder(r.orderFor(result))
+	if err != nil {
+		return err
+	}
+	if r.format == "json" {
+		data, err := evaluation.MarshalResult(result)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	fmt.Print(result.String())
+	return nil
+}
+
+// orderFor keeps the names given to :order that are inputs of the result, so the order carries over to
+// expressions with other inputs
+func (r *repl) orderFor(result *evaluation.Result) []string {
+	order := []string{}
+	for _, name := range r.order {
+		for _, v := range result.Variables {
+			if bus, _, _ := strings.Cut(v, "["); v == name || bus == name {
+				order = append(order, name)
+				break
+			}
+		}
+	}
+	return order
+}
+
+// complete finds the candidates for the word before the cursor: command names after a leading colon, the
+// choices of a command's argument, or else gate names, circuits of the workspace and the variables seen so far
+func (r *repl) complete(line []rune, pos int) (int, []string) {
+	start := pos
+	for start > 0 && isWordRune(line[start-1]) {
+		start--
+	}
+	prefix := string(line[start:pos])
+
+	names := []string{}
+	if text := string(line[:pos]); strings.HasPrefix(text, ":") {
+		name, _, hasArgs := strings.Cut(text[1:], " ")
+		if !hasArgs {
+			for name := range replCommands {
+				names = append(names, name)
+			}
+		} else if c, ok := replCommands[name]; ok && c.choices != nil {
+			// the arguments before the word being completed
+			previous := strings.Fields(string(line[len(name)+1 : start]))
+			names = c.choices(r, previous)
+		}
+	}
+	if len(names) == 0 {
+		if prefix == "" {
+			return start, nil
+		}
+		names = append(evaluation.Gates(), r.circuitNames()...)
+		for name := range r.seen {
+			names = append(names, name)
+		}
+	}
 
-		fmt.Print(result.String())
+	candidates := []string{}
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) && !slices.Contains(candidates, name) {
+			candidates = append(candidates, name)
+		}
+	}
+	sort.Strings(candidates)
+	return start, candidates
+}
+
+// circuitNames are the names of the circuits in the workspace, which can be called like gates
+func (r *repl) circuitNames() []string {
+	circuits, err := r.workspace.list()
+	if err != nil {
+		return nil
+	}
+	names := []string{}
+	for _, c := range circuits {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+// loadSession adds the rules of a saved session to the simplifier and its expressions to the history,
+// where they can be recalled with the Up key
+func (r *repl) loadSession(path string) error {
+	loaded, err := loadSession(path)
+	if err != nil {
+		return err
+	}
+	for _, rule := range loaded.Rules {
+		if err := r.simplifier.AddRule("", rule); err != nil {
+			return fmt.Errorf("rule %q: %w", rule, err)
+		}
+		r.session.Rules = append(r.session.Rules, rule)
+	}
+	for _, expression := range loaded.Expressions {
+		r.history.add(expression)
+		r.session.Expressions = append(r.session.Expressions, expression)
+	}
+	fmt.Printf("Loaded %d rules and %d expressions\n", len(loaded.Rules), len(loaded.Expressions))
+	return nil
+}
+
+// printRestricted handles "expr where a=1, c=0" by printing the residual expression and its truth table. When the
+// outputs of the expression fall apart, the parts are listed in the order of the outputs.
+func (r *repl) printRestricted(expression, assignments string) error {
+	values, err := parseAssignments(assignments)
+	if err != nil {
+		return err
+	}
+	parts, vars, err := r.library.RestrictExpression(expression, values)
+	if err != nil {
+		return err
+	}
+	result, err := evaluation.ComputeParts(parts, vars)
+	if err != nil {
+		return err
+	}
+	formatted := []string{}
+	for _, part := range parts {
+		formatted = append(formatted, evaluation.Format(part))
+	}
+	fmt.Println(strings.Join(formatted, ", "))
+	return r.printResult(result)
+}
+
+func init() {
+	registerCommand(replCommand{
+		name:  "simplify",
+		usage: "expr",
+		help:  "simplify an expression, printing every rewrite",
+		run: func(r *repl, args string) error {
+			return r.printSimplified(args)
+		},
+	})
+	registerCommand(replCommand{
+		name:  "rule",
+		usage: "pattern -> replacement",
+		help:  "add a rewrite rule for :simplify",
+		run: func(r *repl, args string) error {
+			if err := r.simplifier.AddRule("", args); err != nil {
+				return err
+			}
+			r.session.Rules = append(r.session.Rules, args)
+			return nil
+		},
+	})
+	registerCommand(replCommand{
+		name:  "trace",
+		usage: "expr a=1 b=0",
+		help:  "print the value of every node of an expression for one assignment",
+		run: func(r *repl, args string) error {
+			return r.printTrace(args)
+		},
+	})
+}
+
+// printSimplified prints every rewrite step with the rule that fired, followed by the result
+func (r *repl) printSimplified(expression string) error {
+	expr, _, _, err := r.library.ParseExpression(expression)
+	if err != nil {
+		return err
+	}
+	result, steps, err := r.simplifier.Simplify(expr)
+	for i, step := range steps {
+		fmt.Printf("%d. %v -> %v  [%v]\n", i+1, evaluation.Format(step.Before), evaluation.Format(step.After), step.Rule)
+		fmt.Printf("   %v\n", evaluation.Format(step.Expression))
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println(evaluation.Format(result))
+	return nil
+}
+
+// printTrace handles ":trace expr a=1 b=0" by printing the value of every node of the expression
+func (r *repl) printTrace(command string) error {
+	expression, assignments := cutTrailingAssignments(command)
+	values, err := parseAssignments(assignments)
+	if err != nil {
+		return err
+	}
+	trace, err := r.library.TraceExpression(expression, values)
+	if err != nil {
+		return err
 	}
+	fmt.Print(trace.String())
+	return nil
 }
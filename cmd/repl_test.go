@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/VladMinzatu/bool-calculator/evaluation"
+)
+
+// captureStdout returns what fn prints, since the REPL prints its results to stdout
+func captureStdout(t *testing.T, fn func() error) (string, error) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	done := make(chan string)
+	go func() {
+		out, _ := io.ReadAll(r)
+		done <- string(out)
+	}()
+	fnErr := fn()
+	w.Close()
+	return <-done, fnErr
+}
+
+// newWorkspace stores the circuits, given as "name expression", in a new workspace
+func newWorkspace(t *testing.T, circuits ...string) *workspace {
+	t.Helper()
+	w := &workspace{dir: t.TempDir()}
+	for _, circuit := range circuits {
+		if err := runWorkspaceCommand(w, append([]string{"add"}, strings.Fields(circuit)...), &strings.Builder{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return w
+}
+
+func TestReplCircuits(t *testing.T) {
+	w := newWorkspace(t, "sum xor(x, y)")
+	r := &repl{simplifier: evaluation.NewSimplifier(), workspace: w, library: w.loadLibrary(io.Discard), format: "text", seen: map[string]struct{}{}}
+	tests := []struct {
+		input    string
+		contains string
+	}{
+		{input: "sum(a, b)", contains: "0\t1\t1\n"},
+		{input: "sum(a, b) where a=1", contains: "not(b)\n"},
+		{input: "dmux(sum(a, b), s) where s=1", contains: "0, xor(a, b)\n"},
+		{input: ":simplify sum(a, a)", contains: "0\n"},
+		{input: ":trace sum(a, b) a=1 b=0", contains: "xor"},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			out, err := captureStdout(t, func() error {
+				if strings.HasPrefix(test.input, ":") {
+					return r.runCommand(test.input)
+				}
+				return r.evaluate(test.input)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(out, test.contains) {
+				t.Errorf("expected %q in the output\n%s", test.contains, out)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/VladMinzatu/bool-calculator/evaluation"
+)
+
+// RunTest runs script files, or stdin if no files are given, and reports their failures like go test.
+// It returns the process exit code: 1 if any line failed.
+func RunTest(args []string) int {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	verbose := flags.Bool("v", false, "also report the assertions that hold and the truth tables of expression lines")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		return testScript(os.Stdin, "<stdin>", *verbose)
+	}
+	exitCode := 0
+	for _, path := range flags.Args() {
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exitCode = 1
+			continue
+		}
+		exitCode = max(exitCode, testScript(f, path, *verbose))
+		f.Close()
+	}
+	return exitCode
+}
+
+func testScript(r io.Reader, path string, verbose bool) int {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+		return 1
+	}
+	assertions, failedAssertions, errs := 0, 0, 0
+	for _, step := range evaluation.RunScript(string(src)) {
+		var assertionErr *evaluation.AssertionError
+		switch {
+		case errors.As(step.Err, &assertionErr):
+			failedAssertions++
+		case step.Err != nil:
+			errs++
+		}
+		if step.Assertion {
+			assertions++
+		}
+
+		if step.Failed() {
+			fmt.Printf("--- FAIL: %s:%d: %s\n    %v\n", path, step.Line, step.Source, step.Err)
+		} else if verbose && step.Assertion {
+			fmt.Printf("--- PASS: %s:%d: %s\n", path, step.Line, step.Source)
+		} else if verbose && step.Table != "" {
+			fmt.Printf("=== %s:%d: %s\n%s", path, step.Line, step.Source, indent(step.Table))
+		}
+	}
+
+	if failedAssertions == 0 && errs == 0 {
+		fmt.Printf("ok  \t%s\t%d assertions\n", path, assertions)
+		return 0
+	}
+	summary := fmt.Sprintf("%d of %d assertions failed", failedAssertions, assertions)
+	if errs > 0 {
+		summary += fmt.Sprintf(", %d lines with errors", errs)
+	}
+	fmt.Printf("FAIL\t%s\t%s\n", path, summary)
+	return 1
+}
+
+func indent(text string) string {
+	lines := strings.SplitAfter(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = "    " + line
+		}
+	}
+	return strings.Join(lines, "")
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/VladMinzatu/bool-calculator/server"
+)
+
+// RunServe serves the HTTP API until it is interrupted. It returns the process exit code.
+func RunServe(args []string) int {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := flags.String("addr", "localhost:8080", "address to listen on")
+	maxBody := flags.Int64("max-body", 64<<10, "maximum size of a request body in bytes")
+	maxVariables := flags.Int("max-vars", 20, "maximum number of input bits of evaluated expressions")
+	maxConcurrent := flags.Int("max-concurrent", 0, "requests handled at once, others are rejected with 503 (default the number of CPUs)")
+	streamRows := flags.Int("stream-rows", 4096, "truth tables with more rows are streamed as newline delimited JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 {
+		fmt.Fprintln(os.Stderr, "serve takes no arguments")
+		return 2
+	}
+
+	handler := server.New(server.Config{
+		MaxBodyBytes:  *maxBody,
+		MaxVariables:  *maxVariables,
+		MaxConcurrent: *maxConcurrent,
+		StreamRows:    *streamRows,
+	})
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	// the requests in flight are finished before returning, ListenAndServe returns as soon as the shutdown starts
+	shutdownErr := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		shutdownErr <- srv.Shutdown(shutdown)
+	}()
+
+	fmt.Fprintf(os.Stderr, "Serving the API on http://%s, see /openapi.json\n", *addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := <-shutdownErr; err != nil {
+		fmt.Fprintln(os.Stderr, "shutting down:", err)
+		return 1
+	}
+	return 0
+}
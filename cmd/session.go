@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Session is what :save writes and :load reads in both the REPL and the TUI: the rewrite rules defined with
+// :rule and the expressions that were entered, in order. Session files are plain text, with a :rule line for
+// every rule followed by one expression per line, so they can also be written by hand.
+type Session struct {
+	Rules       []string
+	Expressions []string
+}
+
+func init() {
+	registerCommand(replCommand{
+		name:  "save",
+		usage: "file",
+		help:  "save the rules and expressions of the session",
+		run: func(r *repl, args string) error {
+			return saveSession(args, r.session)
+		},
+	})
+	registerCommand(replCommand{
+		name:  "load",
+		usage: "file",
+		help:  "load the rules and expressions of a saved session",
+		run: func(r *repl, args string) error {
+			return r.loadSession(args)
+		},
+	})
+}
+
+func (s Session) write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "# bool-calculator session")
+	for _, rule := range s.Rules {
+		fmt.Fprintln(bw, ":rule "+rule)
+	}
+	for _, expression := range s.Expressions {
+		fmt.Fprintln(bw, expression)
+	}
+	return bw.Flush()
+}
+
+func readSession(r io.Reader) (Session, error) {
+	session := Session{}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if rule, found := strings.CutPrefix(line, ":rule "); found {
+			session.Rules = append(session.Rules, rule)
+		} else if strings.HasPrefix(line, ":") {
+			return Session{}, fmt.Errorf("line %d: unknown command %q in session", n, line)
+		} else {
+			session.Expressions = append(session.Expressions, line)
+		}
+	}
+	return session, scanner.Err()
+}
+
+func saveSession(path string, session Session) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := session.write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func loadSession(path string) (Session, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Session{}, err
+	}
+	defer f.Close()
+	session, err := readSession(f)
+	if err != nil {
+		return Session{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return session, nil
+}
+
+// dataDir is where the history and the last TUI session are kept, following the XDG base directory spec
+func dataDir() (string, error) {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dir, "bool-calculator"), nil
+}
+
+// dataFile is the path of a file in the data directory, or "" if there is no home directory
+func dataFile(name string) string {
+	dir, err := dataDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, name)
+}
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/VladMinzatu/bool-calculator/evaluation"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
+)
+
+// rowFilter limits the truth table in the TUI to some of its rows
+type rowFilter int
+
+const (
+	filterNone  rowFilter = iota
+	filterTrue            // rows where an output is 1
+	filterFalse           // rows where every output is 0
+)
+
+func (f rowFilter) String() string {
+	switch f {
+	case filterTrue:
+		return "rows with output 1"
+	case filterFalse:
+		return "rows with output 0"
+	default:
+		return "all rows"
+	}
+}
+
+func (f rowFilter) next() rowFilter {
+	return (f + 1) % 3
+}
+
+func (f rowFilter) keeps(outputs []bool) bool {
+	switch f {
+	case filterTrue:
+		return isTrue(outputs)
+	case filterFalse:
+		return !isTrue(outputs)
+	default:
+		return true
+	}
+}
+
+// isTrue tells whether a row of the truth table has an output that is 1
+func isTrue(outputs []bool) bool {
+	return slices.Contains(outputs, true)
+}
+
+// newTruthTable creates the table widget. Only keys that don't edit the expression move the cursor.
+func newTruthTable() table.Model {
+	keys := table.KeyMap{
+		LineUp:       key.NewBinding(key.WithKeys("up"), key.WithHelp("↑", "up")),
+		LineDown:     key.NewBinding(key.WithKeys("down"), key.WithHelp("↓", "down")),
+		PageUp:       key.NewBinding(key.WithKeys("pgup"), key.WithHelp("pgup", "page up")),
+		PageDown:     key.NewBinding(key.WithKeys("pgdown"), key.WithHelp("pgdn", "page down")),
+		HalfPageUp:   key.NewBinding(key.WithDisabled()),
+		HalfPageDown: key.NewBinding(key.WithDisabled()),
+		GotoTop:      key.NewBinding(key.WithDisabled()),
+		GotoBottom:   key.NewBinding(key.WithDisabled()),
+	}
+	return table.New(table.WithKeyMap(keys), table.WithFocused(true))
+}
+
+// updateTable fills the table with the rows of the result that pass the filter. The cursor stays
+// at the same position, so re-evaluating doesn't scroll the table.
+func (m *model) updateTable() {
+	m.rows = nil
+	m.table.SetRows(nil)
+	if m.result == nil {
+		m.table.SetColumns(nil)
+		return
+	}
+
+	header := m.result.Header()
+	widths := make([]int, len(header))
+	for i, title := range header {
+		widths[i] = len(title)
+	}
+	rows := []table.Row{}
+	for i, outputs := range m.result.Outputs {
+		if !m.filter.keeps(outputs) {
+			continue
+		}
+		cells := m.result.Cells(i, evaluation.RadixBinary)
+		for j, cell := range cells {
+			widths[j] = max(widths[j], len(cell))
+		}
+		rows = append(rows, cells)
+		m.rows = append(m.rows, i)
+	}
+
+	columns := make([]table.Column, len(header))
+	total := 0
+	for i, title := range header {
+		columns[i] = table.Column{Title: title, Width: widths[i]}
+		total += widths[i] + 2 // cell padding
+	}
+	m.table.SetColumns(columns)
+	m.table.SetRows(rows)
+	m.table.SetWidth(total)
+	m.table.SetCursor(m.table.Cursor())
+}
+
+// selectedRow is the row of the result under the cursor, or -1 if the table is empty
+func (m model) selectedRow() int {
+	cursor := m.table.Cursor()
+	if cursor < 0 || cursor >= len(m.rows) {
+		return -1
+	}
+	return m.rows[cursor]
+}
+
+// footer counts the true and false rows of the whole truth table
+func (m model) footer() string {
+	trueRows := 0
+	for _, outputs := range m.result.Outputs {
+		if isTrue(outputs) {
+			trueRows++
+		}
+	}
+	footer := fmt.Sprintf("%d rows: %d true, %d false", len(m.result.Outputs), trueRows, len(m.result.Outputs)-trueRows)
+	if m.filter != filterNone {
+		footer += fmt.Sprintf(" | showing %d %v", len(m.rows), m.filter)
+	}
+	return footer
+}
+
+// findRow moves the cursor to the first shown row at or after from whose inputs have the values searched for,
+// wrapping around at the end of the table
+func (m *model) findRow(from int) error {
+	values, err := parseAssignments(m.search.Value())
+	if err != nil {
+		return err
+	}
+	for name := range values {
+		if !slices.Contains(m.result.Variables, name) {
+			return fmt.Errorf("%v is not an input of the expression", name)
+		}
+	}
+	for k := range m.rows {
+		i := (from + k) % len(m.rows)
+		row := m.result.Row(m.rows[i])
+		matches := true
+		for name, value := range values {
+			matches = matches && row[name] == value
+		}
+		if matches {
+			m.table.SetCursor(i)
+			return nil
+		}
+	}
+	return errors.New("no row matches the search")
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/VladMinzatu/bool-calculator/evaluation"
+	"github.com/charmbracelet/bubbles/table"
 	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
@@ -13,6 +14,7 @@ import (
 
 var (
 	errorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF0000"))
+	traceStyle = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).Padding(0, 1)
 	gap        = "\n\n"
 )
 
@@ -28,30 +30,95 @@ func (app TerminalApp) Run() {
 type errMsg error
 
 type model struct {
-	input  textinput.Model
-	output textarea.Model
-	result *evaluation.Result
-	err    error
+	input     textinput.Model
+	editor    textarea.Model // replaces the input in multi-line mode, which keeps a copy of the expression
+	multiline bool
+	table     table.Model
+	result    *evaluation.Result
+	err       error
+	width     int
+	height    int
+	library   *evaluation.Library // the circuits of the workspace, which the input can call like gates
+
+	rows      []int // rows of the result shown in the table, after filtering
+	filter    rowFilter
+	searching bool
+	search    textinput.Model
+	searchErr error
+	tracing   bool // show the evaluation of the selected row next to the table
+
+	// playground mode, where each input is a switch
+	playground     bool
+	values         map[string]bool
+	selectedSwitch int // selected switch
+
+	// step clock mode for expressions with sequential gates
+	clockMode bool
+	stepInput textinput.Model
+	sim       *evaluation.Simulator
+	trace     []evaluation.Step
+
+	// browser of the circuits of the workspace
+	browsing     bool
+	circuits     []Circuit
+	circuitTable table.Model
+	browseErr    error
+
+	// expressions entered with Enter and rules of loaded sessions, saved to the data directory on quit
+	session Session
+	status  string // result of the last :save or :load
 }
 
 func NewModel() model {
 	ti := textinput.New()
 	ti.Placeholder = "Enter a boolean expression..."
 	ti.Focus()
-	ti.CharLimit = 256
-	ti.Width = 256
+	ti.CharLimit = 0
 
 	ta := textarea.New()
-	ta.Placeholder = "Output will appear here..."
+	ta.CharLimit = 0
 	ta.ShowLineNumbers = false
-	ta.Blur()
 
-	return model{
-		input:  ti,
-		output: ta,
-		result: nil,
-		err:    nil,
+	search := textinput.New()
+	search.Placeholder = "Inputs to look for, e.g. a=1 c=0"
+	search.CharLimit = 256
+
+	si := textinput.New()
+	si.Placeholder = "Inputs for the next cycle, e.g. a=1 b=0"
+	si.CharLimit = 256
+	si.Width = 256
+
+	// Ctrl+F, Ctrl+B, Ctrl+K, Ctrl+P and Ctrl+T are keys of the TUI, so the inputs don't use them for editing
+	for _, keys := range []*textinput.KeyMap{&ti.KeyMap, &search.KeyMap, &si.KeyMap} {
+		keys.CharacterForward.SetKeys("right")
+		keys.CharacterBackward.SetKeys("left")
+		keys.DeleteAfterCursor.SetEnabled(false)
+		keys.PrevSuggestion.SetKeys("up")
 	}
+	ta.KeyMap.CharacterForward.SetKeys("right")
+	ta.KeyMap.CharacterBackward.SetKeys("left")
+	ta.KeyMap.DeleteAfterCursor.SetEnabled(false)
+	ta.KeyMap.LinePrevious.SetKeys("up")
+	ta.KeyMap.TransposeCharacterBackward.SetEnabled(false)
+
+	m := model{
+		input:     ti,
+		editor:    ta,
+		table:     newTruthTable(),
+		result:    nil,
+		err:       nil,
+		search:    search,
+		stepInput: si,
+	}
+	var warnings strings.Builder
+	m.library = openWorkspace("").loadLibrary(&warnings)
+	m.status = strings.TrimSpace(warnings.String())
+	if path := dataFile("session"); path != "" {
+		if session, err := loadSession(path); err == nil {
+			m.restore(session)
+		}
+	}
+	return m
 }
 
 func (m model) Init() tea.Cmd {
@@ -60,66 +127,385 @@ func (m model) Init() tea.Cmd {
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
-	var cmds []tea.Cmd
-	var cmd tea.Cmd
-
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
-		m.output.SetWidth(msg.Width)
-		m.output.SetHeight(msg.Height - 2*lipgloss.Height(gap) - 1) // 1 for input
+		m.width, m.height = msg.Width, msg.Height
+		m.table.SetHeight(max(m.height-10, 3)) // the lines around the table
+		m.circuitTable.SetHeight(max(m.height-8, 3))
+		m.editor.SetWidth(m.width - 2) // the prompt is drawn by editorView
 	case tea.KeyMsg:
 		switch msg.String() {
-		case "ctrl+c", "esc":
-			return m, tea.Quit
+		case "ctrl+c":
+			return m.quit()
+		case "esc":
+			if m.searching {
+				return m.closeSearch()
+			}
+			if m.browsing {
+				return m.closeBrowser()
+			}
+			return m.quit()
+		case "ctrl+k":
+			if !m.playground && !m.browsing {
+				return m.toggleClockMode()
+			}
+		case "ctrl+p":
+			if !m.clockMode && !m.browsing {
+				return m.togglePlayground()
+			}
+		case "ctrl+b":
+			if m.browsing {
+				return m.closeBrowser()
+			}
+			if !m.playground && !m.clockMode && !m.searching {
+				return m.openBrowser()
+			}
+		}
+		if m.browsing {
+			return m.updateBrowser(msg)
+		}
+		if m.playground {
+			return m.updatePlayground(msg)
+		}
+		if m.clockMode {
+			return m.updateClockMode(msg)
+		}
+		if m.searching {
+			return m.updateSearch(msg)
+		}
+		switch msg.String() {
+		case "up", "down":
+			if !m.multiline {
+				var cmd tea.Cmd
+				m.table, cmd = m.table.Update(msg)
+				return m, cmd
+			}
+		case "pgup", "pgdown":
+			var cmd tea.Cmd
+			m.table, cmd = m.table.Update(msg)
+			return m, cmd
+		case "tab":
+			return m.completeGate()
+		case "ctrl+l":
+			return m.toggleMultiline()
+		case "ctrl+o":
+			m.filter = m.filter.next()
+			m.updateTable()
+			return m, nil
+		case "ctrl+f":
+			if m.result == nil {
+				return m, nil
+			}
+			m.searching = true
+			m.input.Blur()
+			return m, m.search.Focus()
+		case "ctrl+t":
+			m.tracing = !m.tracing
+			return m, nil
+		case "enter":
+			if !m.multiline {
+				return m.submit()
+			}
 		}
 
+	case tea.MouseMsg:
+		if m.playground {
+			return m.updatePlayground(msg)
+		}
+		return m, nil
 	case errMsg:
 		m.err = msg
 		return m, nil
 	}
 
-	m.input, cmd = m.input.Update(msg)
-	cmds = append(cmds, cmd)
-
-	// Validate input as user types
-	m.err = m.validateInput()
-	if m.err == nil {
-		m.output.SetValue(m.result.String())
+	before := m.input.Value()
+	var cmd tea.Cmd
+	if m.multiline {
+		m.editor, cmd = m.editor.Update(msg)
 	} else {
-		m.err = fmt.Errorf("*%v", m.err)
-		m.output.SetValue("")
+		m.input, cmd = m.input.Update(msg)
 	}
+	m.inputChanged(before)
+	return m, cmd
+}
 
-	m.output, cmd = m.output.Update(msg)
-	cmds = append(cmds, cmd)
-
-	return m, tea.Batch(cmds...)
+// inputChanged validates the input as the user types, except for commands
+func (m *model) inputChanged(before string) {
+	if m.multiline {
+		m.input.SetValue(m.editor.Value())
+	}
+	if m.input.Value() == before {
+		return
+	}
+	m.status = ""
+	if isCommand(m.input.Value()) {
+		m.result, m.err = nil, nil
+	} else if m.err = m.validateInput(); m.err != nil {
+		m.err = fmt.Errorf("*%v", m.err)
+	}
+	m.updateTable()
 }
 
 func (m model) View() string {
+	if m.clockMode {
+		return m.clockView()
+	}
+	if m.playground {
+		return m.playgroundView()
+	}
+	if m.browsing {
+		return m.browserView()
+	}
+
 	var b strings.Builder
 
 	b.WriteString("Enter boolean expression:")
 	b.WriteString(gap)
-	b.WriteString(m.input.View())
+	b.WriteString(m.editorView())
 	b.WriteString(gap)
 
+	if m.status != "" {
+		b.WriteString(m.status)
+		b.WriteString(gap)
+	}
 	if m.err != nil {
 		b.WriteString(errorStyle.Render(m.err.Error()))
 		b.WriteString(gap)
-	} else {
+	} else if m.result != nil {
 		b.WriteString("Result:\n")
-		b.WriteString(m.output.View())
+		b.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, m.table.View(), m.traceView()))
+		b.WriteString("\n" + m.footer() + "\n")
 	}
 
-	b.WriteString("\nPress Esc to quit\n")
+	if m.searching {
+		b.WriteString("Search: " + m.search.View())
+		if m.searchErr != nil {
+			b.WriteString("  " + errorStyle.Render(m.searchErr.Error()))
+		}
+		b.WriteString("\nPress Enter for the next match, Esc to stop searching\n")
+		return b.String()
+	}
+	b.WriteString("\nPress Up/Down to move, Ctrl+O to filter, Ctrl+F to search, Ctrl+T to trace the selected row,\n")
+	b.WriteString("Ctrl+P for the playground, Ctrl+K to step the clock, Ctrl+B to browse the circuits of the workspace,\n")
+	b.WriteString("Tab to complete a gate, Ctrl+L for multiple lines, Left/Right to move the cursor (Ctrl+F, Ctrl+B and Ctrl+K don't),\n")
+	b.WriteString("Enter to keep the expression in the session, :save file or :load file to write or read it, Esc to quit\n")
 
 	return b.String()
 }
 
 func (m *model) validateInput() error {
-	result, err := evaluation.Compute(m.input.Value())
+	result, err := m.library.Compute(m.input.Value())
 	m.result = result
 	m.err = err
 	return err
 }
+
+func (m model) updateSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+f":
+		return m.closeSearch()
+	case "enter":
+		m.searchErr = m.findRow(m.table.Cursor() + 1)
+		return m, nil
+	case "up", "down", "pgup", "pgdown":
+		var cmd tea.Cmd
+		m.table, cmd = m.table.Update(msg)
+		return m, cmd
+	}
+	before := m.search.Value()
+	var cmd tea.Cmd
+	m.search, cmd = m.search.Update(msg)
+	if m.search.Value() != before {
+		m.searchErr = m.findRow(0)
+	}
+	return m, cmd
+}
+
+func (m model) closeSearch() (tea.Model, tea.Cmd) {
+	m.searching = false
+	m.searchErr = nil
+	m.search.Blur()
+	return m, m.input.Focus()
+}
+
+// submit runs :save and :load, or keeps a valid expression in the session so it's saved on quit
+func (m model) submit() (tea.Model, tea.Cmd) {
+	value := strings.TrimSpace(m.input.Value())
+	if path, found := strings.CutPrefix(value, ":save "); found {
+		m.err = saveSession(strings.TrimSpace(path), m.session)
+		if m.err == nil {
+			m.status = fmt.Sprintf("Saved %d rules and %d expressions", len(m.session.Rules), len(m.session.Expressions))
+		}
+		return m, nil
+	}
+	if path, found := strings.CutPrefix(value, ":load "); found {
+		session, err := loadSession(strings.TrimSpace(path))
+		if err != nil {
+			m.err = err
+			return m, nil
+		}
+		m.restore(session)
+		m.status = fmt.Sprintf("Loaded %d rules and %d expressions", len(session.Rules), len(session.Expressions))
+		return m, nil
+	}
+	if isCommand(value) {
+		m.err = fmt.Errorf("unknown command %q, use :save file or :load file", value)
+		return m, nil
+	}
+	if m.result != nil && m.err == nil {
+		m.keep(value)
+	}
+	return m, nil
+}
+
+// restore replaces the session and shows its last expression
+func (m *model) restore(session Session) {
+	m.session = session
+	m.input.SetValue("")
+	m.editor.SetValue("")
+	m.result, m.err = nil, nil
+	if n := len(session.Expressions); n > 0 {
+		m.input.SetValue(session.Expressions[n-1])
+		m.editor.SetValue(session.Expressions[n-1])
+		if m.err = m.validateInput(); m.err != nil {
+			m.err = fmt.Errorf("*%v", m.err)
+		}
+	}
+	m.updateTable()
+}
+
+// keep adds an expression to the session, unless it's the last one already
+func (m *model) keep(expression string) {
+	expressions := m.session.Expressions
+	if len(expressions) == 0 || expressions[len(expressions)-1] != expression {
+		m.session.Expressions = append(expressions, expression)
+	}
+}
+
+// quit saves the session with the current expression, so the next run starts where this one stopped
+func (m model) quit() (tea.Model, tea.Cmd) {
+	if value := strings.TrimSpace(m.input.Value()); m.result != nil && m.err == nil && !isCommand(value) {
+		m.keep(value)
+	}
+	if path := dataFile("session"); path != "" {
+		saveSession(path, m.session)
+	}
+	return m, tea.Quit
+}
+
+func isCommand(input string) bool {
+	return strings.HasPrefix(strings.TrimSpace(input), ":")
+}
+
+// traceView shows the value of every node of the expression for the selected row
+func (m model) traceView() string {
+	row := m.selectedRow()
+	if !m.tracing || row < 0 {
+		return ""
+	}
+	trace, err := m.library.TraceExpression(m.input.Value(), m.result.Row(row))
+	if err != nil {
+		return traceStyle.Render(errorStyle.Render(err.Error()))
+	}
+	return traceStyle.Render(fmt.Sprintf("Row %d\n\n%s", row, strings.TrimSuffix(trace.String(), "\n")))
+}
+
+func (m model) toggleClockMode() (tea.Model, tea.Cmd) {
+	if m.clockMode {
+		m.clockMode = false
+		m.stepInput.Blur()
+		return m, m.input.Focus()
+	}
+	sim, err := m.library.NewSimulator(m.input.Value())
+	if err != nil {
+		m.err = fmt.Errorf("*%v", err)
+		return m, nil
+	}
+	m.clockMode = true
+	m.sim = sim
+	m.trace = nil
+	m.err = nil
+	m.input.Blur()
+	return m, m.stepInput.Focus()
+}
+
+func (m model) updateClockMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		inputs, err := parseAssignments(m.stepInput.Value())
+		if err == nil {
+			var step evaluation.Step
+			step, err = m.sim.Step(inputs)
+			if err == nil {
+				m.trace = append(m.trace, step)
+			}
+		}
+		m.err = err
+		return m, nil
+	case "ctrl+r":
+		m.sim.Reset()
+		m.trace = nil
+		m.err = nil
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.stepInput, cmd = m.stepInput.Update(msg)
+	return m, cmd
+}
+
+func (m model) clockView() string {
+	var b strings.Builder
+
+	b.WriteString("Clock mode for: " + m.input.Value())
+	b.WriteString(gap)
+	b.WriteString(m.stepInput.View())
+	b.WriteString(gap)
+
+	if m.err != nil {
+		b.WriteString(errorStyle.Render(m.err.Error()))
+		b.WriteString(gap)
+	}
+	b.WriteString(formatTrace(m.sim, m.trace))
+
+	b.WriteString("\nPress Enter to step, Ctrl+R to reset, Ctrl+K to go back, Esc to quit\n")
+
+	return b.String()
+}
+
+// formatTrace renders the cycles of a simulation as a table of inputs, state and outputs
+func formatTrace(sim *evaluation.Simulator, trace []evaluation.Step) string {
+	var b strings.Builder
+	inputs, state := sim.Inputs(), sim.StateVariables()
+
+	b.WriteString("Cycle")
+	for _, name := range append(append([]string{}, inputs...), state...) {
+		b.WriteString("\t" + name)
+	}
+	b.WriteString("\tOutput\n")
+
+	for _, step := range trace {
+		b.WriteString(fmt.Sprint(step.Cycle))
+		for _, name := range inputs {
+			b.WriteString("\t" + bit(step.Inputs[name]))
+		}
+		for _, name := range state {
+			b.WriteString("\t" + bit(step.State[name]))
+		}
+		b.WriteString("\t")
+		for i, out := range step.Outputs {
+			if i > 0 {
+				b.WriteString("  ")
+			}
+			b.WriteString(bit(out))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func bit(value bool) string {
+	if value {
+		return "1"
+	}
+	return "0"
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// newTestModel opens the TUI on a workspace with the circuits and types the expression
+func newTestModel(t *testing.T, expression string, circuits ...string) model {
+	t.Helper()
+	t.Setenv("BOOL_CALCULATOR_WORKSPACE", newWorkspace(t, circuits...).dir)
+	m := NewModel()
+	return press(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(expression)})
+}
+
+func press(m model, msg tea.KeyMsg) model {
+	updated, _ := m.Update(msg)
+	return updated.(model)
+}
+
+func TestTUICircuits(t *testing.T) {
+	m := newTestModel(t, "sum(a, b)", "sum xor(x, y)")
+	if m.err != nil {
+		t.Fatalf("unexpected error %v", m.err)
+	}
+
+	traced := press(m, tea.KeyMsg{Type: tea.KeyCtrlT})
+	if view := traced.traceView(); !strings.Contains(view, "Row 0") || !strings.Contains(view, "xor") {
+		t.Errorf("unexpected trace\n%s", view)
+	}
+
+	playground := press(m, tea.KeyMsg{Type: tea.KeyCtrlP})
+	if view := playground.playgroundView(); !strings.Contains(view, "Output: 0") {
+		t.Errorf("unexpected playground\n%s", view)
+	}
+
+	m = newTestModel(t, "dff(sum(a, b))", "sum xor(x, y)")
+	clocked, _ := m.toggleClockMode()
+	if m := clocked.(model); !m.clockMode || m.err != nil {
+		t.Errorf("expected clock mode, got error %v", m.err)
+	}
+}
+
+func TestTUIKeysDontEdit(t *testing.T) {
+	m := newTestModel(t, "")
+	bindings := []key.Binding{}
+	for _, keys := range []textinput.KeyMap{m.input.KeyMap, m.search.KeyMap, m.stepInput.KeyMap} {
+		bindings = append(bindings, keys.CharacterForward, keys.CharacterBackward, keys.DeleteAfterCursor, keys.PrevSuggestion)
+	}
+	editor := m.editor.KeyMap
+	bindings = append(bindings, editor.CharacterForward, editor.CharacterBackward, editor.DeleteAfterCursor,
+		editor.LinePrevious, editor.TransposeCharacterBackward)
+	for _, msg := range []tea.KeyMsg{{Type: tea.KeyCtrlF}, {Type: tea.KeyCtrlB}, {Type: tea.KeyCtrlK}, {Type: tea.KeyCtrlP}, {Type: tea.KeyCtrlT}} {
+		if key.Matches(msg, bindings...) {
+			t.Errorf("%s is a key of the TUI, but it also edits the input", msg)
+		}
+	}
+
+	// the arrows still move the cursor
+	m = press(newTestModel(t, "ab"), tea.KeyMsg{Type: tea.KeyLeft})
+	if m.input.Position() != 1 {
+		t.Errorf("got the cursor at %d after Left, expected 1", m.input.Position())
+	}
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/VladMinzatu/bool-calculator/evaluation"
+)
+
+// RunVCD writes a Value Change Dump of an expression. Combinational expressions step through their
+// truth table. Expressions with sequential gates are simulated with one line of inputs per clock
+// cycle, like "a=1 b=0", read from standard input. It returns the process exit code.
+func RunVCD(args []string) int {
+	flags := flag.NewFlagSet("vcd", flag.ContinueOnError)
+	internal := flags.Bool("internal", false, "also record the output of every gate inside the expression")
+	timescale := flags.String("timescale", "1ns", "duration of one time step")
+	output := flags.String("o", "", "write to this file instead of stdout")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "expected one expression")
+		return 2
+	}
+
+	opts := evaluation.VCDOptions{Timescale: *timescale, Internal: *internal}
+	if *output == "" {
+		if err := writeVCD(os.Stdout, flags.Arg(0), os.Stdin, opts); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	err = writeVCD(f, flags.Arg(0), os.Stdin, opts)
+	// the file is only complete once it's closed
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func writeVCD(w io.Writer, expression string, cycles io.Reader, opts evaluation.VCDOptions) error {
+	sim, err := evaluation.NewSimulator(expression)
+	if err != nil {
+		return err
+	}
+	if len(sim.StateVariables()) == 0 {
+		return evaluation.WriteTruthTableVCD(w, expression, opts)
+	}
+
+	trace := []evaluation.Step{}
+	scanner := bufio.NewScanner(cycles)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		inputs, err := parseAssignments(scanner.Text())
+		if err == nil {
+			var step evaluation.Step
+			step, err = sim.Step(inputs)
+			trace = append(trace, step)
+		}
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return evaluation.WriteSimulationVCD(w, sim, trace, opts)
+}
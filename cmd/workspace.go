@@ -0,0 +1,410 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/VladMinzatu/bool-calculator/evaluation"
+)
+
+// Circuit is an expression stored in the workspace under a name. Stored circuits can be called like gates in the
+// REPL, with their inputs in the given order (see evaluation.Library).
+type Circuit struct {
+	Name        string    `json:"name"`
+	Expression  string    `json:"expression"`
+	Inputs      []string  `json:"inputs,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+	Created     time.Time `json:"created"`
+	Updated     time.Time `json:"updated"`
+}
+
+// workspace is a directory with a JSON file for every circuit, so circuits can be shared or kept in version control
+type workspace struct {
+	dir string
+}
+
+// openWorkspace uses the given directory, or $BOOL_CALCULATOR_WORKSPACE, or the workspace in the data directory
+func openWorkspace(dir string) *workspace {
+	if dir == "" {
+		dir = os.Getenv("BOOL_CALCULATOR_WORKSPACE")
+	}
+	if dir == "" {
+		dir = dataFile("workspace")
+	}
+	return &workspace{dir: dir}
+}
+
+func (w *workspace) path(name string) string {
+	return filepath.Join(w.dir, name+".json")
+}
+
+// list returns the circuits sorted by name. A workspace that doesn't exist yet is empty.
+func (w *workspace) list() ([]Circuit, error) {
+	paths, err := filepath.Glob(filepath.Join(w.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	circuits := []Circuit{}
+	for _, path := range paths {
+		c, err := readCircuit(path)
+		if err != nil {
+			return nil, err
+		}
+		circuits = append(circuits, c)
+	}
+	sort.Slice(circuits, func(i, j int) bool {
+		return circuits[i].Name < circuits[j].Name
+	})
+	return circuits, nil
+}
+
+func (w *workspace) get(name string) (Circuit, error) {
+	if name == "" || strings.ContainsAny(name, `./\`) {
+		return Circuit{}, fmt.Errorf("invalid circuit name %q", name)
+	}
+	c, err := readCircuit(w.path(name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return Circuit{}, fmt.Errorf("no circuit named %s in the workspace", name)
+	}
+	return c, err
+}
+
+func readCircuit(path string) (Circuit, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Circuit{}, err
+	}
+	var c Circuit
+	if err := json.Unmarshal(data, &c); err != nil {
+		return Circuit{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if want := strings.TrimSuffix(filepath.Base(path), ".json"); c.Name != want {
+		return Circuit{}, fmt.Errorf("%s: the circuit is named %q, but the file is for %q", path, c.Name, want)
+	}
+	return c, nil
+}
+
+// put checks the circuit, and the circuits that use it, and writes it
+func (w *workspace) put(c Circuit) error {
+	circuits, err := w.others(c.Name)
+	if err != nil {
+		return err
+	}
+	if err := checkCircuits(append(circuits, c)); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(w.dir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(w.path(c.Name), append(data, '\n'), 0o644)
+}
+
+// remove deletes a circuit, unless other circuits use it
+func (w *workspace) remove(name string) error {
+	if _, err := w.get(name); err != nil {
+		return err
+	}
+	circuits, err := w.others(name)
+	if err != nil {
+		return err
+	}
+	if err := checkCircuits(circuits); err != nil {
+		return fmt.Errorf("can't remove %s, the other circuits need it: %w", name, err)
+	}
+	return os.Remove(w.path(name))
+}
+
+// others lists the circuits except the one with the given name
+func (w *workspace) others(name string) ([]Circuit, error) {
+	circuits, err := w.list()
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(circuits, func(c Circuit) bool {
+		return c.Name == name
+	}), nil
+}
+
+// checkCircuits makes sure that every circuit can be used, so a change doesn't break the circuits calling it
+func checkCircuits(circuits []Circuit) error {
+	library, err := newLibrary(circuits)
+	if err != nil {
+		return err
+	}
+	for _, c := range circuits {
+		if _, err := library.Signature(c.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// library makes the circuits of the workspace available as gates
+func (w *workspace) library() (*evaluation.Library, error) {
+	circuits, err := w.list()
+	if err != nil {
+		return nil, err
+	}
+	return newLibrary(circuits)
+}
+
+// loadLibrary is like library, but skips the circuits that can't be read or used, with a warning for each, so that
+// a broken file doesn't keep the other circuits from being called
+func (w *workspace) loadLibrary(warnings io.Writer) *evaluation.Library {
+	paths, _ := filepath.Glob(filepath.Join(w.dir, "*.json"))
+	circuits := []Circuit{}
+	for _, path := range paths {
+		c, err := readCircuit(path)
+		if err != nil {
+			fmt.Fprintf(warnings, "Warning: skipping a circuit of the workspace: %v\n", err)
+			continue
+		}
+		circuits = append(circuits, c)
+	}
+	// a circuit that is skipped can break the circuits calling it, so they are checked again without it
+	for {
+		library := evaluation.NewLibrary()
+		usable := []Circuit{}
+		for _, c := range circuits {
+			if err := library.Add(c.Name, c.Expression, c.Inputs); err != nil {
+				fmt.Fprintf(warnings, "Warning: skipping circuit %s of the workspace: %v\n", c.Name, err)
+				continue
+			}
+			usable = append(usable, c)
+		}
+		circuits = slices.DeleteFunc(usable, func(c Circuit) bool {
+			_, err := library.Signature(c.Name)
+			if err != nil {
+				fmt.Fprintf(warnings, "Warning: skipping circuit %s of the workspace: %v\n", c.Name, err)
+			}
+			return err != nil
+		})
+		if len(circuits) == len(usable) {
+			return library
+		}
+	}
+}
+
+func newLibrary(circuits []Circuit) (*evaluation.Library, error) {
+	library := evaluation.NewLibrary()
+	for _, c := range circuits {
+		if err := library.Add(c.Name, c.Expression, c.Inputs); err != nil {
+			return nil, err
+		}
+	}
+	return library, nil
+}
+
+// expand returns the expression of a circuit with the circuits it calls replaced by their expressions, so that it
+// can be used where the workspace isn't available
+func expand(library *evaluation.Library, c Circuit) (string, error) {
+	if _, _, err := evaluation.ParseExpression(c.Expression); err == nil {
+		return c.Expression, nil
+	}
+	expr, _, wires, err := library.ParseExpression(c.Expression)
+	if err != nil {
+		return "", err
+	}
+	return evaluation.FormatWithWires(expr, wires, evaluation.FormatOptions{}), nil
+}
+
+const workspaceUsage = `workspace commands:
+  list [tag]                 list the circuits, or those with a tag
+  show name                  show a circuit and its truth table
+  add name expression        store an expression as a new circuit
+  update name expression     change the expression of a circuit
+  rm name                    delete a circuit
+  describe name [text]       set the description of a circuit
+  tag name [tag...]          set the tags of a circuit
+  inputs name [a,b[0..3]]    set the order of the inputs when the circuit is called, or sort them by name`
+
+// workspaceCommands are the commands of the workspace, used by both the CLI and the :ws command of the REPL
+var workspaceCommands = []string{"list", "show", "add", "update", "rm", "describe", "tag", "inputs"}
+
+func init() {
+	registerCommand(replCommand{
+		name:    "ws",
+		usage:   "[command arguments...]",
+		help:    "manage the circuits of the workspace, which can be called like gates",
+		choices: workspaceChoices,
+		run: func(r *repl, args string) error {
+			words := strings.Fields(args)
+			err := runWorkspaceCommand(r.workspace, words, os.Stdout)
+			// the circuits are loaded again after a change, so that their warnings aren't repeated on every :ws list
+			if len(words) > 0 && words[0] != "list" && words[0] != "show" {
+				r.library = r.workspace.loadLibrary(os.Stdout)
+			}
+			return err
+		},
+	})
+}
+
+// workspaceChoices completes the workspace commands, and then the names of circuits
+func workspaceChoices(r *repl, previous []string) []string {
+	if len(previous) == 0 {
+		return workspaceCommands
+	}
+	if len(previous) > 1 {
+		return nil
+	}
+	return r.circuitNames()
+}
+
+// runWorkspaceCommand runs a workspace command, given as words. Expressions and descriptions are the rest of the words.
+func runWorkspaceCommand(w *workspace, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(workspaceUsage)
+	}
+	command, args := args[0], args[1:]
+	if command == "list" {
+		if len(args) > 1 {
+			return errors.New("usage: list [tag]")
+		}
+		return listCircuits(w, args, out)
+	}
+	if !slices.Contains(workspaceCommands, command) {
+		return fmt.Errorf("unknown workspace command %s\n%s", command, workspaceUsage)
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("%s needs the name of a circuit", command)
+	}
+	name, rest := args[0], strings.Join(args[1:], " ")
+
+	switch command {
+	case "show":
+		return showCircuit(w, name, out)
+	case "add":
+		if rest == "" {
+			return errors.New("usage: add name expression")
+		}
+		if _, err := w.get(name); err == nil {
+			return fmt.Errorf("there is already a circuit named %s, use update to change it", name)
+		}
+		now := time.Now().UTC().Truncate(time.Second)
+		return w.put(Circuit{Name: name, Expression: rest, Created: now, Updated: now})
+	case "rm":
+		return w.remove(name)
+	}
+
+	c, err := w.get(name)
+	if err != nil {
+		return err
+	}
+	switch command {
+	case "update":
+		if rest == "" {
+			return errors.New("usage: update name expression")
+		}
+		c.Expression = rest
+	case "describe":
+		c.Description = rest
+	case "tag":
+		c.Tags = args[1:]
+	case "inputs":
+		c.Inputs = strings.FieldsFunc(rest, func(r rune) bool {
+			return r == ',' || r == ' '
+		})
+	}
+	c.Updated = time.Now().UTC().Truncate(time.Second)
+	return w.put(c)
+}
+
+func listCircuits(w *workspace, args []string, out io.Writer) error {
+	circuits, err := w.list()
+	if err != nil {
+		return err
+	}
+	library, err := newLibrary(circuits)
+	if err != nil {
+		return err
+	}
+	var sb strings.Builder
+	tw := tabwriter.NewWriter(&sb, 0, 4, 2, ' ', 0)
+	for _, c := range circuits {
+		if len(args) == 1 && !slices.Contains(c.Tags, args[0]) {
+			continue
+		}
+		signature, err := library.Signature(c.Name)
+		if err != nil {
+			signature = c.Name + " (invalid)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", signature, strings.Join(c.Tags, ","), c.Description)
+	}
+	if err := tw.Flush(); err != nil || sb.Len() == 0 {
+		return err
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(sb.String(), "\n"), "\n") {
+		// the columns of circuits without tags or a description are padded with spaces
+		fmt.Fprintln(out, strings.TrimRight(line, " "))
+	}
+	return nil
+}
+
+func showCircuit(w *workspace, name string, out io.Writer) error {
+	c, err := w.get(name)
+	if err != nil {
+		return err
+	}
+	library, err := w.library()
+	if err != nil {
+		return err
+	}
+	signature, err := library.Signature(name)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, signature)
+	if c.Description != "" {
+		fmt.Fprintln(out, c.Description)
+	}
+	if len(c.Tags) > 0 {
+		fmt.Fprintln(out, "Tags: "+strings.Join(c.Tags, ", "))
+	}
+	fmt.Fprintln(out, "Expression: "+c.Expression)
+	result, err := library.Compute(c.Expression)
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(out, result.String())
+	return nil
+}
+
+// RunWorkspace manages the circuits of the workspace from the command line. It returns the process exit code.
+func RunWorkspace(args []string) int {
+	flags := flag.NewFlagSet("workspace", flag.ContinueOnError)
+	dir := flags.String("dir", "", "workspace directory (default $BOOL_CALCULATOR_WORKSPACE or the data directory)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: bool-calculator workspace [-dir path] command [arguments]")
+		flags.PrintDefaults()
+		fmt.Fprintln(flags.Output(), workspaceUsage)
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+	if err := runWorkspaceCommand(openWorkspace(*dir), flags.Args(), os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
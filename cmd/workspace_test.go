@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWorkspaceCommands(t *testing.T) {
+	tests := []struct {
+		name     string
+		commands []string // run in order in a new workspace, all but the last must succeed
+		output   string   // of the last command
+		err      string   // part of the error of the last command, empty if it succeeds
+	}{
+		{
+			name:     "add",
+			commands: []string{"add half dmux(xor(x, y), and(x, y))", "list"},
+			output:   "half(x, y)\n",
+		},
+		{
+			name:     "add twice",
+			commands: []string{"add half xor(x, y)", "add half and(x, y)"},
+			err:      "there is already a circuit named half",
+		},
+		{
+			name:     "add invalid",
+			commands: []string{"add half xor(x,"},
+			err:      "circuit half",
+		},
+		{
+			name:     "add calling a circuit",
+			commands: []string{"add sum xor(x, y)", "add full or(sum(a, b), c)", "list"},
+			output:   "full(a, b, c)\nsum(x, y)\n",
+		},
+		{
+			name:     "update",
+			commands: []string{"add one xor(x, y)", "update one and(x, and(y, z))", "list"},
+			output:   "one(x, y, z)\n",
+		},
+		{
+			name:     "update a missing circuit",
+			commands: []string{"update one and(x, y)"},
+			err:      "no circuit named one",
+		},
+		{
+			name:     "update breaking a caller",
+			commands: []string{"add sum xor(x, y)", "add full or(sum(a, b), c)", "update sum dmux(x, y)"},
+			err:      "full",
+		},
+		{
+			name:     "rm",
+			commands: []string{"add one xor(x, y)", "add two or(x, y)", "rm one", "list"},
+			output:   "two(x, y)\n",
+		},
+		{
+			name:     "rm while used",
+			commands: []string{"add sum xor(x, y)", "add full or(sum(a, b), c)", "rm sum"},
+			err:      "can't remove sum, the other circuits need it",
+		},
+		{
+			name:     "tag",
+			commands: []string{"add one xor(x, y)", "tag one adder basic", "describe one the sum bit", "list"},
+			output:   "one(x, y)  adder,basic  the sum bit\n",
+		},
+		{
+			name:     "list by tag",
+			commands: []string{"add one xor(x, y)", "add two or(x, y)", "add three and(x, y)", "tag one adder", "tag three adder", "list adder"},
+			output:   "one(x, y)    adder\nthree(x, y)  adder\n",
+		},
+		{
+			name:     "list an empty workspace",
+			commands: []string{"list"},
+			output:   "",
+		},
+		{
+			name:     "inputs",
+			commands: []string{"add sel mux(a, b, s)", "inputs sel s,a b", "list"},
+			output:   "sel(s, a, b)\n",
+		},
+		{
+			name:     "inputs sorted by name",
+			commands: []string{"add sel mux(a, b, s)", "inputs sel s,a,b", "inputs sel", "list"},
+			output:   "sel(a, b, s)\n",
+		},
+		{
+			name:     "inputs missing one",
+			commands: []string{"add sel mux(a, b, s)", "inputs sel s,a"},
+			err:      "b",
+		},
+		{
+			name:     "show",
+			commands: []string{"add one xor(x, y)", "describe one the sum bit", "show one"},
+			output:   "one(x, y)\nthe sum bit\nExpression: xor(x, y)\nx\ty\tOutput\n0\t0\t0\n0\t1\t1\n1\t0\t1\n1\t1\t0\n",
+		},
+		{
+			name:     "invalid name",
+			commands: []string{"add ../one xor(x, y)"},
+			err:      "invalid circuit name",
+		},
+		{
+			name:     "unknown command",
+			commands: []string{"copy one two"},
+			err:      "unknown workspace command copy",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := &workspace{dir: t.TempDir()}
+			last := len(test.commands) - 1
+			for _, command := range test.commands[:last] {
+				if err := runWorkspaceCommand(w, strings.Fields(command), &strings.Builder{}); err != nil {
+					t.Fatalf("%s: %v", command, err)
+				}
+			}
+			var out strings.Builder
+			err := runWorkspaceCommand(w, strings.Fields(test.commands[last]), &out)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got error %v, expected one about %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != test.output {
+				t.Errorf("got output\n%q\nexpected\n%q", out.String(), test.output)
+			}
+		})
+	}
+}
+
+func TestLoadLibrary(t *testing.T) {
+	w := &workspace{dir: t.TempDir()}
+	for _, command := range []string{"add sum xor(x, y)", "add full or(sum(a, b), c)", "add one xor(x, y)"} {
+		if err := runWorkspaceCommand(w, strings.Fields(command), &strings.Builder{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a file that can't be read, and a circuit that calls it
+	if err := os.WriteFile(w.path("sum"), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var warnings strings.Builder
+	library := w.loadLibrary(&warnings)
+	if _, err := library.Signature("one"); err != nil {
+		t.Errorf("the valid circuit isn't loaded: %v", err)
+	}
+	for _, name := range []string{"sum", "full"} {
+		if _, err := library.Signature(name); err == nil {
+			t.Errorf("expected %s to be skipped", name)
+		}
+		if !strings.Contains(warnings.String(), name) {
+			t.Errorf("no warning about %s in %q", name, warnings.String())
+		}
+	}
+}
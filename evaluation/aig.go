@@ -0,0 +1,434 @@
+package evaluation
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AIGLiteral follows the AIGER convention: twice the variable index, plus one if negated.
+// Variable 0 is the constant false, so literal 0 is false and literal 1 is true.
+type AIGLiteral uint32
+
+const (
+	AIGFalse AIGLiteral = 0
+	AIGTrue  AIGLiteral = 1
+)
+
+func (l AIGLiteral) Not() AIGLiteral {
+	return l ^ 1
+}
+
+func (l AIGLiteral) IsNegated() bool {
+	return l&1 == 1
+}
+
+func (l AIGLiteral) Variable() uint32 {
+	return uint32(l >> 1)
+}
+
+type AIGAnd struct {
+	LHS  AIGLiteral
+	RHS0 AIGLiteral
+	RHS1 AIGLiteral
+}
+
+// AIG is an and-inverter graph. Inputs take the variable indices 1..len(Inputs), followed by the
+// and gates in creation order, which is also a topological order. Identical and gates are shared.
+type AIG struct {
+	Inputs      []string
+	Ands        []AIGAnd
+	Outputs     []AIGLiteral
+	OutputNames []string
+
+	strash map[[2]AIGLiteral]AIGLiteral
+}
+
+func NewAIG() *AIG {
+	return &AIG{strash: map[[2]AIGLiteral]AIGLiteral{}}
+}
+
+func (g *AIG) MaxVariable() uint32 {
+	return uint32(len(g.Inputs) + len(g.Ands))
+}
+
+func (g *AIG) AddInput(name string) AIGLiteral {
+	if len(g.Ands) > 0 {
+		panic("AIG inputs must be added before any and gate")
+	}
+	g.Inputs = append(g.Inputs, name)
+	return AIGLiteral(2 * len(g.Inputs))
+}
+
+func (g *AIG) And(a, b AIGLiteral) AIGLiteral {
+	if a < b {
+		a, b = b, a
+	}
+	switch {
+	case b == AIGFalse || a == b.Not():
+		return AIGFalse
+	case b == AIGTrue || a == b:
+		return a
+	}
+	key := [2]AIGLiteral{a, b}
+	if lit, ok := g.strash[key]; ok {
+		return lit
+	}
+	lhs := AIGLiteral(2 * (g.MaxVariable() + 1))
+	g.Ands = append(g.Ands, AIGAnd{LHS: lhs, RHS0: a, RHS1: b})
+	g.strash[key] = lhs
+	return lhs
+}
+
+func (g *AIG) Or(a, b AIGLiteral) AIGLiteral {
+	return g.And(a.Not(), b.Not()).Not()
+}
+
+func (g *AIG) Xor(a, b AIGLiteral) AIGLiteral {
+	return g.Or(g.And(a, b.Not()), g.And(a.Not(), b))
+}
+
+func (g *AIG) Mux(a, b, sel AIGLiteral) AIGLiteral {
+	return g.Or(g.And(sel, a), g.And(sel.Not(), b))
+}
+
+func (g *AIG) Evaluate(args map[string]bool) ([]bool, error) {
+	values := make([]bool, g.MaxVariable()+1)
+	for i, name := range g.Inputs {
+		val, ok := args[name]
+		if !ok {
+			return nil, fmt.Errorf("cannot evaluate AIG: no value provided for input %v", name)
+		}
+		values[i+1] = val
+	}
+	literal := func(l AIGLiteral) bool {
+		return values[l.Variable()] != l.IsNegated()
+	}
+	for _, and := range g.Ands {
+		values[and.LHS.Variable()] = literal(and.RHS0) && literal(and.RHS1)
+	}
+	result := make([]bool, len(g.Outputs))
+	for i, out := range g.Outputs {
+		result[i] = literal(out)
+	}
+	return result, nil
+}
+
+// ToAIG converts an expression to an AIG with one input per variable (in sorted order) and one output
+// per expression output. All gates are lowered to AND and NOT.
+func ToAIG(expr Expression) (*AIG, error) {
+	vars, err := collectVariables(expr)
+	if err != nil {
+		return nil, err
+	}
+	g := NewAIG()
+	b := aigBuilder{aig: g, inputs: map[string]AIGLiteral{}}
+	for _, v := range getVarsSlice(vars) {
+		b.inputs[v] = g.AddInput(v)
+	}
+	outputs, err := lower[AIGLiteral](expr, b)
+	if err != nil {
+		return nil, err
+	}
+	g.Outputs = outputs
+	return g, nil
+}
+
+type aigBuilder struct {
+	aig    *AIG
+	inputs map[string]AIGLiteral
+}
+
+func (b aigBuilder) Const(value bool) AIGLiteral {
+	if value {
+		return AIGTrue
+	}
+	return AIGFalse
+}
+
+func (b aigBuilder) Var(name string) AIGLiteral          { return b.inputs[name] }
+func (b aigBuilder) Not(a AIGLiteral) AIGLiteral         { return a.Not() }
+func (b aigBuilder) And(x, y AIGLiteral) AIGLiteral      { return b.aig.And(x, y) }
+func (b aigBuilder) Or(x, y AIGLiteral) AIGLiteral       { return b.aig.Or(x, y) }
+func (b aigBuilder) Nand(x, y AIGLiteral) AIGLiteral     { return b.aig.And(x, y).Not() }
+func (b aigBuilder) Xor(x, y AIGLiteral) AIGLiteral      { return b.aig.Xor(x, y) }
+func (b aigBuilder) Mux(x, y, sel AIGLiteral) AIGLiteral { return b.aig.Mux(x, y, sel) }
+
+func (g *AIG) WriteASCII(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "aag %d %d 0 %d %d\n", g.MaxVariable(), len(g.Inputs), len(g.Outputs), len(g.Ands))
+	for i := range g.Inputs {
+		fmt.Fprintf(bw, "%d\n", 2*(i+1))
+	}
+	for _, out := range g.Outputs {
+		fmt.Fprintf(bw, "%d\n", out)
+	}
+	for _, and := range g.Ands {
+		fmt.Fprintf(bw, "%d %d %d\n", and.LHS, and.RHS0, and.RHS1)
+	}
+	g.writeSymbols(bw)
+	return bw.Flush()
+}
+
+func (g *AIG) WriteBinary(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "aig %d %d 0 %d %d\n", g.MaxVariable(), len(g.Inputs), len(g.Outputs), len(g.Ands))
+	for _, out := range g.Outputs {
+		fmt.Fprintf(bw, "%d\n", out)
+	}
+	for _, and := range g.Ands {
+		writeAIGERDelta(bw, uint32(and.LHS-and.RHS0))
+		writeAIGERDelta(bw, uint32(and.RHS0-and.RHS1))
+	}
+	g.writeSymbols(bw)
+	return bw.Flush()
+}
+
+func writeAIGERDelta(w *bufio.Writer, x uint32) {
+	for x&^0x7f != 0 {
+		w.WriteByte(byte(x&0x7f) | 0x80)
+		x >>= 7
+	}
+	w.WriteByte(byte(x))
+}
+
+func (g *AIG) writeSymbols(w *bufio.Writer) {
+	for i, name := range g.Inputs {
+		fmt.Fprintf(w, "i%d %s\n", i, name)
+	}
+	for i, name := range g.OutputNames {
+		fmt.Fprintf(w, "o%d %s\n", i, name)
+	}
+}
+
+const (
+	// maxAIGERVariable keeps the literals of a file within 32 bits
+	maxAIGERVariable = 1<<31 - 1
+	// maxAIGERInputs bounds the inputs of a binary file, which aren't listed in it, so a header alone can't make
+	// ReadAIGER allocate more than that
+	maxAIGERInputs = 1 << 20
+)
+
+// ReadAIGER reads a combinational AIG (no latches) in either the ASCII ("aag") or binary ("aig") format.
+// The graph is rebuilt through AIG.And, so the result is structurally hashed and renumbered.
+func ReadAIGER(r io.Reader) (*AIG, error) {
+	br := bufio.NewReader(r)
+	header, err := br.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("failed to read AIGER header: %w", err)
+	}
+	fields := strings.Fields(header)
+	if len(fields) < 6 || (fields[0] != "aag" && fields[0] != "aig") {
+		return nil, fmt.Errorf("invalid AIGER header: %q", strings.TrimSpace(header))
+	}
+	binary := fields[0] == "aig"
+	counts := make([]int, len(fields)-1)
+	for i, f := range fields[1:] {
+		counts[i], err = strconv.Atoi(f)
+		if err != nil || counts[i] < 0 {
+			return nil, fmt.Errorf("invalid AIGER header: %q", strings.TrimSpace(header))
+		}
+	}
+	maxVar, numInputs, numLatches, numOutputs, numAnds := counts[0], counts[1], counts[2], counts[3], counts[4]
+	// ASCII files can leave variable indices unused, binary files number every input, latch and and gate in turn
+	if maxVar > maxAIGERVariable || numInputs > maxVar || numLatches > maxVar || numAnds > maxVar ||
+		maxVar < numInputs+numLatches+numAnds {
+		return nil, fmt.Errorf("invalid AIGER header: %q, the maximum variable index must be at least I + L + A", strings.TrimSpace(header))
+	}
+	if binary && maxVar != numInputs+numLatches+numAnds {
+		return nil, fmt.Errorf("invalid AIGER header: %q, the maximum variable index must be I + L + A", strings.TrimSpace(header))
+	}
+	if binary && numInputs > maxAIGERInputs {
+		return nil, fmt.Errorf("binary AIGER file with %d inputs, at most %d are supported", numInputs, maxAIGERInputs)
+	}
+	if numLatches != 0 {
+		return nil, errors.New("AIGER files with latches are not supported")
+	}
+	for _, extra := range counts[5:] {
+		if extra != 0 {
+			return nil, errors.New("AIGER 1.9 sections (bad states, constraints, justice, fairness) are not supported")
+		}
+	}
+
+	// the slices grow as lines are read, so a header can't allocate more than the file holds
+	inputs := []AIGLiteral{}
+	for i := range numInputs {
+		input := AIGLiteral(2 * (i + 1))
+		if !binary {
+			if input, err = readAIGERLiteral(br); err != nil {
+				return nil, err
+			}
+		}
+		inputs = append(inputs, input)
+	}
+	outputs := []AIGLiteral{}
+	for range numOutputs {
+		output, err := readAIGERLiteral(br)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, output)
+	}
+	ands := map[uint32]AIGAnd{}
+	for i := 0; i < numAnds; i++ {
+		var and AIGAnd
+		if binary {
+			and.LHS = AIGLiteral(2 * (numInputs + i + 1))
+			delta0, err := readAIGERDelta(br)
+			if err != nil {
+				return nil, err
+			}
+			delta1, err := readAIGERDelta(br)
+			if err != nil {
+				return nil, err
+			}
+			if delta0 > uint32(and.LHS) || delta1 > uint32(and.LHS)-delta0 {
+				return nil, fmt.Errorf("invalid delta encoding for and gate %d", and.LHS)
+			}
+			and.RHS0 = and.LHS - AIGLiteral(delta0)
+			and.RHS1 = and.RHS0 - AIGLiteral(delta1)
+		} else {
+			line, err := readAIGERLine(br)
+			if err != nil {
+				return nil, err
+			}
+			var lits [3]AIGLiteral
+			if n, _ := fmt.Sscanf(line, "%d %d %d", &lits[0], &lits[1], &lits[2]); n != 3 {
+				return nil, fmt.Errorf("invalid and gate line: %q", line)
+			}
+			and = AIGAnd{LHS: lits[0], RHS0: lits[1], RHS1: lits[2]}
+			if and.LHS.IsNegated() || and.LHS == AIGFalse || int(and.LHS.Variable()) > maxVar {
+				return nil, fmt.Errorf("invalid and gate literal %d", and.LHS)
+			}
+		}
+		ands[and.LHS.Variable()] = and
+	}
+
+	g := NewAIG()
+	inputNames := map[int]string{}
+	outputNames := []string{}
+	for {
+		line, err := br.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+		if line == "c" || (line == "" && err != nil) {
+			break
+		}
+		if len(line) > 1 && (line[0] == 'i' || line[0] == 'o') {
+			idx, name, found := strings.Cut(line[1:], " ")
+			pos, convErr := strconv.Atoi(idx)
+			if found && convErr == nil {
+				switch {
+				case line[0] == 'i' && pos >= 0 && pos < len(inputs):
+					inputNames[pos] = name
+				case line[0] == 'o' && pos >= 0 && pos < len(outputs):
+					for len(outputNames) <= pos {
+						outputNames = append(outputNames, "")
+					}
+					outputNames[pos] = name
+				}
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	mapping := map[uint32]AIGLiteral{0: AIGFalse}
+	for i, in := range inputs {
+		if in.IsNegated() || in == AIGFalse || int(in.Variable()) > maxVar {
+			return nil, fmt.Errorf("invalid input literal %d", in)
+		}
+		name, ok := inputNames[i]
+		if !ok || name == "" {
+			name = fmt.Sprintf("i%d", i)
+		}
+		mapping[in.Variable()] = g.AddInput(name)
+	}
+	var resolve func(l AIGLiteral, depth int) (AIGLiteral, error)
+	resolve = func(l AIGLiteral, depth int) (AIGLiteral, error) {
+		if mapped, ok := mapping[l.Variable()]; ok {
+			if l.IsNegated() {
+				return mapped.Not(), nil
+			}
+			return mapped, nil
+		}
+		and, ok := ands[l.Variable()]
+		if !ok || depth > numAnds {
+			return 0, fmt.Errorf("undefined or cyclic literal %d", l)
+		}
+		rhs0, err := resolve(and.RHS0, depth+1)
+		if err != nil {
+			return 0, err
+		}
+		rhs1, err := resolve(and.RHS1, depth+1)
+		if err != nil {
+			return 0, err
+		}
+		mapping[l.Variable()] = g.And(rhs0, rhs1)
+		return resolve(l, depth)
+	}
+	// rebuild the ands in file order so that unused gates are kept as well
+	andVars := []uint32{}
+	for v := range ands {
+		andVars = append(andVars, v)
+	}
+	sort.Slice(andVars, func(i, j int) bool { return andVars[i] < andVars[j] })
+	for _, v := range andVars {
+		if _, err := resolve(AIGLiteral(2*v), 0); err != nil {
+			return nil, err
+		}
+	}
+	for _, out := range outputs {
+		lit, err := resolve(out, 0)
+		if err != nil {
+			return nil, err
+		}
+		g.Outputs = append(g.Outputs, lit)
+	}
+	if len(outputNames) > 0 {
+		g.OutputNames = make([]string, numOutputs)
+		copy(g.OutputNames, outputNames)
+	}
+	return g, nil
+}
+
+func readAIGERLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", fmt.Errorf("unexpected end of AIGER file: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+func readAIGERLiteral(r *bufio.Reader) (AIGLiteral, error) {
+	line, err := readAIGERLine(r)
+	if err != nil {
+		return 0, err
+	}
+	lit, err := strconv.ParseUint(line, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid literal %q", line)
+	}
+	return AIGLiteral(lit), nil
+}
+
+func readAIGERDelta(r *bufio.Reader) (uint32, error) {
+	var x uint32
+	for shift := 0; ; shift += 7 {
+		ch, err := r.ReadByte()
+		if err != nil {
+			return 0, fmt.Errorf("unexpected end of binary AIGER and section: %w", err)
+		}
+		if shift > 28 {
+			return 0, errors.New("binary AIGER delta overflows 32 bits")
+		}
+		x |= uint32(ch&0x7f) << shift
+		if ch&0x80 == 0 {
+			return x, nil
+		}
+	}
+}
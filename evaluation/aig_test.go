@@ -0,0 +1,187 @@
+package evaluation
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var aigExpressions = []string{
+	"1",
+	"X",
+	"not(X)",
+	"nand(a,b)",
+	"or(a,b)",
+	"xor(a,b)",
+	"mux(a,b,sel)",
+	"dmux(a,sel)",
+	"and(dmux(or(a,b),c))",
+	"mux(xor(a,b),nand(b,c),or(not(a),c))",
+}
+
+func TestToAIGMatchesEvaluation(t *testing.T) {
+	for _, input := range aigExpressions {
+		t.Run(input, func(t *testing.T) {
+			expr, vars, err := ParseExpression(input)
+			if err != nil {
+				t.Fatalf("failed to parse %v: %v", input, err)
+			}
+			aig, err := ToAIG(expr)
+			if err != nil {
+				t.Fatalf("ToAIG() returned unexpected error: %v", err)
+			}
+			verifyAIGEquivalent(t, aig, expr, getVarsSlice(vars))
+		})
+	}
+}
+
+func TestAIGStructuralHashing(t *testing.T) {
+	expr, _, err := ParseExpression("or(and(a,b),and(b,a))")
+	if err != nil {
+		t.Fatal(err)
+	}
+	aig, err := ToAIG(expr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// and(a,b) is shared and or(x,x) collapses to x
+	if len(aig.Ands) != 1 {
+		t.Errorf("expected 1 and gate, got %d: %v", len(aig.Ands), aig.Ands)
+	}
+
+	g := NewAIG()
+	a := g.AddInput("a")
+	verifyEquality(t, g.And(a, AIGFalse), AIGFalse)
+	verifyEquality(t, g.And(a, AIGTrue), a)
+	verifyEquality(t, g.And(a, a.Not()), AIGFalse)
+	verifyEquality(t, g.And(a, a), a)
+	verifyEquality(t, len(g.Ands), 0)
+}
+
+func TestAIGERRoundTrip(t *testing.T) {
+	for _, input := range aigExpressions {
+		expr, vars, err := ParseExpression(input)
+		if err != nil {
+			t.Fatalf("failed to parse %v: %v", input, err)
+		}
+		aig, err := ToAIG(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		aig.OutputNames = make([]string, len(aig.Outputs))
+		for i := range aig.OutputNames {
+			aig.OutputNames[i] = "out" + string(rune('A'+i))
+		}
+
+		for _, binary := range []bool{false, true} {
+			var buf bytes.Buffer
+			if binary {
+				err = aig.WriteBinary(&buf)
+			} else {
+				err = aig.WriteASCII(&buf)
+			}
+			if err != nil {
+				t.Fatalf("failed to write AIGER for %v: %v", input, err)
+			}
+			read, err := ReadAIGER(&buf)
+			if err != nil {
+				t.Fatalf("failed to read AIGER (binary=%v) for %v: %v", binary, input, err)
+			}
+			if !reflect.DeepEqual(read.Inputs, aig.Inputs) || !reflect.DeepEqual(read.OutputNames, aig.OutputNames) {
+				t.Errorf("symbols not preserved for %v: got %v/%v", input, read.Inputs, read.OutputNames)
+			}
+			if !reflect.DeepEqual(read.Ands, aig.Ands) || !reflect.DeepEqual(read.Outputs, aig.Outputs) {
+				t.Errorf("graph not preserved for %v (binary=%v)", input, binary)
+			}
+			verifyAIGEquivalent(t, read, expr, getVarsSlice(vars))
+		}
+	}
+}
+
+func TestWriteASCII(t *testing.T) {
+	expr, _, err := ParseExpression("and(a,not(b))")
+	if err != nil {
+		t.Fatal(err)
+	}
+	aig, err := ToAIG(expr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := aig.WriteASCII(&buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := "aag 3 2 0 1 1\n2\n4\n6\n6 5 2\ni0 a\ni1 b\n"
+	verifyEquality(t, buf.String(), expected)
+}
+
+func TestReadAIGERUnorderedASCII(t *testing.T) {
+	// and gates listed out of order, as allowed by the ASCII format
+	input := "aag 5 2 0 1 3\n2\n4\n11\n10 6 8\n6 2 4\n8 3 5\ni0 x\ni1 y\nc\nsome comment\n"
+	aig, err := ReadAIGER(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expr, vars, err := ParseExpression("not(and(and(x,y),and(not(x),not(y))))")
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifyAIGEquivalent(t, aig, expr, getVarsSlice(vars))
+}
+
+func TestReadAIGERUnusedVariables(t *testing.T) {
+	// ASCII files can skip variable indices, here 2, and name inputs and outputs that don't exist
+	input := "aag 3 1 0 1 1\n2\n6\n6 2 2\ni0 x\ni99999999999 y\no999 z\n"
+	aig, err := ReadAIGER(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expr, vars, err := ParseExpression("and(x,x)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifyAIGEquivalent(t, aig, expr, getVarsSlice(vars))
+}
+
+func TestReadAIGERErrors(t *testing.T) {
+	testCases := []string{
+		"",
+		"aig\n",
+		"foo 1 1 0 1 0\n2\n2\n",
+		"aag 1 1 1 0 0\n2\n2 3\n",
+		"aag 2 1 0 1 1\n2\n4\n4 4 2\n",
+		"aag 2 1 0 1 0\n2\n4\n",
+		"aig 1 1000000000000 0 1 0\n2\n",        // M doesn't match the counts, checked before allocating
+		"aig 3 1 0 1 1\n2\n\x04\x00",            // binary files need M = I + L + A
+		"aag 2 1 0 1 2\n2\n4\n4 2 2\n6 2 3\n",   // M < I + L + A
+		"aag 3 1 0 1 1\n2\n6\n8 2 3\n",          // and gate over M
+		"aig 0 0 0 1000000000000 0\n0\n",        // more outputs than lines
+		"aig 100000000000 100000000000 0 0 0\n", // literals over 32 bits
+		"aig 2000000000 2000000000 0 0 0\n",     // more inputs than a binary file can have
+		"aag 2000000000 2000000000 0 0 0\n2\n",  // more inputs than lines
+	}
+	for _, tc := range testCases {
+		if aig, err := ReadAIGER(strings.NewReader(tc)); err == nil {
+			t.Errorf("expected error reading %q, but got %v", tc, aig)
+		}
+	}
+}
+
+func verifyAIGEquivalent(t *testing.T, aig *AIG, expr Expression, variables []string) {
+	t.Helper()
+	for _, assignment := range generateCombinations(len(variables)) {
+		args := getArgs(variables, assignment)
+		expected, err := expr.Evaluate(args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := aig.Evaluate(args)
+		if err != nil {
+			t.Fatalf("AIG evaluation failed: %v", err)
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("AIG output %v differs from expression output %v for %v", got, expected, args)
+		}
+	}
+}
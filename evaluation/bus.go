@@ -0,0 +1,234 @@
+package evaluation
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Buses are flattened into single bits: bit i of bus a is the variable "a[i]", and a bus valued
+// expression has one output per bit, least significant bit first. Bus widths are inferred from the
+// highest index used.
+
+const maxBusWidth = 64
+
+func bitName(bus string, index int) string {
+	return fmt.Sprintf("%s[%d]", bus, index)
+}
+
+func sliceName(bus string, low, high int) string {
+	return fmt.Sprintf("%s[%d..%d]", bus, low, high)
+}
+
+// busGates holds the number of outputs of every bus gate. Their inputs are listed in gateInputs.
+var busGates = map[TokenType]int{
+	TokenNot16:     16,
+	TokenAnd16:     16,
+	TokenOr16:      16,
+	TokenXor16:     16,
+	TokenNand16:    16,
+	TokenMux16:     16,
+	TokenDmux16:    32,
+	TokenOr8Way:    1,
+	TokenMux4Way16: 16,
+	TokenMux8Way16: 16,
+	TokenDmux4Way:  4,
+	TokenDmux8Way:  8,
+}
+
+// busOutputWidths describes how the outputs of a bus gate are grouped into buses for display
+var busOutputWidths = map[TokenType][]int{
+	TokenNot16:     {16},
+	TokenAnd16:     {16},
+	TokenOr16:      {16},
+	TokenXor16:     {16},
+	TokenNand16:    {16},
+	TokenMux16:     {16},
+	TokenDmux16:    {16, 16},
+	TokenMux4Way16: {16},
+	TokenMux8Way16: {16},
+}
+
+// checkBusUsage rejects variable sets that use a name both as a single bit and as a bus
+func checkBusUsage(vars VariableSet) error {
+	for v := range vars {
+		if bus, _, found := strings.Cut(v, "["); found {
+			if _, ok := vars[bus]; ok {
+				return fmt.Errorf("variable %s is used both as a single bit and as a bus", bus)
+			}
+		}
+	}
+	return nil
+}
+
+type BusVariableExpression struct {
+	name string
+	low  int
+	high int
+}
+
+func (e *BusVariableExpression) NumOutputs() int {
+	return e.high - e.low + 1
+}
+
+func (e *BusVariableExpression) Evaluate(args map[string]bool) ([]bool, error) {
+	result := []bool{}
+	for i := e.low; i <= e.high; i++ {
+		val, ok := args[bitName(e.name, i)]
+		if !ok {
+			return nil, fmt.Errorf("cannot evaluate expression: no value provided for variable %v", bitName(e.name, i))
+		}
+		result = append(result, val)
+	}
+	return result, nil
+}
+
+type BusGateExpression struct {
+	op          TokenType
+	expressions []Expression
+}
+
+func (e *BusGateExpression) NumOutputs() int {
+	return busGates[e.op]
+}
+
+func (e *BusGateExpression) Evaluate(args map[string]bool) ([]bool, error) {
+	in, err := collectInputs(e.expressions, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(in) != gateInputs[e.op] {
+		panic(fmt.Sprintf("parser messed up. Bus gate %s didn't get %d inputs", gateName(e.op), gateInputs[e.op]))
+	}
+	return evaluateBusGate(e.op, in), nil
+}
+
+// evaluateBusGate computes the outputs of a bus gate from its input bits
+func evaluateBusGate(op TokenType, in []bool) []bool {
+	switch op {
+	case TokenNot16:
+		out := Not16(bus16(in, 0))
+		return out[:]
+	case TokenAnd16:
+		out := And16(bus16(in, 0), bus16(in, 1))
+		return out[:]
+	case TokenOr16:
+		out := Or16(bus16(in, 0), bus16(in, 1))
+		return out[:]
+	case TokenXor16:
+		out := Xor16(bus16(in, 0), bus16(in, 1))
+		return out[:]
+	case TokenNand16:
+		out := Nand16(bus16(in, 0), bus16(in, 1))
+		return out[:]
+	case TokenMux16:
+		out := Mux16(bus16(in, 0), bus16(in, 1), in[32])
+		return out[:]
+	case TokenDmux16:
+		out1, out2 := Dmux16(bus16(in, 0), in[16])
+		return append(out1[:], out2[:]...)
+	case TokenOr8Way:
+		return []bool{Or8Way([8]bool(in))}
+	case TokenMux4Way16:
+		out := Mux4Way16(bus16(in, 0), bus16(in, 1), bus16(in, 2), bus16(in, 3), [2]bool(in[64:]))
+		return out[:]
+	case TokenMux8Way16:
+		buses := [8][16]bool{}
+		for i := range buses {
+			buses[i] = bus16(in, i)
+		}
+		out := Mux8Way16(buses[0], buses[1], buses[2], buses[3], buses[4], buses[5], buses[6], buses[7], [3]bool(in[128:]))
+		return out[:]
+	case TokenDmux4Way:
+		out := Dmux4Way(in[0], [2]bool(in[1:]))
+		return out[:]
+	case TokenDmux8Way:
+		out := Dmux8Way(in[0], [3]bool(in[1:]))
+		return out[:]
+	default:
+		panic(fmt.Sprintf("evaluation of bus gate %d not implemented", op))
+	}
+}
+
+// bus16 returns the i-th group of 16 inputs
+func bus16(in []bool, i int) [16]bool {
+	return [16]bool(in[16*i : 16*(i+1)])
+}
+
+// lowerBusGate mirrors the bus gates in gates.go using the primitives of a circuitBuilder
+func lowerBusGate[T any](op TokenType, in []T, b circuitBuilder[T]) []T {
+	bitwise := func(f func(x, y T) T, n int) []T {
+		out := make([]T, 16)
+		for i := range out {
+			if n == 1 {
+				out[i] = f(in[i], in[i])
+			} else {
+				out[i] = f(in[i], in[16+i])
+			}
+		}
+		return out
+	}
+	mux16 := func(x, y []T, sel T) []T {
+		out := make([]T, 16)
+		for i := range out {
+			out[i] = b.Mux(x[i], y[i], sel)
+		}
+		return out
+	}
+	mux4way16 := func(buses [][]T, sel []T) []T {
+		return mux16(mux16(buses[3], buses[1], sel[1]), mux16(buses[2], buses[0], sel[1]), sel[0])
+	}
+	dmux4way := func(a T, sel []T) []T {
+		high := []T{b.And(a, b.Not(sel[1])), b.And(a, sel[1])}
+		return []T{
+			b.And(high[0], b.Not(sel[0])), b.And(high[0], sel[0]),
+			b.And(high[1], b.Not(sel[0])), b.And(high[1], sel[0]),
+		}
+	}
+	buses := func(n int) [][]T {
+		result := [][]T{}
+		for i := 0; i < n; i++ {
+			result = append(result, in[16*i:16*(i+1)])
+		}
+		return result
+	}
+
+	switch op {
+	case TokenNot16:
+		return bitwise(func(x, _ T) T { return b.Not(x) }, 1)
+	case TokenAnd16:
+		return bitwise(b.And, 2)
+	case TokenOr16:
+		return bitwise(b.Or, 2)
+	case TokenXor16:
+		return bitwise(b.Xor, 2)
+	case TokenNand16:
+		return bitwise(b.Nand, 2)
+	case TokenMux16:
+		return mux16(in[:16], in[16:32], in[32])
+	case TokenDmux16:
+		out := make([]T, 32)
+		for i := 0; i < 16; i++ {
+			out[i] = b.And(in[i], b.Not(in[16]))
+			out[16+i] = b.And(in[i], in[16])
+		}
+		return out
+	case TokenOr8Way:
+		result := in[0]
+		for _, bit := range in[1:8] {
+			result = b.Or(result, bit)
+		}
+		return []T{result}
+	case TokenMux4Way16:
+		return mux4way16(buses(4), in[64:])
+	case TokenMux8Way16:
+		all := buses(8)
+		return mux16(mux4way16(all[4:], in[128:]), mux4way16(all[:4], in[128:]), in[130])
+	case TokenDmux4Way:
+		return dmux4way(in[0], in[1:])
+	case TokenDmux8Way:
+		low, high := b.And(in[0], b.Not(in[3])), b.And(in[0], in[3])
+		return append(dmux4way(low, in[1:3]), dmux4way(high, in[1:3])...)
+	default:
+		panic(fmt.Sprintf("lowering of bus gate %d not implemented", op))
+	}
+}
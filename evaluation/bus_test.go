@@ -0,0 +1,155 @@
+package evaluation
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+var busExpressions = []string{
+	"a[0..7]",
+	"not16(a[0..15])",
+	"and16(a[0..15],b[0..15])",
+	"or16(a[0..15],not16(b[0..15]))",
+	"xor16(a[0..15],b[0..15])",
+	"nand16(a[0..15],b[0..15])",
+	"mux16(a[0..15],b[0..15],s)",
+	"dmux16(a[0..15],s)",
+	"or8way(a[8..15])",
+	"mux4way16(a[0..15],b[0..15],c[0..15],d[0..15],s[0..1])",
+	"mux8way16(a[0..15],b[0..15],c[0..15],d[0..15],e[0..15],f[0..15],g[0..15],h[0..15],s[0..2])",
+	"dmux4way(x,s[0..1])",
+	"dmux8way(x,s[0..2])",
+	"or8way(dmux4way(x,s[0..1]),dmux4way(y,s[0..1]))",
+	"and(a[3],or8way(dmux8way(a[1],b[0..2])))",
+}
+
+func TestBusParsing(t *testing.T) {
+	tests := []struct {
+		input             string
+		expectedOutputs   int
+		expectedVariables int
+	}{
+		{"a[0..7]", 8, 8},
+		{"a[3]", 1, 1},
+		{"not16(a[0..15])", 16, 16},
+		{"and16(a[0..7],a[8..15],b[0..15])", 16, 32},
+		{"dmux16(a[0..15],s)", 32, 17},
+		{"or8way(a[8..15])", 1, 8},
+		{"mux4way16(a[0..15],b[0..15],c[0..15],d[0..15],s[0..1])", 16, 66},
+		{"dmux8way(x,s[0..2])", 8, 4},
+		{"and(x1,y2)", 1, 2},
+	}
+	for _, tc := range tests {
+		expr, vars, err := ParseExpression(tc.input)
+		if err != nil {
+			t.Errorf("failed to parse %v: %v", tc.input, err)
+			continue
+		}
+		verifyEquality(t, expr.NumOutputs(), tc.expectedOutputs)
+		verifyEquality(t, len(vars), tc.expectedVariables)
+	}
+}
+
+func TestBusParsingErrors(t *testing.T) {
+	testCases := []string{
+		"and16(a[0..7],b[0..7])",               // too narrow
+		"not16(a[0..16])",                      // too wide
+		"and(a,a[0])",                          // a is both a bit and a bus
+		"or8way(a[0..7],b)",                    // too many inputs
+		"mux4way16(a[0..15],b[0..15],s[0..1])", // missing buses
+	}
+	for _, tc := range testCases {
+		if expr, _, err := ParseExpression(tc); err == nil {
+			t.Errorf("expected error parsing %v, but got %v", tc, expr)
+		}
+	}
+}
+
+func TestBusEvaluation(t *testing.T) {
+	args := map[string]bool{"s[0]": true, "s[1]": false, "x": true}
+	for i := 0; i < 16; i++ {
+		args[bitName("a", i)] = 0xBEEF&(1<<i) != 0
+		args[bitName("b", i)] = 0x0FF0&(1<<i) != 0
+	}
+
+	tests := []struct {
+		input    string
+		expected uint64
+	}{
+		{"a[0..15]", 0xBEEF},
+		{"a[4..11]", 0xEE},
+		{"not16(a[0..15])", 0x4110},
+		{"and16(a[0..15],b[0..15])", 0x0EE0},
+		{"mux4way16(a[0..15],b[0..15],a[0..15],a[0..15],s[0..1])", 0x0FF0},
+		{"dmux4way(x,s[0..1])", 0b0010},
+		{"or8way(b[12..15],b[0..3])", 0},
+		{"or8way(b[0..7])", 1},
+	}
+	for _, tc := range tests {
+		expr, _, err := ParseExpression(tc.input)
+		if err != nil {
+			t.Fatalf("failed to parse %v: %v", tc.input, err)
+		}
+		got, err := expr.Evaluate(args)
+		if err != nil {
+			t.Fatalf("failed to evaluate %v: %v", tc.input, err)
+		}
+		if busToUint(got) != tc.expected {
+			t.Errorf("%v evaluated to %#x, expected %#x", tc.input, busToUint(got), tc.expected)
+		}
+	}
+}
+
+func TestBusLoweringMatchesEvaluation(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, input := range busExpressions {
+		expr, vars, err := ParseExpression(input)
+		if err != nil {
+			t.Fatalf("failed to parse %v: %v", input, err)
+		}
+		aig, err := ToAIG(expr)
+		if err != nil {
+			t.Fatalf("ToAIG(%v) returned unexpected error: %v", input, err)
+		}
+		for i := 0; i < 200; i++ {
+			args := map[string]bool{}
+			for v := range vars {
+				args[v] = rng.Intn(2) == 1
+			}
+			expected, err := expr.Evaluate(args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := aig.Evaluate(args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, expected) {
+				t.Errorf("lowered %v evaluates to %v instead of %v for %v", input, got, expected, args)
+				break
+			}
+		}
+	}
+}
+
+func TestBusRoundTrips(t *testing.T) {
+	for _, input := range busExpressions {
+		expr, _, err := ParseExpression(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reparsed, _, err := ParseExpression(Format(expr))
+		if err != nil || !reflect.DeepEqual(reparsed, expr) {
+			t.Errorf("%v doesn't round trip through Format: %v", input, err)
+		}
+		data, err := MarshalExpression(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, _, err := UnmarshalExpression(data)
+		if err != nil || !reflect.DeepEqual(decoded, expr) {
+			t.Errorf("%v doesn't round trip through JSON: %v", input, err)
+		}
+	}
+}
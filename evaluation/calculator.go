@@ -1,57 +1,298 @@
 package evaluation
 
 import (
+	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 )
 
+// MaxTruthTableVariables is the default limit on the number of input bits Compute enumerates, since a truth table
+// has 2^n rows
+const MaxTruthTableVariables = 20
+
+// ComputeOptions configures the functions that go through the rows of truth tables
+type ComputeOptions struct {
+	MaxVariables int // input bits that are enumerated, MaxTruthTableVariables by default
+}
+
+func (o ComputeOptions) withDefaults() ComputeOptions {
+	if o.MaxVariables <= 0 {
+		o.MaxVariables = MaxTruthTableVariables
+	}
+	return o
+}
+
+// check refuses tables with more input bits than the limit
+func (o ComputeOptions) check(variables []string) error {
+	if limit := o.withDefaults().MaxVariables; len(variables) > limit {
+		return fmt.Errorf("expression has %d input bits, but truth tables are limited to %d", len(variables), limit)
+	}
+	return nil
+}
+
 type Result struct {
 	Variables   []string
 	Outputs     [][]bool
 	Assignments [][]bool
+	// OutputWidths groups the outputs into buses (least significant bit first) for display.
+	// It is nil when every output is a single bit.
+	OutputWidths []int
+	// Wires are the names bound with let, shown between the inputs and the outputs.
+	// WireValues holds the bits of every wire for each row.
+	Wires      []string
+	WireValues [][][]bool
 }
 
+// Radix selects how bus values are displayed in a truth table
+type Radix int
+
+const (
+	RadixBinary Radix = iota
+	RadixHex
+	RadixDecimal
+)
+
 func (r Result) String() string {
+	return r.Table(RadixBinary)
+}
+
+func (r Result) Table(radix Radix) string {
 	outputSpacing := "  "
 
 	var sb strings.Builder
 
 	if len(r.Variables) == 0 {
 		// just print the result
-		for i, val := range r.Outputs[0] {
-			if i > 0 {
-				sb.WriteString(outputSpacing)
-			}
-			sb.WriteString(boolToString(val))
-		}
+		sb.WriteString(strings.Join(r.outputValues(0, radix), outputSpacing))
 		sb.WriteString("\n")
 		return sb.String()
 	}
 
 	// Print header
-	for _, v := range r.Variables {
-		sb.WriteString(v)
-		sb.WriteString("\t")
-	}
-	sb.WriteString("Output\n")
+	sb.WriteString(strings.Join(r.Header(), "\t"))
+	sb.WriteString("\n")
 
 	// Print assignments
+	columns := busColumns(r.Variables)
 	for i := 0; i < len(r.Assignments); i++ {
-		for _, val := range r.Assignments[i] {
-			sb.WriteString(boolToString(val))
-			sb.WriteString("\t")
+		sb.WriteString(strings.Join(r.cells(columns, i, radix), "\t"))
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+// Header names the columns of the truth table: the inputs, with one column per bus, the wires and the output
+func (r Result) Header() []string {
+	header := []string{}
+	for _, c := range busColumns(r.Variables) {
+		header = append(header, c.header)
+	}
+	header = append(header, r.Wires...)
+	return append(header, "Output")
+}
+
+// Cells renders a row of the truth table, with a value for every column of the Header
+func (r Result) Cells(row int, radix Radix) []string {
+	return r.cells(busColumns(r.Variables), row, radix)
+}
+
+func (r Result) cells(columns []busColumn, row int, radix Radix) []string {
+	cells := []string{}
+	if len(r.Variables) > 0 {
+		for _, c := range columns {
+			cells = append(cells, c.value(r.Assignments[row], radix))
 		}
+		for w := range r.Wires {
+			var sb strings.Builder
+			for _, bit := range r.WireValues[row][w] {
+				sb.WriteString(boolToString(bit))
+			}
+			cells = append(cells, sb.String())
+		}
+	}
+	return append(cells, strings.Join(r.outputValues(row, radix), "  "))
+}
+
+// Row returns the input values of a row of the truth table
+func (r Result) Row(row int) map[string]bool {
+	if len(r.Variables) == 0 {
+		return map[string]bool{}
+	}
+	return getArgs(r.Variables, r.Assignments[row])
+}
 
-		for idx, val := range r.Outputs[i] {
-			if idx > 0 {
-				sb.WriteString(outputSpacing)
+// Reorder returns the truth table with its inputs in the given order. A bus name stands for all its bits, most
+// significant first, and the inputs that aren't listed follow in their usual order. The rows are sorted again, so
+// they count up in the new order.
+func (r Result) Reorder(order []string) (*Result, error) {
+	positions := make([]int, 0, len(r.Variables)) // position in r.Variables of each input in the new order
+	used := make([]bool, len(r.Variables))
+	add := func(name string, i int) error {
+		if used[i] {
+			return fmt.Errorf("%v is listed more than once", name)
+		}
+		used[i] = true
+		positions = append(positions, i)
+		return nil
+	}
+	for _, name := range order {
+		found := false
+		for i, v := range r.Variables {
+			if bus, _, isBit := splitBitName(v); v == name || (isBit && bus == name) {
+				if err := add(name, i); err != nil {
+					return nil, err
+				}
+				found = true
 			}
-			sb.WriteString(boolToString(val))
 		}
-		sb.WriteString("\n")
+		if !found {
+			return nil, fmt.Errorf("%v is not an input of the expression", name)
+		}
+	}
+	for i := range r.Variables {
+		if !used[i] {
+			positions = append(positions, i)
+		}
 	}
 
-	return sb.String()
+	result := r
+	result.Variables = make([]string, len(positions))
+	for j, i := range positions {
+		result.Variables[j] = r.Variables[i]
+	}
+	if len(r.Variables) == 0 {
+		return &result, nil
+	}
+	result.Assignments = make([][]bool, len(r.Assignments))
+	result.Outputs = make([][]bool, len(r.Outputs))
+	if r.WireValues != nil {
+		result.WireValues = make([][][]bool, len(r.WireValues))
+	}
+	for row, assignment := range r.Assignments {
+		reordered := make([]bool, len(positions))
+		index := 0
+		for j, i := range positions {
+			reordered[j] = assignment[i]
+			index <<= 1
+			if assignment[i] {
+				index |= 1
+			}
+		}
+		result.Assignments[index] = reordered
+		result.Outputs[index] = r.Outputs[row]
+		if r.WireValues != nil {
+			result.WireValues[index] = r.WireValues[row]
+		}
+	}
+	return &result, nil
+}
+
+func (r Result) outputValues(row int, radix Radix) []string {
+	outputs := r.Outputs[row]
+	if r.OutputWidths == nil {
+		result := []string{}
+		for _, val := range outputs {
+			result = append(result, boolToString(val))
+		}
+		return result
+	}
+
+	result := []string{}
+	start := 0
+	for _, width := range r.OutputWidths {
+		bits := outputs[start : start+width]
+		msbFirst := make([]bool, width)
+		for i := range bits {
+			msbFirst[i] = bits[width-1-i]
+		}
+		result = append(result, busValue(msbFirst, radix))
+		start += width
+	}
+	return result
+}
+
+// busColumn is a group of consecutive truth table variables that are displayed as one column
+type busColumn struct {
+	header  string
+	start   int
+	indices []int // bus indices of the bits, in column order. nil for plain variables
+}
+
+// busColumns groups the bits of a bus into one column where their indices count down by one, like a[7], a[6],
+// a[5]. Bits that aren't next to each other in the bus get columns of their own.
+func busColumns(variables []string) []busColumn {
+	columns := []busColumn{}
+	buses := []string{} // the bus of each column, "" for plain variables
+	for i, v := range variables {
+		bus, index, isBit := splitBitName(v)
+		last := len(columns) - 1
+		if isBit && last >= 0 && buses[last] == bus && columns[last].indices[len(columns[last].indices)-1] == index+1 {
+			columns[last].indices = append(columns[last].indices, index)
+			continue
+		}
+		column := busColumn{header: v, start: i}
+		if isBit {
+			column.indices = []int{index}
+		} else {
+			bus = ""
+		}
+		columns = append(columns, column)
+		buses = append(buses, bus)
+	}
+
+	for i, c := range columns {
+		if len(c.indices) > 1 {
+			columns[i].header = fmt.Sprintf("%s[%d..%d]", buses[i], c.indices[0], c.indices[len(c.indices)-1])
+		}
+	}
+	return columns
+}
+
+func (c busColumn) value(assignment []bool, radix Radix) string {
+	if c.indices == nil {
+		return boolToString(assignment[c.start])
+	}
+	return busValue(assignment[c.start:c.start+len(c.indices)], radix)
+}
+
+// busValue renders bits given most significant first. A slice like a[4..7] shows its own value, so its lowest
+// bit counts as 1.
+func busValue(bits []bool, radix Radix) string {
+	if len(bits) == 1 || radix == RadixBinary {
+		var sb strings.Builder
+		for _, bit := range bits {
+			sb.WriteString(boolToString(bit))
+		}
+		return sb.String()
+	}
+
+	var value uint64
+	for _, bit := range bits {
+		value <<= 1
+		if bit {
+			value |= 1
+		}
+	}
+	if radix == RadixHex {
+		digits := (len(bits) + 3) / 4
+		return fmt.Sprintf("0x%0*X", digits, value)
+	}
+	return strconv.FormatUint(value, 10)
+}
+
+// splitBitName splits a bus bit name like a[3] into the bus name and index
+func splitBitName(name string) (string, int, bool) {
+	bus, index, found := strings.Cut(name, "[")
+	if !found {
+		return name, 0, false
+	}
+	i, err := strconv.Atoi(strings.TrimSuffix(index, "]"))
+	if err != nil {
+		return name, 0, false
+	}
+	return bus, i, true
 }
 
 func boolToString(val bool) string {
@@ -62,29 +303,61 @@ func boolToString(val bool) string {
 }
 
 func Compute(expression string) (*Result, error) {
-	expr, vars, err := ParseExpression(expression)
+	expr, vars, wires, err := ParseExpressionWithWires(expression)
 	if err != nil {
 		return nil, err
 	}
+	return ComputeWithWires(expr, vars, wires)
+}
 
+// ComputeExpression computes the truth table of an expression that was already parsed
+func ComputeExpression(expr Expression, vars VariableSet) (*Result, error) {
+	return ComputeWithWires(expr, vars, nil)
+}
+
+// ComputeWithWires computes the truth table with a column for each wire. The wires must be nodes of the expression,
+// as returned by ParseExpressionWithWires.
+func ComputeWithWires(expr Expression, vars VariableSet, wires []Wire) (*Result, error) {
+	return ComputeWith(expr, vars, wires, ComputeOptions{})
+}
+
+// ComputeWith is ComputeWithWires with options, like a larger limit on the input bits
+func ComputeWith(expr Expression, vars VariableSet, wires []Wire, opts ComputeOptions) (*Result, error) {
 	variables := getVarsSlice(vars)
+	if err := opts.check(variables); err != nil {
+		return nil, err
+	}
 	if len(variables) == 0 {
 		value, err := expr.Evaluate(map[string]bool{})
 		if err != nil {
 			return nil, err
 		}
-		return &Result{Variables: nil, Outputs: [][]bool{value}, Assignments: nil}, nil
+		return &Result{Variables: nil, Outputs: [][]bool{value}, Assignments: nil, OutputWidths: outputWidths(expr)}, nil
 	}
 
-	result := Result{Variables: variables}
+	result := Result{Variables: variables, OutputWidths: outputWidths(expr)}
+	dag := NewDAG(expr)
+	for _, w := range wires {
+		if _, ok := dag.index[w.Expression]; !ok {
+			return nil, fmt.Errorf("wire %s is not part of the expression", w.Name)
+		}
+		result.Wires = append(result.Wires, w.Name)
+	}
 	assignments := generateCombinations(len(variables))
 	for _, assignment := range assignments {
-		value, err := expr.Evaluate(getArgs(variables, assignment))
+		values, err := dag.evaluateNodes(getArgs(variables, assignment))
 		if err != nil {
 			return nil, err
 		}
-		result.Outputs = append(result.Outputs, value)
+		result.Outputs = append(result.Outputs, values[len(values)-1])
 		result.Assignments = append(result.Assignments, assignment)
+		if len(wires) > 0 {
+			wireValues := make([][]bool, len(wires))
+			for i, w := range wires {
+				wireValues[i] = values[dag.index[w.Expression]]
+			}
+			result.WireValues = append(result.WireValues, wireValues)
+		}
 	}
 	return &result, nil
 }
@@ -94,25 +367,50 @@ func generateCombinations(n int) [][]bool {
 	result := make([][]bool, total)
 
 	for i := 0; i < total; i++ {
-		combination := make([]bool, n)
-		for j := 0; j < n; j++ {
-			combination[n-j-1] = (i & (1 << j)) != 0
-		}
-		result[i] = combination
+		result[i] = combination(i, n)
 	}
 
 	return result
 }
 
+// combination is row i of a truth table with n inputs, the first input being the most significant bit
+func combination(i, n int) []bool {
+	result := make([]bool, n)
+	for j := 0; j < n; j++ {
+		result[n-j-1] = (i & (1 << j)) != 0
+	}
+	return result
+}
+
 func getVarsSlice(vars VariableSet) []string {
 	result := []string{}
 	for v, _ := range vars {
 		result = append(result, v)
 	}
-	sort.Strings(result)
+	// bits of the same bus are ordered from the most significant, so rows count up in bus values
+	sort.Slice(result, func(i, j int) bool {
+		busI, indexI, _ := splitBitName(result[i])
+		busJ, indexJ, _ := splitBitName(result[j])
+		if busI != busJ {
+			return busI < busJ
+		}
+		return indexI > indexJ
+	})
 	return result
 }
 
+func outputWidths(expr Expression) []int {
+	switch e := expr.(type) {
+	case *BusVariableExpression:
+		if e.NumOutputs() > 1 {
+			return []int{e.NumOutputs()}
+		}
+	case *BusGateExpression:
+		return busOutputWidths[e.op]
+	}
+	return nil
+}
+
 func getArgs(variables []string, assignment []bool) map[string]bool {
 	result := map[string]bool{}
 	for i := 0; i < len(variables); i++ {
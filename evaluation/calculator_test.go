@@ -102,3 +102,145 @@ func TestCompute(t *testing.T) {
 		})
 	}
 }
+
+func TestComputeBuses(t *testing.T) {
+	got, err := Compute("and(a[1],b)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifyEquality(t, len(got.Variables), 2)
+	verifyEquality(t, got.Variables[0], "a[1]")
+	if got.OutputWidths != nil {
+		t.Errorf("expected single bit outputs, got widths %v", got.OutputWidths)
+	}
+
+	got, err = Compute("mux16(a[0..15],b[0..15],s)")
+	if err == nil {
+		t.Errorf("expected error for truth table with 33 input bits, got %d rows", len(got.Assignments))
+	}
+
+	// bus bits are ordered from the most significant, so the rows count up
+	got, err = Compute("dmux4way(x,s[0..1])")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Variables, []string{"s[1]", "s[0]", "x"}) {
+		t.Errorf("unexpected variable order %v", got.Variables)
+	}
+
+	// the limit can be changed
+	expr, vars, err := ParseExpression("and(a,b)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ComputeWith(expr, vars, nil, ComputeOptions{MaxVariables: 2}); err != nil {
+		t.Errorf("unexpected error at the limit: %v", err)
+	}
+	if got, err := ComputeWith(expr, vars, nil, ComputeOptions{MaxVariables: 1}); err == nil {
+		t.Errorf("expected error for truth table with 2 input bits over a limit of 1, got %d rows", len(got.Assignments))
+	}
+}
+
+func TestResultTable(t *testing.T) {
+	tests := []struct {
+		expression string
+		radix      Radix
+		expected   string
+	}{
+		{
+			expression: "and(a,b)",
+			radix:      RadixHex,
+			expected:   "a\tb\tOutput\n0\t0\t0\n0\t1\t0\n1\t0\t0\n1\t1\t1\n",
+		},
+		{
+			expression: "dmux4way(x,s[0..1])",
+			radix:      RadixBinary,
+			expected: "s[1..0]\tx\tOutput\n" +
+				"00\t0\t0  0  0  0\n00\t1\t1  0  0  0\n" +
+				"01\t0\t0  0  0  0\n01\t1\t0  1  0  0\n" +
+				"10\t0\t0  0  0  0\n10\t1\t0  0  1  0\n" +
+				"11\t0\t0  0  0  0\n11\t1\t0  0  0  1\n",
+		},
+		{
+			expression: "let s = xor(a, b), d = dmux(s, b) in or(d)",
+			radix:      RadixBinary,
+			expected:   "a\tb\ts\td\tOutput\n0\t0\t0\t00\t0\n0\t1\t1\t01\t1\n1\t0\t1\t10\t1\n1\t1\t0\t00\t0\n",
+		},
+		{
+			expression: "a[2..4]",
+			radix:      RadixDecimal,
+			expected: "a[4..2]\tOutput\n" +
+				"0\t0\n1\t1\n2\t2\n3\t3\n4\t4\n5\t5\n6\t6\n7\t7\n",
+		},
+		{
+			expression: "and(a[3],a[1])",
+			radix:      RadixDecimal,
+			expected:   "a[3]\ta[1]\tOutput\n0\t0\t0\n0\t1\t0\n1\t0\t0\n1\t1\t1\n",
+		},
+		{
+			expression: "or8way(a[7],a[6],a[3],a[2],a[1],0,0,0)",
+			radix:      RadixHex,
+			expected: "a[7..6]\ta[3..1]\tOutput\n" +
+				"0x0\t0x0\t0\n0x0\t0x1\t1\n0x0\t0x2\t1\n0x0\t0x3\t1\n0x0\t0x4\t1\n0x0\t0x5\t1\n0x0\t0x6\t1\n0x0\t0x7\t1\n" +
+				"0x1\t0x0\t1\n0x1\t0x1\t1\n0x1\t0x2\t1\n0x1\t0x3\t1\n0x1\t0x4\t1\n0x1\t0x5\t1\n0x1\t0x6\t1\n0x1\t0x7\t1\n" +
+				"0x2\t0x0\t1\n0x2\t0x1\t1\n0x2\t0x2\t1\n0x2\t0x3\t1\n0x2\t0x4\t1\n0x2\t0x5\t1\n0x2\t0x6\t1\n0x2\t0x7\t1\n" +
+				"0x3\t0x0\t1\n0x3\t0x1\t1\n0x3\t0x2\t1\n0x3\t0x3\t1\n0x3\t0x4\t1\n0x3\t0x5\t1\n0x3\t0x6\t1\n0x3\t0x7\t1\n",
+		},
+		{
+			expression: "dmux16(and16(a[12..15],a[12..15],a[12..15],a[12..15],a[12..15],a[12..15],a[12..15],a[12..15]),a[15])",
+			radix:      RadixHex,
+			expected: "a[15..12]\tOutput\n" +
+				"0x0\t0x0000  0x0000\n0x1\t0x1111  0x0000\n0x2\t0x2222  0x0000\n0x3\t0x3333  0x0000\n" +
+				"0x4\t0x4444  0x0000\n0x5\t0x5555  0x0000\n0x6\t0x6666  0x0000\n0x7\t0x7777  0x0000\n" +
+				"0x8\t0x0000  0x8888\n0x9\t0x0000  0x9999\n0xA\t0x0000  0xAAAA\n0xB\t0x0000  0xBBBB\n" +
+				"0xC\t0x0000  0xCCCC\n0xD\t0x0000  0xDDDD\n0xE\t0x0000  0xEEEE\n0xF\t0x0000  0xFFFF\n",
+		},
+		{
+			expression: "not16(and16(0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,1,1,1,1,1,1,1,1,1,1,1,1,1,1,1,1))",
+			radix:      RadixDecimal,
+			expected:   "65535\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.expression, func(t *testing.T) {
+			result, err := Compute(tc.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			verifyEquality(t, result.Table(tc.radix), tc.expected)
+		})
+	}
+}
+
+func TestResultReorder(t *testing.T) {
+	result, err := Compute("let s = xor(a, b) in mux(s, c[1], c[0])")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reordered, err := result.Reorder([]string{"c", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(reordered.Variables, []string{"c[1]", "c[0]", "b", "a"}) {
+		t.Errorf("unexpected variable order %v", reordered.Variables)
+	}
+	for row, assignment := range reordered.Assignments {
+		if !reflect.DeepEqual(assignment, generateCombinations(4)[row]) {
+			t.Errorf("row %d has inputs %v, expected the rows to count up", row, assignment)
+		}
+		args := getArgs(reordered.Variables, assignment)
+		s := Xor(args["a"], args["b"])
+		verifyEquality(t, reordered.WireValues[row][0][0], s)
+		verifyEquality(t, reordered.Outputs[row][0], Mux(s, args["c[1]"], args["c[0]"]))
+	}
+	if header := reordered.Header(); !reflect.DeepEqual(header, []string{"c[1..0]", "b", "a", "s", "Output"}) {
+		t.Errorf("unexpected header %v", header)
+	}
+
+	for _, order := range [][]string{{"d"}, {"a", "a"}, {"c", "c[0]"}} {
+		if _, err := result.Reorder(order); err == nil {
+			t.Errorf("expected error for order %v", order)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package evaluation
+
+import (
+	"fmt"
+	"go/format"
+	"strings"
+)
+
+type CodegenOptions struct {
+	Package  string // Go only, defaults to "generated"
+	FuncName string // defaults to "F"
+	// Bitsliced generates code over 64-bit words, evaluating 64 independent input assignments
+	// at once: bit i of every argument and result belongs to assignment i.
+	Bitsliced bool
+}
+
+func (o CodegenOptions) withDefaults() CodegenOptions {
+	if o.Package == "" {
+		o.Package = "generated"
+	}
+	if o.FuncName == "" {
+		o.FuncName = "F"
+	}
+	return o
+}
+
+// GenerateGo emits a Go source file with a single function computing the expression.
+// Parameters are the expression's variables in sorted order and there is one result per output.
+func GenerateGo(expr Expression, opts CodegenOptions) (string, error) {
+	opts = opts.withDefaults()
+	params, body, outputs, err := generateBody(expr, goSyntax(opts.Bitsliced))
+	if err != nil {
+		return "", err
+	}
+	valueType := "bool"
+	if opts.Bitsliced {
+		valueType = "uint64"
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "// Code generated by bool-calculator. DO NOT EDIT.\n\npackage %s\n\n", opts.Package)
+	fmt.Fprintf(&sb, "func %s(", opts.FuncName)
+	if len(params) > 0 {
+		fmt.Fprintf(&sb, "%s %s", strings.Join(params, ", "), valueType)
+	}
+	sb.WriteString(") ")
+	if len(outputs) == 1 {
+		sb.WriteString(valueType)
+	} else {
+		sb.WriteString("(" + strings.Repeat(valueType+", ", len(outputs)-1) + valueType + ")")
+	}
+	sb.WriteString(" {\n")
+	sb.WriteString(body)
+	fmt.Fprintf(&sb, "return %s\n}\n", strings.Join(outputs, ", "))
+
+	src, err := format.Source([]byte(sb.String()))
+	if err != nil {
+		return "", fmt.Errorf("generated invalid Go code: %w", err)
+	}
+	return string(src), nil
+}
+
+// GenerateGoTest emits a Go test file for the function produced by GenerateGo with the same options.
+// The expected values are taken from the truth table of the expression. The table is named after the
+// function, so that the tests of several functions can be generated into the same package.
+func GenerateGoTest(expr Expression, opts CodegenOptions) (string, error) {
+	opts = opts.withDefaults()
+	vars, err := collectVariables(expr)
+	if err != nil {
+		return "", err
+	}
+	result, err := ComputeExpression(expr, vars)
+	if err != nil {
+		return "", err
+	}
+	table := "truthTable" + opts.FuncName
+	assignments := result.Assignments
+	if len(result.Variables) == 0 {
+		assignments = [][]bool{{}}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "// Code generated by bool-calculator. DO NOT EDIT.\n\npackage %s\n\nimport \"testing\"\n\n", opts.Package)
+	// long expressions are formatted over several lines
+	for _, line := range strings.Split(Format(expr), "\n") {
+		fmt.Fprintf(&sb, "// %s\n", line)
+	}
+	fmt.Fprintf(&sb, "var %s = []struct {\n\tin  []bool\n\tout []bool\n}{\n", table)
+	for i := range assignments {
+		fmt.Fprintf(&sb, "\t{%s, %s},\n", goBoolSlice(assignments[i]), goBoolSlice(result.Outputs[i]))
+	}
+	sb.WriteString("}\n\n")
+
+	args := make([]string, len(result.Variables))
+	results := make([]string, len(result.Outputs[0]))
+	for i := range results {
+		results[i] = fmt.Sprintf("o%d", i)
+	}
+
+	fmt.Fprintf(&sb, "func Test%s(t *testing.T) {\n", opts.FuncName)
+	if !opts.Bitsliced {
+		for i := range args {
+			args[i] = fmt.Sprintf("row.in[%d]", i)
+		}
+		fmt.Fprintf(&sb, "for _, row := range %s {\n", table)
+		fmt.Fprintf(&sb, "%s := %s(%s)\n", strings.Join(results, ", "), opts.FuncName, strings.Join(args, ", "))
+		fmt.Fprintf(&sb, "got := []bool{%s}\n", strings.Join(results, ", "))
+		sb.WriteString("for i := range got {\nif got[i] != row.out[i] {\n")
+		sb.WriteString("t.Errorf(\"output %d for inputs %v: got %v, want %v\", i, row.in, got[i], row.out[i])\n")
+		sb.WriteString("}\n}\n}\n}\n")
+	} else {
+		for i := range args {
+			args[i] = fmt.Sprintf("in[%d]", i)
+		}
+		fmt.Fprintf(&sb, "for start := 0; start < len(%s); start += 64 {\n", table)
+		fmt.Fprintf(&sb, "rows := %[1]s[start:min(start+64, len(%[1]s))]\n", table)
+		fmt.Fprintf(&sb, "in := make([]uint64, %d)\n", len(args))
+		sb.WriteString("for lane, row := range rows {\nfor i, v := range row.in {\nif v {\nin[i] |= 1 << lane\n}\n}\n}\n")
+		fmt.Fprintf(&sb, "%s := %s(%s)\n", strings.Join(results, ", "), opts.FuncName, strings.Join(args, ", "))
+		fmt.Fprintf(&sb, "got := []uint64{%s}\n", strings.Join(results, ", "))
+		sb.WriteString("for lane, row := range rows {\nfor i := range got {\nif (got[i]>>lane&1 == 1) != row.out[i] {\n")
+		sb.WriteString("t.Errorf(\"output %d for inputs %v: got %v, want %v\", i, row.in, got[i]>>lane&1 == 1, row.out[i])\n")
+		sb.WriteString("}\n}\n}\n}\n}\n")
+	}
+
+	src, err := format.Source([]byte(sb.String()))
+	if err != nil {
+		return "", fmt.Errorf("generated invalid Go code: %w", err)
+	}
+	return string(src), nil
+}
+
+func goBoolSlice(values []bool) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = fmt.Sprint(v)
+	}
+	return "[]bool{" + strings.Join(parts, ", ") + "}"
+}
+
+// GenerateC emits a C99 function computing the expression. Single-output expressions return their
+// value, multi-output expressions write them through trailing pointer parameters.
+func GenerateC(expr Expression, opts CodegenOptions) (string, error) {
+	opts = opts.withDefaults()
+	params, body, outputs, err := generateBody(expr, cSyntax(opts.Bitsliced))
+	if err != nil {
+		return "", err
+	}
+	valueType := "bool"
+	header := "#include <stdbool.h>\n"
+	if opts.Bitsliced {
+		valueType = "uint64_t"
+		header = "#include <stdint.h>\n"
+	}
+
+	signature := []string{}
+	for _, p := range params {
+		signature = append(signature, valueType+" "+p)
+	}
+	returnType := valueType
+	if len(outputs) > 1 {
+		returnType = "void"
+		for i := range outputs {
+			signature = append(signature, fmt.Sprintf("%s *out%d", valueType, i))
+		}
+	}
+	if len(signature) == 0 {
+		signature = []string{"void"}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "/* Code generated by bool-calculator. DO NOT EDIT. */\n\n%s\n", header)
+	fmt.Fprintf(&sb, "%s %s(%s) {\n", returnType, opts.FuncName, strings.Join(signature, ", "))
+	for _, line := range strings.Split(strings.TrimSuffix(body, "\n"), "\n") {
+		if line != "" {
+			sb.WriteString("    " + line + "\n")
+		}
+	}
+	if len(outputs) == 1 {
+		fmt.Fprintf(&sb, "    return %s;\n", outputs[0])
+	} else {
+		for i, out := range outputs {
+			fmt.Fprintf(&sb, "    *out%d = %s;\n", i, out)
+		}
+	}
+	sb.WriteString("}\n")
+	return sb.String(), nil
+}
+
+type codeSyntax struct {
+	declare  string // format for a temporary declaration, taking name and value
+	constant [2]string
+	not      string
+	and      string
+	or       string
+	nand     string
+	xor      string
+	mux      string // takes a, b and sel
+	keywords map[string]struct{}
+}
+
+var goKeywords = toSet("break", "case", "chan", "const", "continue", "default", "defer", "else", "fallthrough", "for",
+	"func", "go", "goto", "if", "import", "interface", "map", "package", "range", "return", "select", "struct",
+	"switch", "type", "var", "bool", "uint64", "true", "false", "min", "max", "len")
+
+var cKeywords = toSet("auto", "break", "case", "char", "const", "continue", "default", "do", "double", "else", "enum",
+	"extern", "float", "for", "goto", "if", "inline", "int", "long", "register", "restrict", "return", "short",
+	"signed", "sizeof", "static", "struct", "switch", "typedef", "union", "unsigned", "void", "volatile", "while",
+	"bool", "true", "false")
+
+func toSet(names ...string) map[string]struct{} {
+	result := map[string]struct{}{}
+	for _, n := range names {
+		result[n] = struct{}{}
+	}
+	return result
+}
+
+func goSyntax(bitsliced bool) codeSyntax {
+	if bitsliced {
+		return codeSyntax{"%s := %s", [2]string{"uint64(0)", "^uint64(0)"}, "^%s", "%s & %s", "%s | %s", "^(%s & %s)", "%s ^ %s", "(%[3]s & %[1]s) | (^%[3]s & %[2]s)", goKeywords}
+	}
+	return codeSyntax{"%s := %s", [2]string{"false", "true"}, "!%s", "%s && %s", "%s || %s", "!(%s && %s)", "%s != %s", "(%[3]s && %[1]s) || (!%[3]s && %[2]s)", goKeywords}
+}
+
+func cSyntax(bitsliced bool) codeSyntax {
+	if bitsliced {
+		return codeSyntax{"const uint64_t %s = %s;", [2]string{"UINT64_C(0)", "~UINT64_C(0)"}, "~%s", "%s & %s", "%s | %s", "~(%s & %s)", "%s ^ %s", "(%[3]s & %[1]s) | (~%[3]s & %[2]s)", cKeywords}
+	}
+	return codeSyntax{"const bool %s = %s;", [2]string{"false", "true"}, "!%s", "%s && %s", "%s || %s", "!(%s && %s)", "%s != %s", "%[3]s ? %[1]s : %[2]s", cKeywords}
+}
+
+func generateBody(expr Expression, syntax codeSyntax) ([]string, string, []string, error) {
+	vars, err := collectVariables(expr)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	b := &codeBuilder{syntax: syntax, params: map[string]string{}, temps: map[string]string{}}
+	params := []string{}
+	for _, v := range getVarsSlice(vars) {
+		b.params[v] = identifier(v, syntax.keywords)
+		params = append(params, b.params[v])
+	}
+	outputs, err := lower[string](expr, b)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return params, b.body.String(), outputs, nil
+}
+
+// identifier maps a variable name to a valid identifier. Variable names never contain '_', so
+// escaped names and the generated temporaries can't collide with them.
+func identifier(name string, keywords map[string]struct{}) string {
+	var sb strings.Builder
+	for i := 0; i < len(name); i++ {
+		if isLetter(name[i]) || ('0' <= name[i] && name[i] <= '9' && i > 0) {
+			sb.WriteByte(name[i])
+		} else {
+			sb.WriteByte('_')
+		}
+	}
+	id := sb.String()
+	if _, ok := keywords[id]; ok {
+		id += "_"
+	}
+	return id
+}
+
+// codeBuilder emits one temporary per gate. Identical gates over the same operands reuse the temporary.
+type codeBuilder struct {
+	syntax codeSyntax
+	params map[string]string
+	temps  map[string]string
+	body   strings.Builder
+}
+
+func (b *codeBuilder) temp(value string) string {
+	if name, ok := b.temps[value]; ok {
+		return name
+	}
+	name := fmt.Sprintf("t_%d", len(b.temps))
+	b.temps[value] = name
+	fmt.Fprintf(&b.body, b.syntax.declare+"\n", name, value)
+	return name
+}
+
+func (b *codeBuilder) Const(value bool) string {
+	if value {
+		return b.syntax.constant[1]
+	}
+	return b.syntax.constant[0]
+}
+
+func (b *codeBuilder) Var(name string) string  { return b.params[name] }
+func (b *codeBuilder) Not(x string) string     { return b.temp(fmt.Sprintf(b.syntax.not, x)) }
+func (b *codeBuilder) And(x, y string) string  { return b.temp(fmt.Sprintf(b.syntax.and, x, y)) }
+func (b *codeBuilder) Or(x, y string) string   { return b.temp(fmt.Sprintf(b.syntax.or, x, y)) }
+func (b *codeBuilder) Nand(x, y string) string { return b.temp(fmt.Sprintf(b.syntax.nand, x, y)) }
+func (b *codeBuilder) Xor(x, y string) string  { return b.temp(fmt.Sprintf(b.syntax.xor, x, y)) }
+func (b *codeBuilder) Mux(x, y, sel string) string {
+	return b.temp(fmt.Sprintf(b.syntax.mux, x, y, sel))
+}
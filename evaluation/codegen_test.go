@@ -0,0 +1,170 @@
+package evaluation
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateGo(t *testing.T) {
+	expr, _, err := ParseExpression("dmux(xor(a,b),not(c))")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := GenerateGo(expr, CodegenOptions{})
+	if err != nil {
+		t.Fatalf("GenerateGo() returned unexpected error: %v", err)
+	}
+	expected := `// Code generated by bool-calculator. DO NOT EDIT.
+
+package generated
+
+func F(a, b, c bool) (bool, bool) {
+	t_0 := a != b
+	t_1 := !c
+	t_2 := !t_1
+	t_3 := t_0 && t_2
+	t_4 := t_0 && t_1
+	return t_3, t_4
+}
+`
+	verifyEquality(t, got, expected)
+}
+
+func TestGenerateGoSharesIdenticalGates(t *testing.T) {
+	expr, _, err := ParseExpression("or(and(a,b),not(and(a,b)))")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := GenerateGo(expr, CodegenOptions{FuncName: "Shared"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifyEquality(t, strings.Count(got, "a && b"), 1)
+}
+
+func TestGenerateC(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		opts     CodegenOptions
+		expected string
+	}{
+		{
+			name:  "single output",
+			input: "mux(a,int,s)",
+			opts:  CodegenOptions{FuncName: "select"},
+			expected: `/* Code generated by bool-calculator. DO NOT EDIT. */
+
+#include <stdbool.h>
+
+bool select(bool a, bool int_, bool s) {
+    const bool t_0 = s ? a : int_;
+    return t_0;
+}
+`,
+		},
+		{
+			name:  "bitsliced with multiple outputs",
+			input: "dmux(1,s)",
+			opts:  CodegenOptions{Bitsliced: true},
+			expected: `/* Code generated by bool-calculator. DO NOT EDIT. */
+
+#include <stdint.h>
+
+void F(uint64_t s, uint64_t *out0, uint64_t *out1) {
+    const uint64_t t_0 = ~s;
+    const uint64_t t_1 = ~UINT64_C(0) & t_0;
+    const uint64_t t_2 = ~UINT64_C(0) & s;
+    *out0 = t_1;
+    *out1 = t_2;
+}
+`,
+		},
+		{
+			name:  "no inputs",
+			input: "1",
+			expected: `/* Code generated by bool-calculator. DO NOT EDIT. */
+
+#include <stdbool.h>
+
+bool F(void) {
+    return true;
+}
+`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			expr, _, err := ParseExpression(tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := GenerateC(expr, tc.opts)
+			if err != nil {
+				t.Fatalf("GenerateC() returned unexpected error: %v", err)
+			}
+			verifyEquality(t, got, tc.expected)
+		})
+	}
+}
+
+func TestGeneratedGoPassesGeneratedTest(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiles generated code with the go tool")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not available")
+	}
+
+	expressions := []string{
+		"0",
+		"nand(a,b)",
+		"mux(xor(a,b),nand(b,c),or(not(a),c))",
+		"dmux(and(a,b),or(c,d))",
+		"xor(and(a,b),xor(c,xor(d,xor(e,xor(f,g)))))", // 128 rows, so two bitsliced words
+		// formatted over several lines
+		"or(and(and(alpha,beta),and(gamma,delta)),xor(and(epsilon,zeta),and(eta,theta)))",
+	}
+	for _, bitsliced := range []bool{false, true} {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "go.mod"), "module generated\n\ngo 1.23\n")
+		for i, expression := range expressions {
+			expr, _, err := ParseExpression(expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts := CodegenOptions{FuncName: "F" + string(rune('A'+i)), Bitsliced: bitsliced}
+			src, err := GenerateGo(expr, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			test, err := GenerateGoTest(expr, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// every function and its test go in the same package
+			name := strings.ToLower(opts.FuncName)
+			writeFile(t, filepath.Join(dir, name+".go"), src)
+			writeFile(t, filepath.Join(dir, name+"_test.go"), test)
+		}
+
+		cmd := exec.Command(goTool, "test", "./...")
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GOFLAGS=", "GOWORK=off")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Errorf("generated code (bitsliced=%v) failed its tests: %v\n%s", bitsliced, err, out)
+		}
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
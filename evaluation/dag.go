@@ -0,0 +1,158 @@
+package evaluation
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HashCons returns the expression with structurally identical subexpressions merged into the same
+// node, which turns the tree into a DAG. ParseExpression already returns hash-consed expressions.
+func HashCons(expr Expression) Expression {
+	return newHashConser().share(expr)
+}
+
+func newHashConser() *hashConser {
+	return &hashConser{nodes: map[string]Expression{}, ids: map[Expression]int{}}
+}
+
+type hashConser struct {
+	nodes map[string]Expression // shared node by structure
+	ids   map[Expression]int    // number of each shared node, used in the keys of its parents
+}
+
+func (h *hashConser) share(expr Expression) Expression {
+	if _, ok := h.ids[expr]; ok {
+		return expr
+	}
+
+	name, args := formatParts(expr)
+	var key strings.Builder
+	key.WriteString(name)
+	if e, ok := expr.(*StateExpression); ok {
+		// unnamed sequential gates with the same inputs still have their own state
+		fmt.Fprintf(&key, "%v", e.state)
+	}
+	shared := make([]Expression, len(args))
+	changed := false
+	for i, arg := range args {
+		shared[i] = h.share(arg)
+		changed = changed || shared[i] != arg
+		fmt.Fprintf(&key, " %d", h.ids[shared[i]])
+	}
+
+	if node, ok := h.nodes[key.String()]; ok {
+		return node
+	}
+	node := expr
+	if changed {
+		node = withInputs(expr, shared)
+	}
+	h.nodes[key.String()] = node
+	h.ids[node] = len(h.ids)
+	return node
+}
+
+// DAG is a hash-consed expression that evaluates every shared subexpression only once per assignment
+type DAG struct {
+	root  Expression
+	nodes []Expression // distinct nodes, each one after its arguments
+	index map[Expression]int
+}
+
+func NewDAG(expr Expression) *DAG {
+	d := &DAG{root: HashCons(expr), index: map[Expression]int{}}
+	d.visit(d.root)
+	return d
+}
+
+func (d *DAG) visit(expr Expression) {
+	if _, ok := d.index[expr]; ok {
+		return
+	}
+	_, args := formatParts(expr)
+	for _, arg := range args {
+		d.visit(arg)
+	}
+	d.index[expr] = len(d.nodes)
+	d.nodes = append(d.nodes, expr)
+}
+
+// Root is the hash-consed expression
+func (d *DAG) Root() Expression {
+	return d.root
+}
+
+// NumNodes counts the distinct subexpressions, including variables and literals
+func (d *DAG) NumNodes() int {
+	return len(d.nodes)
+}
+
+// NumGates counts the distinct gates. Gates that appear several times in the expression are counted once.
+func (d *DAG) NumGates() int {
+	gates := 0
+	for _, node := range d.nodes {
+		if _, args := formatParts(node); len(args) > 0 {
+			gates++
+		}
+	}
+	return gates
+}
+
+func (d *DAG) NumOutputs() int {
+	return d.root.NumOutputs()
+}
+
+// Evaluate computes the nodes in order, so each gate reads the remembered outputs of its arguments
+func (d *DAG) Evaluate(args map[string]bool) ([]bool, error) {
+	values, err := d.evaluateNodes(args)
+	if err != nil {
+		return nil, err
+	}
+	return values[len(values)-1], nil
+}
+
+// evaluateNodes returns the outputs of every node, in the order of d.nodes
+func (d *DAG) evaluateNodes(args map[string]bool) ([][]bool, error) {
+	values := make([][]bool, len(d.nodes))
+	for i, node := range d.nodes {
+		_, children := formatParts(node)
+		if len(children) == 0 {
+			value, err := node.Evaluate(args)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = value
+			continue
+		}
+		in := []bool{}
+		for _, child := range children {
+			in = append(in, values[d.index[child]]...)
+		}
+		value, err := applyGate(node, in, args)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// applyGate computes the outputs of a gate from the values of its inputs
+func applyGate(expr Expression, in []bool, args map[string]bool) ([]bool, error) {
+	switch e := expr.(type) {
+	case *NotExpression:
+		return []bool{Not(in[0])}, nil
+	case *BinaryExpression:
+		return []bool{binaryGate(e.op, in[0], in[1])}, nil
+	case *MuxExpression:
+		return []bool{Mux(in[0], in[1], in[2])}, nil
+	case *DmuxExpression:
+		a, b := Dmux(in[0], in[1])
+		return []bool{a, b}, nil
+	case *BusGateExpression:
+		return evaluateBusGate(e.op, in), nil
+	default:
+		// sequential gates output their current state, which doesn't depend on the inputs
+		return expr.Evaluate(args)
+	}
+}
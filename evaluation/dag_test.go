@@ -0,0 +1,99 @@
+package evaluation
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHashCons(t *testing.T) {
+	expr, _, err := ParseExpression("xor(and(a,b),or(and(a,b),not(and(a,b))))")
+	if err != nil {
+		t.Fatal(err)
+	}
+	xor := expr.(*BinaryExpression)
+	or := xor.expressions[1].(*BinaryExpression)
+	not := or.expressions[1].(*NotExpression)
+	if xor.expressions[0] != or.expressions[0] || xor.expressions[0] != not.expression {
+		t.Errorf("identical subexpressions aren't shared")
+	}
+
+	dag := NewDAG(expr)
+	verifyEquality(t, dag.NumGates(), 4) // and, not, or, xor
+	verifyEquality(t, dag.NumNodes(), 6)
+	if dag.Root() != expr {
+		t.Errorf("hash-consing a parsed expression again should keep it")
+	}
+
+	// unnamed sequential gates keep their own state even with the same inputs
+	dag = NewDAG(mustParse(t, "and(dff(a),dff(a))"))
+	verifyEquality(t, dag.NumGates(), 3)
+	dag = NewDAG(mustParse(t, "and(dff:q(a),q)"))
+	verifyEquality(t, dag.NumNodes(), 4)
+}
+
+func TestHashConsRebuiltExpressions(t *testing.T) {
+	// restricted expressions share their identical nodes already, so hash-consing them changes nothing
+	expr := Restrict(mustParse(t, "xor(and(x,y),or(z,and(x,mux(y,w,s))))"), map[string]bool{"s": true})[0]
+	verifyEquality(t, Format(expr), "xor(and(x, y), or(z, and(x, y)))")
+	verifyEquality(t, NewDAG(expr).NumGates(), 3)
+	verifyEquality(t, Format(HashCons(expr)), Format(expr))
+}
+
+func TestDAGEvaluation(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	expressions := append([]string{
+		"xor(and(a,b),or(and(a,b),not(and(a,b))))",
+		"dmux(mux(a,b,c),mux(a,b,c))",
+		"and(dff(a),dff(a))",
+	}, busExpressions...)
+	for _, input := range expressions {
+		expr, vars, err := ParseExpression(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dag := NewDAG(expr)
+		verifyEquality(t, dag.NumOutputs(), expr.NumOutputs())
+		for i := 0; i < 50; i++ {
+			args := map[string]bool{}
+			for v := range vars {
+				args[v] = rng.Intn(2) == 1
+			}
+			expected, err := expr.Evaluate(args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := dag.Evaluate(args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, expected) {
+				t.Fatalf("DAG of %v evaluates to %v instead of %v for %v", input, got, expected, args)
+			}
+		}
+	}
+
+	if _, err := NewDAG(mustParse(t, "and(a,b)")).Evaluate(map[string]bool{"a": true}); err == nil {
+		t.Errorf("expected error for a missing variable")
+	}
+}
+
+func TestSharedLowering(t *testing.T) {
+	expr := mustParse(t, "or(xor(and(a,b),c),not(xor(and(a,b),c)))")
+	code, err := GenerateGo(expr, CodegenOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// and, xor, not and or each get one temporary
+	verifyEquality(t, strings.Count(code, ":="), 4)
+}
+
+func mustParse(t *testing.T, input string) Expression {
+	t.Helper()
+	expr, _, err := ParseExpression(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return expr
+}
@@ -0,0 +1,230 @@
+package evaluation
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Notation int
+
+const (
+	NotationPrefix      Notation = iota // and(a, not(b)), the notation accepted by ParseExpression
+	NotationInfix                       // a & !b, mux and dmux keep the prefix form
+	NotationSExpression                 // (and a (not b))
+)
+
+func (n Notation) String() string {
+	switch n {
+	case NotationPrefix:
+		return "prefix"
+	case NotationInfix:
+		return "infix"
+	case NotationSExpression:
+		return "sexpr"
+	default:
+		return "UNHANDLED"
+	}
+}
+
+func ParseNotation(name string) (Notation, error) {
+	for _, n := range []Notation{NotationPrefix, NotationInfix, NotationSExpression} {
+		if n.String() == name {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown notation %q, expected one of prefix, infix, sexpr", name)
+}
+
+type FormatOptions struct {
+	Notation Notation
+	Width    int    // maximum line width before gate arguments get wrapped, defaults to 80
+	Indent   string // defaults to two spaces
+}
+
+// Format renders an expression in canonical prefix notation. ParseExpression(Format(e)) yields a
+// structurally equal expression.
+func Format(expr Expression) string {
+	return FormatWith(expr, FormatOptions{})
+}
+
+// FormatWith renders an expression in the given notation. Gates whose arguments don't fit in the
+// line width are broken up with one argument per line. Infix output is never wrapped.
+func FormatWith(expr Expression, opts FormatOptions) string {
+	if opts.Width <= 0 {
+		opts.Width = 80
+	}
+	if opts.Indent == "" {
+		opts.Indent = "  "
+	}
+	f := formatter{opts: opts}
+	if opts.Notation == NotationInfix {
+		return f.infix(expr, true)
+	}
+	return f.wrapped(expr, "")
+}
+
+// FormatWithWires renders the expression as a let binding the wires, and writes every use of a wire as its name.
+// Lets nested in the expression are moved to the front, which keeps the meaning since the parser gives every wire a
+// name of its own.
+func FormatWithWires(expr Expression, wires []Wire, opts FormatOptions) string {
+	if len(wires) == 0 {
+		return FormatWith(expr, opts)
+	}
+	if opts.Width <= 0 {
+		opts.Width = 80
+	}
+	if opts.Indent == "" {
+		opts.Indent = "  "
+	}
+	f := formatter{opts: opts, names: map[Expression]string{}}
+	render := func(e Expression, indent string) (string, string) {
+		if opts.Notation == NotationInfix {
+			return f.infix(e, true), f.infix(e, true)
+		}
+		return f.flat(e), f.wrapped(e, indent)
+	}
+
+	flat, wrapped := []string{}, []string{}
+	for _, w := range wires {
+		if _, ok := f.names[w.Expression]; ok {
+			continue // same value as an earlier wire, which is used instead
+		}
+		short, long := render(w.Expression, opts.Indent)
+		flat = append(flat, w.Name+" = "+short)
+		wrapped = append(wrapped, w.Name+" = "+long)
+		f.names[w.Expression] = w.Name
+	}
+	short, long := render(expr, "")
+
+	line := "let " + strings.Join(flat, ", ") + " in " + short
+	if opts.Notation == NotationInfix || len(line) <= opts.Width {
+		return line
+	}
+	return "let\n" + opts.Indent + strings.Join(wrapped, ",\n"+opts.Indent) + "\nin " + long
+}
+
+type formatter struct {
+	opts  FormatOptions
+	names map[Expression]string // wires written by name
+}
+
+// formatParts splits an expression into its gate name and arguments. Leaves have no arguments.
+func formatParts(expr Expression) (string, []Expression) {
+	switch e := expr.(type) {
+	case *LiteralExpression:
+		return boolToString(e.value), nil
+	case *VariableExpression:
+		return e.variableName, nil
+	case *BusVariableExpression:
+		return sliceName(e.name, e.low, e.high), nil
+	case *NotExpression:
+		return "not", []Expression{e.expression}
+	case *BinaryExpression:
+		return gateName(e.op), e.expressions
+	case *MuxExpression:
+		return "mux", e.expressions
+	case *DmuxExpression:
+		return "dmux", e.expressions
+	case *BusGateExpression:
+		return gateName(e.op), e.expressions
+	case *StateExpression:
+		if e.label != "" {
+			return gateName(e.op) + ":" + e.label, e.expressions
+		}
+		return gateName(e.op), e.expressions
+	default:
+		return fmt.Sprintf("<%T>", expr), nil
+	}
+}
+
+func gateName(op TokenType) string {
+	for name, tokenType := range keywords {
+		if tokenType == op {
+			return name
+		}
+	}
+	return op.String()
+}
+
+func (f formatter) flat(expr Expression) string {
+	if wire, ok := f.names[expr]; ok {
+		return wire
+	}
+	name, args := formatParts(expr)
+	if args == nil {
+		return name
+	}
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		parts[i] = f.flat(arg)
+	}
+	if f.opts.Notation == NotationSExpression {
+		return "(" + name + " " + strings.Join(parts, " ") + ")"
+	}
+	return name + "(" + strings.Join(parts, ", ") + ")"
+}
+
+func (f formatter) wrapped(expr Expression, indent string) string {
+	flat := f.flat(expr)
+	name, args := formatParts(expr)
+	if _, ok := f.names[expr]; ok || args == nil || len(indent)+len(flat) <= f.opts.Width {
+		return flat
+	}
+
+	inner := indent + f.opts.Indent
+	var sb strings.Builder
+	if f.opts.Notation == NotationSExpression {
+		sb.WriteString("(" + name)
+		for _, arg := range args {
+			sb.WriteString("\n" + inner + f.wrapped(arg, inner))
+		}
+		sb.WriteString(")")
+		return sb.String()
+	}
+	sb.WriteString(name + "(")
+	for i, arg := range args {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString("\n" + inner + f.wrapped(arg, inner))
+	}
+	sb.WriteString("\n" + indent + ")")
+	return sb.String()
+}
+
+var infixOperators = map[TokenType]string{
+	TokenAnd: " & ",
+	TokenOr:  " | ",
+	TokenXor: " ^ ",
+}
+
+func (f formatter) infix(expr Expression, isRoot bool) string {
+	if wire, ok := f.names[expr]; ok {
+		return wire
+	}
+	switch e := expr.(type) {
+	case *NotExpression:
+		return "!" + f.infix(e.expression, false)
+	case *BinaryExpression:
+		if len(e.expressions) != 2 {
+			break // both inputs come from a single multi-output expression
+		}
+		if e.op == TokenNand {
+			return "!(" + f.infix(e.expressions[0], false) + " & " + f.infix(e.expressions[1], false) + ")"
+		}
+		result := f.infix(e.expressions[0], false) + infixOperators[e.op] + f.infix(e.expressions[1], false)
+		if isRoot {
+			return result
+		}
+		return "(" + result + ")"
+	}
+	name, args := formatParts(expr)
+	if args == nil {
+		return name
+	}
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		parts[i] = f.infix(arg, true)
+	}
+	return name + "(" + strings.Join(parts, ", ") + ")"
+}
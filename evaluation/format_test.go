@@ -0,0 +1,162 @@
+package evaluation
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFormatRoundTrip(t *testing.T) {
+	inputs := []string{
+		"1",
+		"foo",
+		"not( X )",
+		"nand(a,b)",
+		"and(dmux(or(a,b),c))",
+		"mux(xor(a,b),nand(b,c),or(not(a),c))",
+		"or(and(and(and(alpha,beta),and(gamma,delta)),and(and(epsilon,zeta),and(eta,theta))),not(xor(iota,kappa)))",
+	}
+
+	for _, input := range inputs {
+		expr, _, err := ParseExpression(input)
+		if err != nil {
+			t.Fatalf("failed to parse %v: %v", input, err)
+		}
+		for _, width := range []int{0, 10} {
+			formatted := FormatWith(expr, FormatOptions{Width: width})
+			reparsed, _, err := ParseExpression(formatted)
+			if err != nil {
+				t.Errorf("failed to parse formatted expression %q: %v", formatted, err)
+				continue
+			}
+			if !reflect.DeepEqual(reparsed, expr) {
+				t.Errorf("formatted expression %q doesn't round trip to %v", formatted, input)
+			}
+			if FormatWith(reparsed, FormatOptions{Width: width}) != formatted {
+				t.Errorf("formatting %q is not idempotent", formatted)
+			}
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		opts     FormatOptions
+		expected string
+	}{
+		{
+			name:     "canonical spacing",
+			input:    " and( a,not(b) ) ",
+			expected: "and(a, not(b))",
+		},
+		{
+			name:  "wrapped prefix",
+			input: "mux(and(a,b),or(c,d),sel)",
+			opts:  FormatOptions{Width: 20, Indent: "    "},
+			expected: `mux(
+    and(a, b),
+    or(c, d),
+    sel
+)`,
+		},
+		{
+			name:  "nested wrapping",
+			input: "and(or(aaaa,bbbb),or(cccc,dddddd))",
+			opts:  FormatOptions{Width: 17},
+			expected: `and(
+  or(aaaa, bbbb),
+  or(
+    cccc,
+    dddddd
+  )
+)`,
+		},
+		{
+			name:     "s-expression",
+			input:    "mux(and(a,b),not(c),1)",
+			opts:     FormatOptions{Notation: NotationSExpression},
+			expected: "(mux (and a b) (not c) 1)",
+		},
+		{
+			name:  "wrapped s-expression",
+			input: "mux(and(a,b),not(c),1)",
+			opts:  FormatOptions{Notation: NotationSExpression, Width: 15},
+			expected: `(mux
+  (and a b)
+  (not c)
+  1)`,
+		},
+		{
+			name:     "infix",
+			input:    "or(and(a,not(b)),xor(nand(c,d),e))",
+			opts:     FormatOptions{Notation: NotationInfix},
+			expected: "(a & !b) | (!(c & d) ^ e)",
+		},
+		{
+			name:     "infix keeps call syntax for mux and dmux",
+			input:    "and(dmux(or(a,b),mux(a,b,c)))",
+			opts:     FormatOptions{Notation: NotationInfix},
+			expected: "and(dmux(a | b, mux(a, b, c)))",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			expr, _, err := ParseExpression(tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			verifyEquality(t, FormatWith(expr, tc.opts), tc.expected)
+		})
+	}
+}
+
+func TestParseNotation(t *testing.T) {
+	for _, n := range []Notation{NotationPrefix, NotationInfix, NotationSExpression} {
+		parsed, err := ParseNotation(n.String())
+		if err != nil || parsed != n {
+			t.Errorf("ParseNotation(%q) = %v, %v", n.String(), parsed, err)
+		}
+	}
+	if _, err := ParseNotation("postfix"); err == nil || !strings.Contains(err.Error(), "postfix") {
+		t.Errorf("expected error for unknown notation, got %v", err)
+	}
+}
+
+func TestFormatWithWires(t *testing.T) {
+	tests := []struct {
+		input    string
+		opts     FormatOptions
+		expected string
+	}{
+		{"let  s=xor(a,b) in and(s,or(s,c))", FormatOptions{}, "let s = xor(a, b) in and(s, or(s, c))"},
+		{"and(let s = not(a) in s, let t = and(s2, b) in t)", FormatOptions{}, "let s = not(a), t = and(s2, b) in and(s, t)"},
+		{"let s = xor(a,b), t = s in and(s, t)", FormatOptions{}, "let s = xor(a, b) in and(s, s)"},
+		{"let s = xor(a,b) in and(s,c)", FormatOptions{Notation: NotationInfix}, "let s = a ^ b in s & c"},
+		{
+			"let carry = and(alpha, beta), total = xor(alpha, beta) in mux(carry, total, select)",
+			FormatOptions{Width: 30},
+			"let\n  carry = and(alpha, beta),\n  total = xor(alpha, beta)\nin mux(carry, total, select)",
+		},
+		{"and(a,b)", FormatOptions{}, "and(a, b)"},
+	}
+	for _, tc := range tests {
+		expr, _, wires, err := ParseExpressionWithWires(tc.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		formatted := FormatWithWires(expr, wires, tc.opts)
+		verifyEquality(t, formatted, tc.expected)
+		if tc.opts.Notation != NotationPrefix {
+			continue
+		}
+		reparsed, _, err := ParseExpression(formatted)
+		if err != nil {
+			t.Errorf("failed to parse formatted expression %q: %v", formatted, err)
+		} else if Format(reparsed) != Format(expr) {
+			t.Errorf("formatted expression %q doesn't round trip to %v", formatted, tc.input)
+		}
+	}
+}
@@ -0,0 +1,610 @@
+package evaluation
+
+import (
+	"bufio"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MachineKind selects whether the outputs of a state machine depend only on the current state (Moore)
+// or also on the current inputs (Mealy)
+type MachineKind int
+
+const (
+	Moore MachineKind = iota
+	Mealy
+)
+
+func (k MachineKind) String() string {
+	if k == Mealy {
+		return "mealy"
+	}
+	return "moore"
+}
+
+// Transition moves the machine from one state to another when Condition, an expression over the
+// inputs, evaluates to 1
+type Transition struct {
+	From      string
+	To        string
+	Condition string
+}
+
+// FSM describes a finite state machine over single bit inputs and outputs. When no transition of
+// the current state matches the inputs, the machine stays in its current state.
+type FSM struct {
+	Kind    MachineKind
+	Inputs  []string
+	Outputs []string
+	// States lists all states, the first one is the initial state
+	States      []string
+	Transitions []Transition
+	// OutputLogic maps a state and an output name to the expression for that output. Moore outputs
+	// can't use the inputs. Outputs that aren't listed are 0.
+	OutputLogic map[string]map[string]string
+}
+
+// ParseFSM reads a state machine description with one declaration per line:
+//
+//	mealy                     (or moore, the default)
+//	inputs x y
+//	outputs z
+//	state A B                 (the first state is the initial state)
+//	A -> B when and(x, y)     (without when, the transition is always taken)
+//	output B z = x
+//
+// Lines starting with # are comments.
+func ParseFSM(text string) (*FSM, error) {
+	fsm := &FSM{OutputLogic: map[string]map[string]string{}}
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for line := 1; scanner.Scan(); line++ {
+		if err := fsm.parseLine(strings.TrimSpace(scanner.Text())); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(fsm.States) == 0 {
+		return nil, fmt.Errorf("state machine has no states")
+	}
+	return fsm, nil
+}
+
+func (fsm *FSM) parseLine(line string) error {
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+	fields := strings.Fields(line)
+	switch fields[0] {
+	case "moore", "mealy":
+		if len(fields) != 1 {
+			return fmt.Errorf("unexpected %q after %v", strings.Join(fields[1:], " "), fields[0])
+		}
+		fsm.Kind = Moore
+		if fields[0] == "mealy" {
+			fsm.Kind = Mealy
+		}
+		return nil
+	case "inputs":
+		return appendNames(&fsm.Inputs, fields[1:])
+	case "outputs":
+		return appendNames(&fsm.Outputs, fields[1:])
+	case "state":
+		return appendNames(&fsm.States, fields[1:])
+	case "output":
+		lhs, expression, found := strings.Cut(strings.TrimPrefix(line, "output"), "=")
+		names := strings.Fields(lhs)
+		if !found || len(names) != 2 || strings.TrimSpace(expression) == "" {
+			return fmt.Errorf("expected output <state> <name> = <expression>")
+		}
+		if fsm.OutputLogic[names[0]] == nil {
+			fsm.OutputLogic[names[0]] = map[string]string{}
+		}
+		fsm.OutputLogic[names[0]][names[1]] = strings.TrimSpace(expression)
+		return nil
+	}
+
+	from, rest, found := strings.Cut(line, "->")
+	if !found {
+		return fmt.Errorf("unknown declaration %q", fields[0])
+	}
+	to, condition, hasCondition := strings.Cut(strings.TrimSpace(rest), " when ")
+	if !hasCondition {
+		condition = "1"
+	}
+	transition := Transition{From: strings.TrimSpace(from), To: strings.TrimSpace(to), Condition: strings.TrimSpace(condition)}
+	if transition.From == "" || transition.To == "" || transition.Condition == "" {
+		return fmt.Errorf("expected <state> -> <state> [when <expression>]")
+	}
+	fsm.Transitions = append(fsm.Transitions, transition)
+	return nil
+}
+
+func appendNames(names *[]string, fields []string) error {
+	for _, name := range fields {
+		if !isPlainVariable(name) {
+			return fmt.Errorf("invalid name %q", name)
+		}
+		*names = append(*names, name)
+	}
+	return nil
+}
+
+// isPlainVariable reports whether name is a variable name without a bus index
+func isPlainVariable(name string) bool {
+	tokens, err := ParseTokens(name)
+	return err == nil && len(tokens) == 1 && tokens[0].tokenType == TokenVariable && tokens[0].literal == name &&
+		!strings.Contains(name, "[")
+}
+
+// StateTable is the transition table of a state machine. Input assignments are numbered like the rows
+// of a truth table, with the first input as the most significant bit.
+type StateTable struct {
+	Kind    MachineKind
+	States  []string
+	Inputs  []string
+	Outputs []string
+	// Next[s][row] is the index of the state following state s for the input assignment row
+	Next [][]int
+	// Output[s][row] are the output values in state s for the input assignment row
+	Output [][][]bool
+}
+
+// StateTable checks the machine description and tabulates its transitions and outputs
+func (fsm *FSM) StateTable() (*StateTable, error) {
+	if len(fsm.Inputs) > MaxTruthTableVariables {
+		return nil, fmt.Errorf("state machine has %d inputs, but state tables are limited to %d", len(fsm.Inputs), MaxTruthTableVariables)
+	}
+	if err := checkUnique(fsm.States, fsm.Inputs, fsm.Outputs); err != nil {
+		return nil, err
+	}
+	states := indexOf(fsm.States)
+	outputs := indexOf(fsm.Outputs)
+	rows := 1 << len(fsm.Inputs)
+
+	table := &StateTable{Kind: fsm.Kind, States: fsm.States, Inputs: fsm.Inputs, Outputs: fsm.Outputs}
+	fired := make([][]int, len(fsm.States)) // index of the transition taken for each state and row, or -1
+	for s := range fsm.States {
+		fired[s] = make([]int, rows)
+		for row := range fired[s] {
+			fired[s][row] = -1
+		}
+		table.Output = append(table.Output, make([][]bool, rows))
+		for row := range table.Output[s] {
+			table.Output[s][row] = make([]bool, len(fsm.Outputs))
+		}
+	}
+
+	for i, t := range fsm.Transitions {
+		from, ok := states[t.From]
+		if !ok {
+			return nil, fmt.Errorf("transition %v -> %v: unknown state %v", t.From, t.To, t.From)
+		}
+		if _, ok := states[t.To]; !ok {
+			return nil, fmt.Errorf("transition %v -> %v: unknown state %v", t.From, t.To, t.To)
+		}
+		values, err := evaluateOverInputs(t.Condition, fsm.Inputs)
+		if err != nil {
+			return nil, fmt.Errorf("transition %v -> %v: %w", t.From, t.To, err)
+		}
+		for row, taken := range values {
+			if !taken {
+				continue
+			}
+			if previous := fired[from][row]; previous >= 0 && fsm.Transitions[previous].To != t.To {
+				return nil, fmt.Errorf("state %v has transitions to both %v and %v for inputs %v",
+					t.From, fsm.Transitions[previous].To, t.To, describeRow(fsm.Inputs, row))
+			}
+			fired[from][row] = i
+		}
+	}
+
+	for state, logic := range fsm.OutputLogic {
+		s, ok := states[state]
+		if !ok {
+			return nil, fmt.Errorf("output for unknown state %v", state)
+		}
+		for name, expression := range logic {
+			o, ok := outputs[name]
+			if !ok {
+				return nil, fmt.Errorf("state %v: unknown output %v", state, name)
+			}
+			inputs := fsm.Inputs
+			if fsm.Kind == Moore {
+				inputs = nil
+			}
+			values, err := evaluateOverInputs(expression, inputs)
+			if err != nil {
+				return nil, fmt.Errorf("output %v of state %v: %w", name, state, err)
+			}
+			for row := range table.Output[s] {
+				table.Output[s][row][o] = values[row%len(values)]
+			}
+		}
+	}
+
+	table.Next = make([][]int, len(fsm.States))
+	for s := range fsm.States {
+		table.Next[s] = make([]int, rows)
+		for row, t := range fired[s] {
+			table.Next[s][row] = s
+			if t >= 0 {
+				table.Next[s][row] = states[fsm.Transitions[t].To]
+			}
+		}
+	}
+	return table, nil
+}
+
+// evaluateOverInputs computes the truth table of a single output expression and returns its value
+// for every assignment of the inputs
+func evaluateOverInputs(expression string, inputs []string) ([]bool, error) {
+	result, err := Compute(expression)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Outputs[0]) != 1 {
+		return nil, fmt.Errorf("expression %v has %d outputs instead of 1", expression, len(result.Outputs[0]))
+	}
+	positions := indexOf(inputs)
+	for _, v := range result.Variables {
+		if _, ok := positions[v]; !ok {
+			return nil, fmt.Errorf("expression %v uses %v, which is not an input", expression, v)
+		}
+	}
+
+	n := len(inputs)
+	values := make([]bool, 1<<n)
+	for row := range values {
+		resultRow := 0
+		for _, v := range result.Variables {
+			resultRow = resultRow<<1 | (row>>(n-1-positions[v]))&1
+		}
+		values[row] = result.Outputs[resultRow][0]
+	}
+	return values, nil
+}
+
+func checkUnique(groups ...[]string) error {
+	seen := map[string]struct{}{}
+	for _, names := range groups {
+		for _, name := range names {
+			if _, ok := seen[name]; ok {
+				return fmt.Errorf("%v is declared more than once", name)
+			}
+			seen[name] = struct{}{}
+		}
+	}
+	return nil
+}
+
+func indexOf(names []string) map[string]int {
+	result := map[string]int{}
+	for i, name := range names {
+		result[name] = i
+	}
+	return result
+}
+
+func describeRow(inputs []string, row int) string {
+	parts := []string{}
+	for i, name := range inputs {
+		parts = append(parts, fmt.Sprintf("%v=%d", name, (row>>(len(inputs)-1-i))&1))
+	}
+	return strings.Join(parts, " ")
+}
+
+func (t *StateTable) String() string {
+	var sb strings.Builder
+	sb.WriteString("State\t")
+	for _, name := range t.Inputs {
+		sb.WriteString(name + "\t")
+	}
+	sb.WriteString("Next")
+	for _, name := range t.Outputs {
+		sb.WriteString("\t" + name)
+	}
+	sb.WriteString("\n")
+
+	for s, state := range t.States {
+		for row, next := range t.Next[s] {
+			sb.WriteString(state + "\t")
+			for i := range t.Inputs {
+				sb.WriteString(boolToString((row>>(len(t.Inputs)-1-i))&1 == 1) + "\t")
+			}
+			sb.WriteString(t.States[next])
+			for _, val := range t.Output[s][row] {
+				sb.WriteString("\t" + boolToString(val))
+			}
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+// Minimize removes unreachable states and merges equivalent states using Hopcroft's partition
+// refinement. Merged states are named after their first state in the original order.
+func (t *StateTable) Minimize() *StateTable {
+	reachable := t.reachable()
+
+	// start from the states grouped by their outputs
+	blocks := [][]int{}
+	signatures := map[string]int{}
+	for _, s := range reachable {
+		signature := fmt.Sprint(t.Output[s])
+		b, ok := signatures[signature]
+		if !ok {
+			b = len(blocks)
+			signatures[signature] = b
+			blocks = append(blocks, nil)
+		}
+		blocks[b] = append(blocks[b], s)
+	}
+
+	waiting := []int{}
+	inWaiting := map[int]bool{}
+	for b := range blocks {
+		waiting = append(waiting, b)
+		inWaiting[b] = true
+	}
+	rows := 1 << len(t.Inputs)
+	for len(waiting) > 0 {
+		splitter := append([]int{}, blocks[waiting[0]]...)
+		inWaiting[waiting[0]] = false
+		waiting = waiting[1:]
+		inSplitter := map[int]bool{}
+		for _, s := range splitter {
+			inSplitter[s] = true
+		}
+
+		for row := 0; row < rows; row++ {
+			for b := 0; b < len(blocks); b++ {
+				var leading, rest []int
+				for _, s := range blocks[b] {
+					if inSplitter[t.Next[s][row]] {
+						leading = append(leading, s)
+					} else {
+						rest = append(rest, s)
+					}
+				}
+				if len(leading) == 0 || len(rest) == 0 {
+					continue
+				}
+				blocks[b] = leading
+				blocks = append(blocks, rest)
+				added := len(blocks) - 1
+				switch {
+				case inWaiting[b]:
+					waiting = append(waiting, added)
+					inWaiting[added] = true
+				case len(leading) <= len(rest):
+					waiting = append(waiting, b)
+					inWaiting[b] = true
+				default:
+					waiting = append(waiting, added)
+					inWaiting[added] = true
+				}
+			}
+		}
+	}
+
+	// number the merged states in the order of their first original state
+	for _, members := range blocks {
+		sort.Ints(members)
+	}
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i][0] < blocks[j][0] })
+	blockOf := make([]int, len(t.States))
+	for b, members := range blocks {
+		for _, s := range members {
+			blockOf[s] = b
+		}
+	}
+
+	result := &StateTable{Kind: t.Kind, Inputs: t.Inputs, Outputs: t.Outputs}
+	for _, members := range blocks {
+		representative := members[0]
+		next := make([]int, rows)
+		for row, s := range t.Next[representative] {
+			next[row] = blockOf[s]
+		}
+		result.States = append(result.States, t.States[representative])
+		result.Next = append(result.Next, next)
+		result.Output = append(result.Output, t.Output[representative])
+	}
+	return result
+}
+
+// reachable lists the states reachable from the initial state, in their original order
+func (t *StateTable) reachable() []int {
+	seen := make([]bool, len(t.States))
+	seen[0] = true
+	queue := []int{0}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		for _, next := range t.Next[s] {
+			if !seen[next] {
+				seen[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	result := []int{}
+	for s, ok := range seen {
+		if ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// Encoding selects how states are assigned to state bits
+type Encoding int
+
+const (
+	EncodingBinary Encoding = iota
+	EncodingOneHot
+	EncodingGray
+)
+
+func (e Encoding) String() string {
+	switch e {
+	case EncodingOneHot:
+		return "onehot"
+	case EncodingGray:
+		return "gray"
+	}
+	return "binary"
+}
+
+func ParseEncoding(name string) (Encoding, error) {
+	for _, e := range []Encoding{EncodingBinary, EncodingOneHot, EncodingGray} {
+		if e.String() == name {
+			return e, nil
+		}
+	}
+	return EncodingBinary, fmt.Errorf("unknown state encoding %q, expected binary, onehot or gray", name)
+}
+
+// encodedStatePrefix names the variables holding the encoded state
+const encodedStatePrefix = "st"
+
+// Synthesis is the logic implementing a state machine with an encoded state. All expressions are
+// over the state variables and the inputs.
+type Synthesis struct {
+	Encoding Encoding
+	// StateVariables are the state bits, st0 being the least significant one
+	StateVariables []string
+	// Codes are the values of the state variables for each state
+	Codes map[string][]bool
+	// NextState has the expression computing each state variable for the next clock cycle
+	NextState []string
+	// Outputs has the expression for each output of the machine
+	Outputs []string
+}
+
+// Encode assigns codes to states. Every encoding gives the initial state the code 0, which is also
+// the state sequential gates reset to. One-hot codes have a bit per state, set in that state, except
+// for the bit of the initial state, which is inverted: st0 is 1 in every other state.
+func (t *StateTable) Encode(encoding Encoding) [][]bool {
+	n := len(t.States)
+	width := n
+	if encoding != EncodingOneHot {
+		width = 1
+		for 1<<width < n {
+			width++
+		}
+	}
+	codes := make([][]bool, n)
+	for s := range codes {
+		codes[s] = make([]bool, width)
+		value := s
+		switch encoding {
+		case EncodingOneHot:
+			value = 1<<s ^ 1
+		case EncodingGray:
+			value = s ^ (s >> 1)
+		}
+		for bit := range codes[s] {
+			codes[s][bit] = value&(1<<bit) != 0
+		}
+	}
+	return codes
+}
+
+// Synthesize derives sum of products expressions for the next state and the outputs of the machine.
+// Unused codes are not treated as don't cares: with binary and Gray encodings the terms only match the codes of the
+// states, so the expressions are 0 for the others. One-hot terms only test the bit of their state.
+func (t *StateTable) Synthesize(encoding Encoding) (*Synthesis, error) {
+	codes := t.Encode(encoding)
+	result := &Synthesis{Encoding: encoding, Codes: map[string][]bool{}}
+	for bit := range codes[0] {
+		result.StateVariables = append(result.StateVariables, fmt.Sprintf("%s%d", encodedStatePrefix, bit))
+	}
+	if err := checkUnique(result.StateVariables, t.Inputs); err != nil {
+		return nil, fmt.Errorf("state variables clash with the inputs: %w", err)
+	}
+	for s, state := range t.States {
+		result.Codes[state] = codes[s]
+	}
+
+	stateTerm := func(s int) []string {
+		if encoding == EncodingOneHot && s == 0 {
+			return []string{fmt.Sprintf("not(%s)", result.StateVariables[0])}
+		}
+		if encoding == EncodingOneHot {
+			return []string{result.StateVariables[s]}
+		}
+		return literals(result.StateVariables, codes[s])
+	}
+	rows := 1 << len(t.Inputs)
+	sumOfProducts := func(value func(s, row int) bool) (string, error) {
+		terms := []string{}
+		for s := range t.States {
+			matching := []int{}
+			for row := 0; row < rows; row++ {
+				if value(s, row) {
+					matching = append(matching, row)
+				}
+			}
+			if len(matching) == rows {
+				terms = append(terms, joinGates("and", stateTerm(s), "1"))
+				continue
+			}
+			for _, row := range matching {
+				assignment := make([]bool, len(t.Inputs))
+				for i := range assignment {
+					assignment[i] = (row>>(len(t.Inputs)-1-i))&1 == 1
+				}
+				terms = append(terms, joinGates("and", append(stateTerm(s), literals(t.Inputs, assignment)...), "1"))
+			}
+		}
+		expr, _, err := ParseExpression(joinGates("or", terms, "0"))
+		if err != nil {
+			return "", err
+		}
+		return Format(expr), nil
+	}
+
+	for bit := range result.StateVariables {
+		expression, err := sumOfProducts(func(s, row int) bool { return codes[t.Next[s][row]][bit] })
+		if err != nil {
+			return nil, err
+		}
+		result.NextState = append(result.NextState, expression)
+	}
+	for o := range t.Outputs {
+		expression, err := sumOfProducts(func(s, row int) bool { return t.Output[s][row][o] })
+		if err != nil {
+			return nil, err
+		}
+		result.Outputs = append(result.Outputs, expression)
+	}
+	return result, nil
+}
+
+func literals(names []string, values []bool) []string {
+	result := []string{}
+	for i, name := range names {
+		if values[i] {
+			result = append(result, name)
+		} else {
+			result = append(result, fmt.Sprintf("not(%s)", name))
+		}
+	}
+	return result
+}
+
+// joinGates combines the operands with a balanced tree of a binary gate
+func joinGates(gate string, operands []string, empty string) string {
+	switch len(operands) {
+	case 0:
+		return empty
+	case 1:
+		return operands[0]
+	}
+	half := len(operands) / 2
+	return fmt.Sprintf("%s(%s,%s)", gate, joinGates(gate, operands[:half], empty), joinGates(gate, operands[half:], empty))
+}
@@ -0,0 +1,197 @@
+package evaluation
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// detects two consecutive 1s on x
+const sequenceDetector = `
+# Mealy machine, z is set on the second 1
+mealy
+inputs x
+outputs z
+state idle seen
+idle -> seen when x
+seen -> idle when not(x)
+output seen z = x
+`
+
+// counts 0, 1, 2, 3 while en is set. two and copy behave the same, and unused can't be reached.
+const counter = `
+inputs en
+outputs hi lo
+state zero one two three copy unused
+zero -> one when en
+one -> two when en
+two -> three when en
+three -> zero when en
+three -> copy when not(en)
+copy -> zero when en
+unused -> zero
+output one lo = 1
+output two hi = 1
+output three hi = 1
+output three lo = 1
+output copy hi = 1
+output copy lo = 1
+`
+
+func TestStateTable(t *testing.T) {
+	fsm, err := ParseFSM(sequenceDetector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifyEquality(t, fsm.Kind, Mealy)
+	table, err := fsm.StateTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "State\tx\tNext\tz\n" +
+		"idle\t0\tidle\t0\n" +
+		"idle\t1\tseen\t0\n" +
+		"seen\t0\tidle\t0\n" +
+		"seen\t1\tseen\t1\n"
+	verifyEquality(t, table.String(), expected)
+}
+
+func TestMinimize(t *testing.T) {
+	fsm, err := ParseFSM(counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table, err := fsm.StateTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	minimal := table.Minimize()
+	if !reflect.DeepEqual(minimal.States, []string{"zero", "one", "two", "three"}) {
+		t.Fatalf("unexpected states after minimization %v", minimal.States)
+	}
+	if !reflect.DeepEqual(minimal.Next, [][]int{{0, 1}, {1, 2}, {2, 3}, {3, 0}}) {
+		t.Errorf("unexpected transitions after minimization %v", minimal.Next)
+	}
+
+	// already minimal machines stay the same
+	fsm, _ = ParseFSM(sequenceDetector)
+	table, _ = fsm.StateTable()
+	if !reflect.DeepEqual(table.Minimize(), table) {
+		t.Errorf("minimizing a minimal machine changed it to %v", table.Minimize())
+	}
+}
+
+func TestSynthesize(t *testing.T) {
+	for _, description := range []string{sequenceDetector, counter} {
+		fsm, err := ParseFSM(description)
+		if err != nil {
+			t.Fatal(err)
+		}
+		table, err := fsm.StateTable()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, encoding := range []Encoding{EncodingBinary, EncodingOneHot, EncodingGray} {
+			synthesis, err := table.Synthesize(encoding)
+			if err != nil {
+				t.Fatal(err)
+			}
+			verifySynthesis(t, table, synthesis)
+		}
+	}
+}
+
+func verifySynthesis(t *testing.T, table *StateTable, synthesis *Synthesis) {
+	t.Helper()
+	parse := func(expressions []string) []Expression {
+		result := []Expression{}
+		for _, e := range expressions {
+			expr, _, err := ParseExpression(e)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result = append(result, expr)
+		}
+		return result
+	}
+	evaluate := func(expressions []Expression, args map[string]bool) []bool {
+		result := []bool{}
+		for _, expr := range expressions {
+			value, err := expr.Evaluate(args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result = append(result, value[0])
+		}
+		return result
+	}
+	next, outputs := parse(synthesis.NextState), parse(synthesis.Outputs)
+
+	for s, state := range table.States {
+		for row := range table.Next[s] {
+			args := map[string]bool{}
+			for i, name := range synthesis.StateVariables {
+				args[name] = synthesis.Codes[state][i]
+			}
+			for i, name := range table.Inputs {
+				args[name] = (row>>(len(table.Inputs)-1-i))&1 == 1
+			}
+			expected := synthesis.Codes[table.States[table.Next[s][row]]]
+			if got := evaluate(next, args); !reflect.DeepEqual(got, expected) {
+				t.Errorf("%v encoding: next state of %v for %v is %v instead of %v", synthesis.Encoding, state, args, got, expected)
+			}
+			if got := evaluate(outputs, args); !reflect.DeepEqual(got, table.Output[s][row]) {
+				t.Errorf("%v encoding: outputs of %v for %v are %v instead of %v", synthesis.Encoding, state, args, got, table.Output[s][row])
+			}
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	table := &StateTable{States: []string{"a", "b", "c", "d"}}
+	verifyEquality(t, table.Encode(EncodingGray)[3][0], false)
+	verifyEquality(t, table.Encode(EncodingGray)[3][1], true)
+	verifyEquality(t, len(table.Encode(EncodingBinary)[0]), 2)
+	verifyEquality(t, len(table.Encode(EncodingOneHot)[0]), 4)
+	// the initial state is all zero, like sequential gates after a reset
+	for _, e := range []Encoding{EncodingBinary, EncodingOneHot, EncodingGray} {
+		verifyEquality(t, slices.Contains(table.Encode(e)[0], true), false)
+	}
+	if code := table.Encode(EncodingOneHot)[2]; !reflect.DeepEqual(code, []bool{true, false, true, false}) {
+		t.Errorf("one-hot code of the third state is %v", code)
+	}
+	for _, e := range []Encoding{EncodingBinary, EncodingOneHot, EncodingGray} {
+		parsed, err := ParseEncoding(e.String())
+		if err != nil || parsed != e {
+			t.Errorf("encoding %v doesn't round trip: %v", e, err)
+		}
+	}
+}
+
+func TestFSMErrors(t *testing.T) {
+	testCases := []string{
+		"inputs x\nstate a b\na -> b when x\na -> a when 1",       // two transitions for x=1
+		"inputs x\noutputs z\nstate a\noutput a z = x",            // Moore output depending on inputs
+		"inputs x\nstate a\na -> b",                               // unknown state
+		"inputs x\nstate a b\na -> b when y",                      // condition uses an unknown input
+		"inputs x\nstate a b\na -> b when dmux(x,1)",              // condition with two outputs
+		"inputs x\nstate x",                                       // state named like an input
+		"inputs x[0]\nstate a",                                    // bus bits aren't supported
+		"state a\nfoo",                                            // unknown declaration
+		"inputs x",                                                // no states
+		"mealy\ninputs st0\noutputs z\nstate a\noutput a z = st0", // clashes with the state variables
+	}
+	for _, tc := range testCases {
+		fsm, err := ParseFSM(tc)
+		if err == nil {
+			var table *StateTable
+			table, err = fsm.StateTable()
+			if err == nil {
+				_, err = table.Synthesize(EncodingBinary)
+			}
+		}
+		if err == nil {
+			t.Errorf("expected error for state machine %q", tc)
+		}
+	}
+}
@@ -33,3 +33,92 @@ func Dmux(a, sel bool) (bool, bool) {
 	}
 	return a, false
 }
+
+func Not16(a [16]bool) [16]bool {
+	var out [16]bool
+	for i := range out {
+		out[i] = Not(a[i])
+	}
+	return out
+}
+
+func And16(a, b [16]bool) [16]bool {
+	var out [16]bool
+	for i := range out {
+		out[i] = And(a[i], b[i])
+	}
+	return out
+}
+
+func Or16(a, b [16]bool) [16]bool {
+	var out [16]bool
+	for i := range out {
+		out[i] = Or(a[i], b[i])
+	}
+	return out
+}
+
+func Xor16(a, b [16]bool) [16]bool {
+	var out [16]bool
+	for i := range out {
+		out[i] = Xor(a[i], b[i])
+	}
+	return out
+}
+
+func Nand16(a, b [16]bool) [16]bool {
+	var out [16]bool
+	for i := range out {
+		out[i] = Nand(a[i], b[i])
+	}
+	return out
+}
+
+func Mux16(a, b [16]bool, sel bool) [16]bool {
+	var out [16]bool
+	for i := range out {
+		out[i] = Mux(a[i], b[i], sel)
+	}
+	return out
+}
+
+func Dmux16(a [16]bool, sel bool) ([16]bool, [16]bool) {
+	var out1, out2 [16]bool
+	for i := range a {
+		out1[i], out2[i] = Dmux(a[i], sel)
+	}
+	return out1, out2
+}
+
+func Or8Way(a [8]bool) bool {
+	result := false
+	for _, bit := range a {
+		result = Or(result, bit)
+	}
+	return result
+}
+
+// Mux4Way16 selects a for sel = 00, b for 01, c for 10 and d for 11 (sel[1] is the high bit)
+func Mux4Way16(a, b, c, d [16]bool, sel [2]bool) [16]bool {
+	return Mux16(Mux16(d, b, sel[1]), Mux16(c, a, sel[1]), sel[0])
+}
+
+func Mux8Way16(a, b, c, d, e, f, g, h [16]bool, sel [3]bool) [16]bool {
+	low := [2]bool{sel[0], sel[1]}
+	return Mux16(Mux4Way16(e, f, g, h, low), Mux4Way16(a, b, c, d, low), sel[2])
+}
+
+// Dmux4Way routes a to output sel, where sel[1] is the high bit
+func Dmux4Way(a bool, sel [2]bool) [4]bool {
+	high0, high1 := Dmux(a, sel[1])
+	out0, out1 := Dmux(high0, sel[0])
+	out2, out3 := Dmux(high1, sel[0])
+	return [4]bool{out0, out1, out2, out3}
+}
+
+func Dmux8Way(a bool, sel [3]bool) [8]bool {
+	low, high := Dmux(a, sel[2])
+	lowOuts := Dmux4Way(low, [2]bool{sel[0], sel[1]})
+	highOuts := Dmux4Way(high, [2]bool{sel[0], sel[1]})
+	return [8]bool{lowOuts[0], lowOuts[1], lowOuts[2], lowOuts[3], highOuts[0], highOuts[1], highOuts[2], highOuts[3]}
+}
@@ -95,6 +95,81 @@ func TestDmux(t *testing.T) {
 	}
 }
 
+func TestBitwise16(t *testing.T) {
+	a := busFromUint(0b1100_1010_0101_0011)
+	b := busFromUint(0b1010_0110_1001_0101)
+
+	verifyEquality(t, bus16ToUint(Not16(a)), 0b0011_0101_1010_1100)
+	verifyEquality(t, bus16ToUint(And16(a, b)), 0b1000_0010_0001_0001)
+	verifyEquality(t, bus16ToUint(Or16(a, b)), 0b1110_1110_1101_0111)
+	verifyEquality(t, bus16ToUint(Xor16(a, b)), 0b0110_1100_1100_0110)
+	verifyEquality(t, bus16ToUint(Nand16(a, b)), 0b0111_1101_1110_1110)
+	verifyEquality(t, bus16ToUint(Mux16(a, b, true)), bus16ToUint(a))
+	verifyEquality(t, bus16ToUint(Mux16(a, b, false)), bus16ToUint(b))
+
+	out1, out2 := Dmux16(a, false)
+	verifyEquality(t, bus16ToUint(out1), bus16ToUint(a))
+	verifyEquality(t, bus16ToUint(out2), 0)
+}
+
+func TestOr8Way(t *testing.T) {
+	verifyEquality(t, Or8Way([8]bool{}), false)
+	for i := 0; i < 8; i++ {
+		in := [8]bool{}
+		in[i] = true
+		verifyEquality(t, Or8Way(in), true)
+	}
+}
+
+func TestMultiWay(t *testing.T) {
+	buses := [8][16]bool{}
+	for i := range buses {
+		buses[i] = busFromUint(uint64(1000 + i))
+	}
+	for sel := 0; sel < 8; sel++ {
+		sel3 := [3]bool{sel&1 == 1, sel&2 == 2, sel&4 == 4}
+		sel2 := [2]bool{sel3[0], sel3[1]}
+
+		if sel < 4 {
+			out := Mux4Way16(buses[0], buses[1], buses[2], buses[3], sel2)
+			verifyEquality(t, busToUint(out[:]), uint64(1000+sel))
+
+			dmux := Dmux4Way(true, sel2)
+			verifyEquality(t, busToUint(dmux[:]), 1<<sel)
+		}
+
+		out := Mux8Way16(buses[0], buses[1], buses[2], buses[3], buses[4], buses[5], buses[6], buses[7], sel3)
+		verifyEquality(t, busToUint(out[:]), uint64(1000+sel))
+
+		dmux := Dmux8Way(true, sel3)
+		verifyEquality(t, busToUint(dmux[:]), 1<<sel)
+		dmux = Dmux8Way(false, sel3)
+		verifyEquality(t, busToUint(dmux[:]), 0)
+	}
+}
+
+func busFromUint(value uint64) [16]bool {
+	var bus [16]bool
+	for i := range bus {
+		bus[i] = value&(1<<i) != 0
+	}
+	return bus
+}
+
+func bus16ToUint(bus [16]bool) uint64 {
+	return busToUint(bus[:])
+}
+
+func busToUint(bits []bool) uint64 {
+	var value uint64
+	for i, bit := range bits {
+		if bit {
+			value |= 1 << i
+		}
+	}
+	return value
+}
+
 func verifyEquality[T comparable](t *testing.T, actual, expected T) {
 	if actual != expected {
 		t.Errorf("got %+v, wanted %+v", actual, expected)
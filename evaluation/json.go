@@ -0,0 +1,223 @@
+package evaluation
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ASTVersion is the version of the JSON encoding produced by MarshalExpression. Decoding rejects other versions.
+const ASTVersion = 1
+
+// ASTSchema is the JSON Schema describing the encoding produced by MarshalExpression.
+//
+//go:embed ast.schema.json
+var ASTSchema string
+
+type jsonAST struct {
+	Version int       `json:"version"`
+	Root    *jsonNode `json:"root"`
+}
+
+type jsonNode struct {
+	Type  string     `json:"type"`
+	Value *bool      `json:"value,omitempty"`
+	Name  string     `json:"name,omitempty"`
+	Low   *int       `json:"low,omitempty"`
+	High  *int       `json:"high,omitempty"`
+	Op    string     `json:"op,omitempty"`
+	Args  []jsonNode `json:"args,omitempty"`
+}
+
+const (
+	jsonLiteral  = "literal"
+	jsonVariable = "variable"
+	jsonBus      = "bus"
+	jsonGate     = "gate"
+)
+
+func MarshalExpression(expr Expression) ([]byte, error) {
+	root, err := toJSONNode(expr)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonAST{Version: ASTVersion, Root: &root})
+}
+
+func toJSONNode(expr Expression) (jsonNode, error) {
+	switch e := expr.(type) {
+	case *LiteralExpression:
+		value := e.value
+		return jsonNode{Type: jsonLiteral, Value: &value}, nil
+	case *VariableExpression:
+		return jsonNode{Type: jsonVariable, Name: e.variableName}, nil
+	case *BusVariableExpression:
+		low, high := e.low, e.high
+		return jsonNode{Type: jsonBus, Name: e.name, Low: &low, High: &high}, nil
+	}
+
+	name, args := formatParts(expr)
+	if args == nil {
+		return jsonNode{}, fmt.Errorf("cannot encode expression of type %T", expr)
+	}
+	node := jsonNode{Type: jsonGate, Op: name}
+	if e, ok := expr.(*StateExpression); ok {
+		node.Op, node.Name = gateName(e.op), e.label
+	}
+	for _, arg := range args {
+		child, err := toJSONNode(arg)
+		if err != nil {
+			return jsonNode{}, err
+		}
+		node.Args = append(node.Args, child)
+	}
+	return node, nil
+}
+
+type jsonTable struct {
+	Variables []string  `json:"variables"`
+	Wires     []string  `json:"wires,omitempty"`
+	Rows      []jsonRow `json:"rows"`
+}
+
+type jsonRow struct {
+	Inputs  []bool   `json:"inputs"`
+	Wires   [][]bool `json:"wires,omitempty"`
+	Outputs []bool   `json:"outputs"`
+}
+
+// MarshalResult encodes a truth table as JSON: the names of the inputs and wires, and for every row the values
+// of the inputs, wires and outputs in the same order
+func MarshalResult(r *Result) ([]byte, error) {
+	table := jsonTable{Variables: r.Variables, Wires: r.Wires, Rows: []jsonRow{}}
+	if table.Variables == nil {
+		table.Variables = []string{}
+	}
+	for row, outputs := range r.Outputs {
+		jr := jsonRow{Inputs: []bool{}, Outputs: outputs}
+		if len(r.Variables) > 0 {
+			jr.Inputs = r.Assignments[row]
+		}
+		if r.WireValues != nil {
+			jr.Wires = r.WireValues[row]
+		}
+		table.Rows = append(table.Rows, jr)
+	}
+	return json.Marshal(table)
+}
+
+// UnmarshalExpression decodes an expression encoded by MarshalExpression, applying the same
+// validation as ParseExpression (known gates, number of inputs, variable names).
+func UnmarshalExpression(data []byte) (Expression, VariableSet, error) {
+	var ast jsonAST
+	if err := json.Unmarshal(data, &ast); err != nil {
+		return nil, nil, fmt.Errorf("invalid expression JSON: %w", err)
+	}
+	if ast.Version != ASTVersion {
+		return nil, nil, fmt.Errorf("unsupported expression JSON version %d, expected %d", ast.Version, ASTVersion)
+	}
+	if ast.Root == nil {
+		return nil, nil, errors.New("expression JSON has no root node")
+	}
+	d := jsonDecoder{variables: VariableSet{}, labels: map[string]struct{}{}}
+	expr, err := d.decode(*ast.Root)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkBusUsage(d.variables); err != nil {
+		return nil, nil, err
+	}
+	return expr, d.variables, nil
+}
+
+type jsonDecoder struct {
+	variables VariableSet
+	stateBits int // allocated the same way as by the parser, so decoding yields the parsed expression
+	labels    map[string]struct{}
+}
+
+func (d *jsonDecoder) decode(node jsonNode) (Expression, error) {
+	switch node.Type {
+	case jsonLiteral:
+		if node.Value == nil {
+			return nil, errors.New("literal node without a value")
+		}
+		return &LiteralExpression{value: *node.Value}, nil
+	case jsonVariable:
+		tokens, err := ParseTokens(node.Name)
+		if err != nil || len(tokens) != 1 || tokens[0].tokenType != TokenVariable {
+			return nil, fmt.Errorf("invalid variable name %q", node.Name)
+		}
+		d.variables[node.Name] = struct{}{}
+		return &VariableExpression{variableName: node.Name}, nil
+	case jsonBus:
+		if node.Low == nil || node.High == nil {
+			return nil, fmt.Errorf("bus node %q without low and high index", node.Name)
+		}
+		tokens, err := ParseTokens(sliceName(node.Name, *node.Low, *node.High))
+		if err != nil || len(tokens) != 1 || tokens[0].tokenType != TokenSlice {
+			return nil, fmt.Errorf("invalid bus %q with indices %d..%d", node.Name, *node.Low, *node.High)
+		}
+		for i := *node.Low; i <= *node.High; i++ {
+			d.variables[bitName(node.Name, i)] = struct{}{}
+		}
+		return &BusVariableExpression{name: node.Name, low: *node.Low, high: *node.High}, nil
+	case jsonGate:
+	default:
+		return nil, fmt.Errorf("unknown node type %q", node.Type)
+	}
+
+	args := []Expression{}
+	inputs := 0
+	for _, child := range node.Args {
+		arg, err := d.decode(child)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		inputs += arg.NumOutputs()
+	}
+
+	tokenType, ok := keywords[node.Op]
+	if !ok {
+		return nil, fmt.Errorf("unknown gate %q", node.Op)
+	}
+	expectedInputs := gateInputs[tokenType]
+	if inputs != expectedInputs {
+		return nil, fmt.Errorf("%s gate expects %d inputs, but got %d", node.Op, expectedInputs, inputs)
+	}
+
+	switch tokenType {
+	case TokenNot:
+		return &NotExpression{expression: args[0]}, nil
+	case TokenMux:
+		return &MuxExpression{args}, nil
+	case TokenDmux:
+		return &DmuxExpression{args}, nil
+	case TokenNand, TokenAnd, TokenOr, TokenXor:
+		return &BinaryExpression{tokenType, args}, nil
+	case TokenDff, TokenBit, TokenRegister:
+		if node.Name != "" {
+			tokens, err := ParseTokens(node.Name)
+			if err != nil || len(tokens) != 1 || tokens[0].tokenType != TokenVariable || strings.Contains(node.Name, "[") {
+				return nil, fmt.Errorf("invalid state name %q", node.Name)
+			}
+			if _, exists := d.labels[node.Name]; exists {
+				return nil, fmt.Errorf("state name %s is used by more than one gate", node.Name)
+			}
+			d.labels[node.Name] = struct{}{}
+		}
+		expr := newStateExpression(tokenType, args, node.Name, d.stateBits)
+		if node.Name == "" {
+			d.stateBits += len(expr.state)
+		}
+		for _, name := range expr.state {
+			d.variables[name] = struct{}{}
+		}
+		return expr, nil
+	default:
+		return &BusGateExpression{tokenType, args}, nil
+	}
+}
@@ -0,0 +1,123 @@
+package evaluation
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	inputs := []string{
+		"1",
+		"0",
+		"foo",
+		"not(X)",
+		"nand(a,b)",
+		"and(dmux(or(a,b),c))",
+		"mux(xor(a,b),nand(b,c),or(not(a),c))",
+	}
+
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			expr, vars, err := ParseExpression(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := MarshalExpression(expr)
+			if err != nil {
+				t.Fatalf("MarshalExpression() returned unexpected error: %v", err)
+			}
+			decoded, decodedVars, err := UnmarshalExpression(data)
+			if err != nil {
+				t.Fatalf("UnmarshalExpression() returned unexpected error for %s: %v", data, err)
+			}
+			if !reflect.DeepEqual(decoded, expr) {
+				t.Errorf("expression didn't round trip through %s", data)
+			}
+			if !reflect.DeepEqual(decodedVars, vars) {
+				t.Errorf("expected variables %v, got %v", vars, decodedVars)
+			}
+		})
+	}
+}
+
+func TestMarshalExpression(t *testing.T) {
+	expr, _, err := ParseExpression("and(not(a),1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := MarshalExpression(expr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"version":1,"root":{"type":"gate","op":"and","args":[{"type":"gate","op":"not","args":[{"type":"variable","name":"a"}]},{"type":"literal","value":true}]}}`
+	verifyEquality(t, string(data), expected)
+}
+
+func TestUnmarshalExpressionErrors(t *testing.T) {
+	testCases := []string{
+		`not json`,
+		`{"version":2,"root":{"type":"literal","value":true}}`,
+		`{"version":1}`,
+		`{"version":1,"root":{"type":"literal"}}`,
+		`{"version":1,"root":{"type":"constant","value":true}}`,
+		`{"version":1,"root":{"type":"variable","name":"not"}}`,
+		`{"version":1,"root":{"type":"variable","name":"a b"}}`,
+		`{"version":1,"root":{"type":"gate","op":"implies","args":[{"type":"literal","value":true},{"type":"literal","value":true}]}}`,
+		`{"version":1,"root":{"type":"gate","op":"and","args":[{"type":"literal","value":true}]}}`,
+		`{"version":1,"root":{"type":"gate","op":"not","args":[{"type":"gate","op":"dmux","args":[{"type":"variable","name":"a"},{"type":"variable","name":"b"}]}]}}`,
+	}
+	for _, tc := range testCases {
+		if expr, _, err := UnmarshalExpression([]byte(tc)); err == nil {
+			t.Errorf("expected error decoding %s, but got %v", tc, expr)
+		}
+	}
+}
+
+func TestASTSchema(t *testing.T) {
+	var schema map[string]any
+	if err := json.Unmarshal([]byte(ASTSchema), &schema); err != nil {
+		t.Fatalf("ASTSchema is not valid JSON: %v", err)
+	}
+	version := schema["properties"].(map[string]any)["version"].(map[string]any)["const"]
+	verifyEquality(t, version, any(float64(ASTVersion)))
+
+	ops := schema["$defs"].(map[string]any)["gate"].(map[string]any)["properties"].(map[string]any)["op"].(map[string]any)["enum"].([]any)
+	if len(ops) != len(keywords) {
+		t.Errorf("schema lists %d gates, but there are %d", len(ops), len(keywords))
+	}
+	for _, op := range ops {
+		if _, ok := keywords[op.(string)]; !ok {
+			t.Errorf("schema lists unknown gate %v", op)
+		}
+	}
+}
+
+func TestMarshalResult(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   string
+	}{
+		{"1", `{"variables":[],"rows":[{"inputs":[],"outputs":[true]}]}`},
+		{"not(a)", `{"variables":["a"],"rows":[{"inputs":[false],"outputs":[true]},{"inputs":[true],"outputs":[false]}]}`},
+		{
+			"let n = not(a) in dmux(n, a)",
+			`{"variables":["a"],"wires":["n"],"rows":[` +
+				`{"inputs":[false],"wires":[[true]],"outputs":[true,false]},` +
+				`{"inputs":[true],"wires":[[false]],"outputs":[false,false]}]}`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.expression, func(t *testing.T) {
+			result, err := Compute(tc.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := MarshalResult(result)
+			if err != nil {
+				t.Fatal(err)
+			}
+			verifyEquality(t, string(data), tc.expected)
+		})
+	}
+}
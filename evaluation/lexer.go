@@ -1,12 +1,19 @@
 package evaluation
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
 
 type TokenType int
 
 const (
 	TokenValue TokenType = iota
 	TokenVariable
+	TokenSlice // bus slice, e.g. a[0..7]. A single indexed bit like a[3] is a TokenVariable
 
 	// Gates
 	TokenNand
@@ -17,9 +24,32 @@ const (
 	TokenMux
 	TokenDmux
 
+	// Bus gates
+	TokenNot16
+	TokenAnd16
+	TokenOr16
+	TokenXor16
+	TokenNand16
+	TokenMux16
+	TokenDmux16
+	TokenOr8Way
+	TokenMux4Way16
+	TokenMux8Way16
+	TokenDmux4Way
+	TokenDmux8Way
+
+	// Sequential gates
+	TokenDff
+	TokenBit
+	TokenRegister
+
+	TokenLet // let s = xor(a, b) in and(s, c). "in" is a TokenVariable, since it's also a common input name
+
 	TokenLparan
 	TokenRparan
 	TokenComma
+	TokenColon
+	TokenEquals
 
 	tokenEOF // used only internally, won't be returned by our parser
 )
@@ -32,6 +62,12 @@ func (t TokenType) String() string {
 		return ")"
 	case TokenComma:
 		return ","
+	case TokenColon:
+		return ":"
+	case TokenEquals:
+		return "="
+	case TokenLet:
+		return "let"
 	default:
 		return "UNHANDLED"
 	}
@@ -45,6 +81,23 @@ var keywords = map[string]TokenType{
 	"xor":  TokenXor,
 	"mux":  TokenMux,
 	"dmux": TokenDmux,
+
+	"not16":     TokenNot16,
+	"and16":     TokenAnd16,
+	"or16":      TokenOr16,
+	"xor16":     TokenXor16,
+	"nand16":    TokenNand16,
+	"mux16":     TokenMux16,
+	"dmux16":    TokenDmux16,
+	"or8way":    TokenOr8Way,
+	"mux4way16": TokenMux4Way16,
+	"mux8way16": TokenMux8Way16,
+	"dmux4way":  TokenDmux4Way,
+	"dmux8way":  TokenDmux8Way,
+
+	"dff":      TokenDff,
+	"bit":      TokenBit,
+	"register": TokenRegister,
 }
 
 type Token struct {
@@ -52,6 +105,26 @@ type Token struct {
 	literal   string
 }
 
+func (t Token) Type() TokenType {
+	return t.tokenType
+}
+
+func (t Token) Literal() string {
+	return t.literal
+}
+
+// IsGate tells whether the token type is the name of a gate
+func (t TokenType) IsGate() bool {
+	_, ok := gateInputs[t]
+	return ok
+}
+
+// TokenPosition is a token with the byte offsets where it starts and ends in the text
+type TokenPosition struct {
+	Token
+	Start, End int
+}
+
 func ParseTokens(text string) ([]Token, error) {
 	result := []Token{}
 	for tok, idx, err := nextToken(text, 0); tok.tokenType != tokenEOF; tok, idx, err = nextToken(text, idx) {
@@ -63,6 +136,41 @@ func ParseTokens(text string) ([]Token, error) {
 	return result, nil
 }
 
+// ParseTokensWithPositions lexes the text like ParseTokens, recording where every token is. On an error it also
+// returns the tokens before the error, so that editors can still highlight them. The error is a *SyntaxError.
+func ParseTokensWithPositions(text string) ([]TokenPosition, error) {
+	result := []TokenPosition{}
+	index := 0
+	for {
+		start := index
+		for start < len(text) && isWhitespace(text[start]) {
+			start++
+		}
+		tok, end, err := nextToken(text, index)
+		if err != nil {
+			// the error is about the characters from the start of the token to where the lexer gave up
+			_, size := utf8.DecodeRuneInString(text[start:])
+			return result, &SyntaxError{Start: start, End: max(end, start+size), err: err}
+		}
+		if tok.tokenType == tokenEOF {
+			return result, nil
+		}
+		result = append(result, TokenPosition{Token: tok, Start: start, End: end})
+		index = end
+	}
+}
+
+// LexErrorOffset is the byte offset of the first character of the text that isn't part of a token, or false if the
+// whole text can be lexed
+func LexErrorOffset(text string) (int, bool) {
+	_, err := ParseTokensWithPositions(text)
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		return 0, false
+	}
+	return syntaxErr.Start, true
+}
+
 func nextToken(text string, index int) (Token, int, error) {
 	var token Token
 	currentIndex := index
@@ -88,13 +196,17 @@ func nextToken(text string, index int) (Token, int, error) {
 		token = Token{tokenType: TokenRparan, literal: string(ch)}
 	case ',':
 		token = Token{tokenType: TokenComma, literal: string(ch)}
+	case ':':
+		token = Token{tokenType: TokenColon, literal: string(ch)}
+	case '=':
+		token = Token{tokenType: TokenEquals, literal: string(ch)}
 	case '0', '1':
 		token = Token{tokenType: TokenValue, literal: string(ch)}
 	default:
 		// Handle identifiers (variables and keywords)
 		if isLetter(ch) {
 			identifier := string(ch)
-			for currentIndex < len(text) && isLetter(text[currentIndex]) {
+			for currentIndex < len(text) && (isLetter(text[currentIndex]) || isDigit(text[currentIndex])) {
 				identifier += string(text[currentIndex])
 				currentIndex++
 			}
@@ -102,6 +214,10 @@ func nextToken(text string, index int) (Token, int, error) {
 			// Check if identifier is a keyword
 			if tokenType, isKeyword := keywords[identifier]; isKeyword {
 				token = Token{tokenType: tokenType, literal: identifier}
+			} else if identifier == "let" {
+				token = Token{tokenType: TokenLet, literal: identifier}
+			} else if currentIndex < len(text) && text[currentIndex] == '[' {
+				return nextBusToken(text, identifier, currentIndex)
 			} else {
 				token = Token{tokenType: TokenVariable, literal: identifier}
 			}
@@ -113,6 +229,58 @@ func nextToken(text string, index int) (Token, int, error) {
 	return token, currentIndex, nil
 }
 
+// nextBusToken lexes the index part of a bus variable: name[i] or name[low..high]
+func nextBusToken(text string, name string, index int) (Token, int, error) {
+	end := index + 1
+	for end < len(text) && text[end] != ']' {
+		if !isDigit(text[end]) && text[end] != '.' {
+			_, size := utf8.DecodeRuneInString(text[end:])
+			return Token{}, end + size, fmt.Errorf("invalid character in index of bus %s: %c", name, text[end])
+		}
+		end++
+	}
+	if end >= len(text) {
+		return Token{}, end, fmt.Errorf("missing ] in index of bus %s", name)
+	}
+	_, low, high, err := parseBusIndex(name + text[index:end+1])
+	if err != nil {
+		return Token{}, end + 1, err
+	}
+	if strings.Contains(text[index:end], "..") {
+		return Token{tokenType: TokenSlice, literal: sliceName(name, low, high)}, end + 1, nil
+	}
+	return Token{tokenType: TokenVariable, literal: bitName(name, low)}, end + 1, nil
+}
+
+// parseBusIndex splits name[i] or name[low..high] into its parts. For a single index low == high.
+func parseBusIndex(literal string) (string, int, int, error) {
+	name, index, found := strings.Cut(literal, "[")
+	if !found || !strings.HasSuffix(index, "]") {
+		return "", 0, 0, fmt.Errorf("invalid bus reference %s", literal)
+	}
+	index = strings.TrimSuffix(index, "]")
+	lowStr, highStr, isSlice := strings.Cut(index, "..")
+	if !isSlice {
+		highStr = lowStr
+	}
+	low, errLow := strconv.Atoi(lowStr)
+	high, errHigh := strconv.Atoi(highStr)
+	if errLow != nil || errHigh != nil || low < 0 {
+		return "", 0, 0, fmt.Errorf("invalid index in bus reference %s", literal)
+	}
+	if low > high {
+		return "", 0, 0, fmt.Errorf("invalid slice %s: the low index must not be greater than the high index", literal)
+	}
+	if high >= maxBusWidth {
+		return "", 0, 0, fmt.Errorf("index in bus reference %s is out of range, buses can have at most %d bits", literal, maxBusWidth)
+	}
+	return name, low, high, nil
+}
+
+func isDigit(ch byte) bool {
+	return '0' <= ch && ch <= '9'
+}
+
 func isWhitespace(ch byte) bool {
 	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r'
 }
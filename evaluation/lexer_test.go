@@ -89,6 +89,26 @@ func TestTokenizer(t *testing.T) {
 				{tokenType: TokenRparan, literal: ")"},
 			},
 		},
+		{
+			text: "mux4way16(a[0..15],x1[3],sel[0..1])",
+			expectedTokens: []Token{
+				{tokenType: TokenMux4Way16, literal: "mux4way16"},
+				{tokenType: TokenLparan, literal: "("},
+				{tokenType: TokenSlice, literal: "a[0..15]"},
+				{tokenType: TokenComma, literal: ","},
+				{tokenType: TokenVariable, literal: "x1[3]"},
+				{tokenType: TokenComma, literal: ","},
+				{tokenType: TokenSlice, literal: "sel[0..1]"},
+				{tokenType: TokenRparan, literal: ")"},
+			},
+		},
+		{
+			text: "a[007] b[2..02]", // indices are normalized
+			expectedTokens: []Token{
+				{tokenType: TokenVariable, literal: "a[7]"},
+				{tokenType: TokenSlice, literal: "b[2..2]"},
+			},
+		},
 	}
 
 	for i, tc := range testCases {
@@ -107,6 +127,13 @@ func TestTokenizerErrors(t *testing.T) {
 		" dmux(foo_bar,1,XOR) ", // underscore not allowed in variable names
 		"2and3",                 // invalid value + keyword as part of identifier
 		"@#$",                   // multiple invalid characters
+		"a[3",                   // unterminated index
+		"a[x]",                  // non numeric index
+		"a[7..3]",               // reversed slice
+		"a[1...3]",              // malformed slice
+		"a[64]",                 // index beyond the maximum bus width
+		"[3]",                   // index without a bus name
+		"b[ 0..15]",             // no whitespace allowed inside the index
 	}
 
 	for _, tc := range testCases {
@@ -116,3 +143,37 @@ func TestTokenizerErrors(t *testing.T) {
 		}
 	}
 }
+
+func TestParseTokensWithPositions(t *testing.T) {
+	text := " mux(a[0..1],\n b)"
+	tokens, err := ParseTokensWithPositions(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	literals := []string{}
+	for _, tok := range tokens {
+		if text[tok.Start:tok.End] != tok.Literal() && tok.Type() != TokenSlice {
+			t.Errorf("token %v is at %d..%d, where the text is %q", tok.Literal(), tok.Start, tok.End, text[tok.Start:tok.End])
+		}
+		literals = append(literals, text[tok.Start:tok.End])
+	}
+	if !reflect.DeepEqual(literals, []string{"mux", "(", "a[0..1]", ",", "b", ")"}) {
+		t.Errorf("unexpected tokens %v", literals)
+	}
+	verifyEquality(t, tokens[0].Type().IsGate(), true)
+	verifyEquality(t, tokens[2].Type().IsGate(), false)
+
+	// the tokens before an error are returned with it
+	tokens, err = ParseTokensWithPositions("and(a, b_c)")
+	if err == nil {
+		t.Error("expected an error for an invalid character")
+	}
+	verifyEquality(t, len(tokens), 5)
+	verifyEquality(t, tokens[4].End, 8)
+
+	offset, found := LexErrorOffset("and(a,  $b)")
+	verifyEquality(t, offset, 8)
+	verifyEquality(t, found, true)
+	_, found = LexErrorOffset("and(a,")
+	verifyEquality(t, found, false)
+}
@@ -0,0 +1,262 @@
+package evaluation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Library holds circuits: named expressions that can be used as gates in other expressions. A call like
+// half(x, and(y, z)) is replaced by the expression of the circuit with the arguments in place of its inputs,
+// so parsed expressions only contain the built-in gates.
+type Library struct {
+	circuits map[string]*libraryCircuit
+}
+
+type libraryCircuit struct {
+	expression string
+	inputs     []string // as given to Add, may contain slices
+
+	body      Expression // parsed on first use, since circuits can use circuits that are added later
+	bits      []string   // the input bits of body, in the order of the gate's inputs
+	resolving bool
+}
+
+func NewLibrary() *Library {
+	return &Library{circuits: map[string]*libraryCircuit{}}
+}
+
+// Add defines a circuit. Inputs lists the inputs of the gate in order, as bits or bus slices like a[0..3]. Without
+// inputs, the gate takes the variables of the expression sorted by name, with the bits of a bus from the least
+// significant, so a slice like x[0..3] passes its bits in order. The expression is checked when the circuit is
+// first used.
+func (l *Library) Add(name, expression string, inputs []string) error {
+	if err := checkCircuitName(name); err != nil {
+		return err
+	}
+	l.circuits[name] = &libraryCircuit{expression: expression, inputs: inputs}
+	return nil
+}
+
+// addExpression defines a circuit from an expression that is already parsed, like one that uses names only a
+// script knows. Unlike Add, it checks the circuit right away.
+func (l *Library) addExpression(name string, body Expression, vars VariableSet, inputs []string) error {
+	if err := checkCircuitName(name); err != nil {
+		return err
+	}
+	if len(stateExpressions(body)) > 0 {
+		return fmt.Errorf("circuit %s has sequential gates, which can't be used in other expressions", name)
+	}
+	bits, err := circuitInputs(vars, inputs)
+	if err != nil {
+		return fmt.Errorf("circuit %s: %w", name, err)
+	}
+	l.circuits[name] = &libraryCircuit{inputs: inputs, body: body, bits: bits}
+	return nil
+}
+
+func checkCircuitName(name string) error {
+	if _, ok := keywords[name]; ok || name == "let" || name == "in" {
+		return fmt.Errorf("%s is a reserved name and can't be the name of a circuit", name)
+	}
+	tokens, err := ParseTokens(name)
+	if err != nil || len(tokens) != 1 || tokens[0].tokenType != TokenVariable || tokens[0].literal != name ||
+		strings.Contains(name, "[") {
+		return fmt.Errorf("invalid circuit name %q, names are a letter followed by letters or digits", name)
+	}
+	return nil
+}
+
+// Names lists the circuits, sorted
+func (l *Library) Names() []string {
+	names := make([]string, 0, len(l.circuits))
+	for name := range l.circuits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Signature shows how a circuit is called, like GateSignature. It reports errors in the expression of the circuit.
+func (l *Library) Signature(name string) (string, error) {
+	c, err := l.resolve(name)
+	if err != nil {
+		return "", err
+	}
+	parts := []string{}
+	bits := []Expression{}
+	for _, bit := range c.bits {
+		bits = append(bits, &VariableExpression{variableName: bit})
+	}
+	for _, part := range compactBits(bits) {
+		switch p := part.(type) {
+		case *VariableExpression:
+			parts = append(parts, p.variableName)
+		case *BusVariableExpression:
+			parts = append(parts, sliceName(p.name, p.low, p.high))
+		}
+	}
+	return name + "(" + strings.Join(parts, ", ") + ")", nil
+}
+
+// ParseExpression parses an expression that can call the circuits of the library, like ParseExpressionWithWires
+func (l *Library) ParseExpression(input string) (Expression, VariableSet, []Wire, error) {
+	return parseExpression(input, l, nil)
+}
+
+// Compute computes the truth table of an expression that can call the circuits of the library
+func (l *Library) Compute(expression string) (*Result, error) {
+	expr, vars, wires, err := l.ParseExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+	return ComputeWithWires(expr, vars, wires)
+}
+
+// isCall tells whether the tokens at pos are a call of a circuit of the library
+func (l *Library) isCall(tokens []Token, pos int) bool {
+	if l == nil || pos+1 >= len(tokens) || tokens[pos].tokenType != TokenVariable || tokens[pos+1].tokenType != TokenLparan {
+		return false
+	}
+	_, ok := l.circuits[tokens[pos].literal]
+	return ok
+}
+
+func (l *Library) resolve(name string) (*libraryCircuit, error) {
+	c, ok := l.circuits[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown circuit %s", name)
+	}
+	if c.body != nil {
+		return c, nil
+	}
+	if c.resolving {
+		return nil, fmt.Errorf("circuit %s uses itself", name)
+	}
+	c.resolving = true
+	defer func() { c.resolving = false }()
+
+	body, vars, _, err := l.ParseExpression(c.expression)
+	if err != nil {
+		return nil, fmt.Errorf("circuit %s: %w", name, err)
+	}
+	if len(stateExpressions(body)) > 0 {
+		return nil, fmt.Errorf("circuit %s has sequential gates, which can't be used in other expressions", name)
+	}
+	bits, err := circuitInputs(vars, c.inputs)
+	if err != nil {
+		return nil, fmt.Errorf("circuit %s: %w", name, err)
+	}
+	c.body, c.bits = body, bits
+	return c, nil
+}
+
+// circuitInputs expands the inputs given to Add into bits, checking that they are the variables of the expression
+func circuitInputs(vars VariableSet, inputs []string) ([]string, error) {
+	if len(inputs) == 0 {
+		bits := getVarsSlice(vars)
+		sort.SliceStable(bits, func(i, j int) bool {
+			busI, indexI, _ := splitBitName(bits[i])
+			busJ, indexJ, _ := splitBitName(bits[j])
+			if busI != busJ {
+				return busI < busJ
+			}
+			return indexI < indexJ
+		})
+		return bits, nil
+	}
+
+	bits := []string{}
+	seen := map[string]bool{}
+	for _, input := range inputs {
+		names := []string{input}
+		if strings.Contains(input, "..") {
+			bus, low, high, err := parseBusIndex(input)
+			if err != nil {
+				return nil, err
+			}
+			names = nil
+			for i := low; i <= high; i++ {
+				names = append(names, bitName(bus, i))
+			}
+		}
+		for _, bit := range names {
+			if _, ok := vars[bit]; !ok {
+				return nil, fmt.Errorf("input %s is not a variable of the expression", bit)
+			}
+			if seen[bit] {
+				return nil, fmt.Errorf("input %s is listed more than once", bit)
+			}
+			seen[bit] = true
+			bits = append(bits, bit)
+		}
+	}
+	for _, v := range getVarsSlice(vars) {
+		if !seen[v] {
+			return nil, fmt.Errorf("variable %s is missing from the inputs", v)
+		}
+	}
+	return bits, nil
+}
+
+// instantiate replaces a call of the circuit by its expression, with the arguments in place of its inputs.
+// Arguments with several outputs must be bus slices, whose bits go to consecutive inputs.
+func (c *libraryCircuit) instantiate(name string, args []Expression) (Expression, error) {
+	bits := []Expression{}
+	for i, arg := range args {
+		switch a := arg.(type) {
+		case *BusVariableExpression:
+			for j := a.low; j <= a.high; j++ {
+				bits = append(bits, &VariableExpression{variableName: bitName(a.name, j)})
+			}
+		default:
+			if arg.NumOutputs() != 1 {
+				return nil, fmt.Errorf("input %d of %s has %d outputs, but only bus slices can be passed to several inputs of a circuit", i+1, name, arg.NumOutputs())
+			}
+			bits = append(bits, arg)
+		}
+	}
+	values := map[string]Expression{}
+	for i, bit := range c.bits {
+		values[bit] = bits[i]
+	}
+	parts := substitution{values: values, done: map[Expression][]Expression{}}.substitute(c.body)
+	if len(parts) != 1 {
+		return nil, fmt.Errorf("%s only passes on its inputs, so it can't be used as a gate", name)
+	}
+	return parts[0], nil
+}
+
+type substitution struct {
+	values map[string]Expression
+	done   map[Expression][]Expression // shared nodes are substituted once
+}
+
+// substitute returns the expression with the values in place of its variables, split into parts when a bus
+// slice falls apart
+func (s substitution) substitute(expr Expression) []Expression {
+	if parts, ok := s.done[expr]; ok {
+		return parts
+	}
+	var parts []Expression
+	switch e := expr.(type) {
+	case *LiteralExpression:
+		parts = []Expression{e}
+	case *VariableExpression:
+		parts = []Expression{s.values[e.variableName]}
+	case *BusVariableExpression:
+		for i := e.low; i <= e.high; i++ {
+			parts = append(parts, s.values[bitName(e.name, i)])
+		}
+		parts = compactBits(parts)
+	default:
+		_, args := formatParts(expr)
+		inputs := []Expression{}
+		for _, arg := range args {
+			inputs = append(inputs, s.substitute(arg)...)
+		}
+		parts = []Expression{withInputs(expr, inputs)}
+	}
+	s.done[expr] = parts
+	return parts
+}
@@ -0,0 +1,139 @@
+package evaluation
+
+import (
+	"testing"
+)
+
+func testLibrary(t *testing.T) *Library {
+	t.Helper()
+	l := NewLibrary()
+	circuits := []struct {
+		name, expression string
+		inputs           []string
+	}{
+		{"half", "dmux(xor(a, b), 0)", nil},
+		{"carry", "or(and(a, b), and(cin, xor(a, b)))", nil},
+		{"full", "xor(xor(a, b), cin)", nil},
+		{"select", "mux(x, y, s)", []string{"s", "x", "y"}},
+		{"add2", "full(a[1], b[1], carry(a[0], b[0], c))", []string{"a[0..1]", "b[0..1]", "c"}},
+		{"loop", "not(loop(a))", nil},
+	}
+	for _, c := range circuits {
+		if err := l.Add(c.name, c.expression, c.inputs); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return l
+}
+
+func TestLibraryCircuits(t *testing.T) {
+	l := testLibrary(t)
+	tests := []struct {
+		expression string
+		equivalent string
+	}{
+		{"full(x, y, z)", "xor(xor(x, y), z)"},
+		{"select(1, p, q)", "p"},
+		{"and(select(s, x, y), carry(x, y, 0))", "and(mux(x, y, s), or(and(x, y), and(0, xor(x, y))))"},
+		{"add2(p[0..1], q[0..1], 0)", "xor(xor(p[1], q[1]), or(and(p[0], q[0]), and(0, xor(p[0], q[0]))))"},
+		{"add2(p[0], p[1], q[0..1], 1)", "xor(xor(p[1], q[1]), or(and(p[0], q[0]), and(1, xor(p[0], q[0]))))"},
+		{"let s = xor(x, y) in full(s, s, x)", "x"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.expression, func(t *testing.T) {
+			got, err := l.Compute(tc.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected, _, err := ParseExpression(tc.equivalent)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for row, assignment := range got.Assignments {
+				want, err := expected.Evaluate(getArgs(got.Variables, assignment))
+				if err != nil {
+					t.Fatal(err)
+				}
+				verifyEquality(t, got.Outputs[row][0], want[0])
+			}
+		})
+	}
+}
+
+func TestLibraryErrors(t *testing.T) {
+	l := testLibrary(t)
+	for _, expression := range []string{
+		"loop(a)",              // a circuit that calls itself
+		"half(a)",              // too few inputs
+		"full(a, b, c, d)",     // too many inputs
+		"carry(half(a, b), c)", // a gate with several outputs as the input of a circuit
+		"missing(a)",           // not a circuit
+	} {
+		if _, err := l.Compute(expression); err == nil {
+			t.Errorf("expected an error for %v", expression)
+		}
+	}
+
+	for _, name := range []string{"and", "let", "in", "a[0]", "2x", "x y"} {
+		if err := l.Add(name, "a", nil); err == nil {
+			t.Errorf("expected an error for circuit name %q", name)
+		}
+	}
+
+	l.Add("seq", "dff(a)", nil)
+	l.Add("wrongInputs", "and(a, b)", []string{"a"})
+	l.Add("extraInputs", "and(a, b)", []string{"a", "b", "c"})
+	for _, name := range []string{"seq", "wrongInputs", "extraInputs"} {
+		if _, err := l.Signature(name); err == nil {
+			t.Errorf("expected an error for circuit %v", name)
+		}
+	}
+}
+
+func TestLibrarySignature(t *testing.T) {
+	l := testLibrary(t)
+	for name, expected := range map[string]string{
+		"half":   "half(a, b)",
+		"select": "select(s, x, y)",
+		"add2":   "add2(a[0..1], b[0..1], c)",
+	} {
+		got, err := l.Signature(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		verifyEquality(t, got, expected)
+	}
+}
+
+func TestLibraryRestrictTraceSimulate(t *testing.T) {
+	l := testLibrary(t)
+	residual, vars, err := l.RestrictExpression("select(s, x, y)", map[string]bool{"s": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifyEquality(t, formatRestricted(residual), "x")
+	verifyEquality(t, len(vars), 1)
+
+	trace, err := l.TraceExpression("full(x, 1, 0)", map[string]bool{"x": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifyEquality(t, trace.Outputs[0], false)
+
+	sim, err := l.NewSimulator("dff(full(x, y, 0))")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sim.Step(map[string]bool{"x": true, "y": false}); err != nil {
+		t.Fatal(err)
+	}
+	step, err := sim.Step(map[string]bool{"x": false, "y": false})
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifyEquality(t, step.Outputs[0], true)
+
+	if _, _, err := RestrictExpression("select(s, x, y)", map[string]bool{"s": true}); err == nil {
+		t.Error("expected an error restricting a circuit without the library")
+	}
+}
@@ -0,0 +1,155 @@
+package evaluation
+
+import "fmt"
+
+// Logic is a four-valued logic level, as in Verilog. X is an unknown value and Z an undriven
+// (high-impedance) one. Gates read Z like X and never output Z, so restricted to 0, 1 and X the
+// gates follow Kleene's three-valued logic.
+type Logic uint8
+
+const (
+	Logic0 Logic = iota
+	Logic1
+	LogicX
+	LogicZ
+)
+
+func (l Logic) String() string {
+	switch l {
+	case Logic0:
+		return "0"
+	case Logic1:
+		return "1"
+	case LogicZ:
+		return "Z"
+	}
+	return "X"
+}
+
+// ParseLogic reads 0, 1, X or Z (case insensitive)
+func ParseLogic(value string) (Logic, error) {
+	switch value {
+	case "0":
+		return Logic0, nil
+	case "1":
+		return Logic1, nil
+	case "x", "X":
+		return LogicX, nil
+	case "z", "Z":
+		return LogicZ, nil
+	}
+	return LogicX, fmt.Errorf("invalid logic value %q, expected 0, 1, X or Z", value)
+}
+
+func LogicOf(value bool) Logic {
+	if value {
+		return Logic1
+	}
+	return Logic0
+}
+
+// Known reports whether the value is 0 or 1
+func (l Logic) Known() bool {
+	return l == Logic0 || l == Logic1
+}
+
+// read is the value a gate sees on its input: an undriven input reads as unknown
+func (l Logic) read() Logic {
+	if l == LogicZ {
+		return LogicX
+	}
+	return l
+}
+
+func LogicNot(a Logic) Logic {
+	if !a.Known() {
+		return LogicX
+	}
+	return LogicOf(a == Logic0)
+}
+
+// LogicAnd is 0 as soon as one input is 0, even if the other one is unknown
+func LogicAnd(a, b Logic) Logic {
+	switch {
+	case a == Logic0 || b == Logic0:
+		return Logic0
+	case a == Logic1 && b == Logic1:
+		return Logic1
+	}
+	return LogicX
+}
+
+// LogicOr is 1 as soon as one input is 1, even if the other one is unknown
+func LogicOr(a, b Logic) Logic {
+	switch {
+	case a == Logic1 || b == Logic1:
+		return Logic1
+	case a == Logic0 && b == Logic0:
+		return Logic0
+	}
+	return LogicX
+}
+
+func LogicNand(a, b Logic) Logic {
+	return LogicNot(LogicAnd(a, b))
+}
+
+func LogicXor(a, b Logic) Logic {
+	if !a.Known() || !b.Known() {
+		return LogicX
+	}
+	return LogicOf(a != b)
+}
+
+// LogicMux merges its inputs when the selector is unknown: the output is only known if both inputs agree
+func LogicMux(a, b, sel Logic) Logic {
+	switch {
+	case sel == Logic1:
+		return a.read()
+	case sel == Logic0:
+		return b.read()
+	case a.Known() && a == b:
+		return a
+	}
+	return LogicX
+}
+
+// LogicDmux routes a to the output picked by sel. With an unknown selector, both outputs are only known when a is 0.
+func LogicDmux(a, sel Logic) (Logic, Logic) {
+	return LogicAnd(a, LogicNot(sel)), LogicAnd(a, sel)
+}
+
+// logicEvaluator is a circuitBuilder that evaluates the gates directly on four-valued logic
+type logicEvaluator map[string]Logic
+
+func (e logicEvaluator) Const(value bool) Logic { return LogicOf(value) }
+
+// Var reads variables that weren't assigned as X
+func (e logicEvaluator) Var(name string) Logic {
+	if val, ok := e[name]; ok {
+		return val
+	}
+	return LogicX
+}
+
+func (e logicEvaluator) Not(a Logic) Logic         { return LogicNot(a) }
+func (e logicEvaluator) And(a, b Logic) Logic      { return LogicAnd(a, b) }
+func (e logicEvaluator) Or(a, b Logic) Logic       { return LogicOr(a, b) }
+func (e logicEvaluator) Nand(a, b Logic) Logic     { return LogicNand(a, b) }
+func (e logicEvaluator) Xor(a, b Logic) Logic      { return LogicXor(a, b) }
+func (e logicEvaluator) Mux(a, b, sel Logic) Logic { return LogicMux(a, b, sel) }
+
+// EvaluateLogic evaluates an expression over four-valued logic. Variables missing from args are
+// unknown (X), so the result shows which outputs are already determined by a partial assignment.
+func EvaluateLogic(expr Expression, args map[string]Logic) ([]Logic, error) {
+	return lower[Logic](expr, logicEvaluator(args))
+}
+
+// EvaluateLogicExpression parses and evaluates an expression over four-valued logic
+func EvaluateLogicExpression(expression string, args map[string]Logic) ([]Logic, error) {
+	expr, _, err := ParseExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+	return EvaluateLogic(expr, args)
+}
@@ -0,0 +1,156 @@
+package evaluation
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+var logicValues = []Logic{Logic0, Logic1, LogicX, LogicZ}
+
+func TestLogicGatesMatchBooleanGates(t *testing.T) {
+	for _, a := range []bool{false, true} {
+		verifyEquality(t, LogicNot(LogicOf(a)), LogicOf(Not(a)))
+		for _, b := range []bool{false, true} {
+			verifyEquality(t, LogicAnd(LogicOf(a), LogicOf(b)), LogicOf(And(a, b)))
+			verifyEquality(t, LogicOr(LogicOf(a), LogicOf(b)), LogicOf(Or(a, b)))
+			verifyEquality(t, LogicNand(LogicOf(a), LogicOf(b)), LogicOf(Nand(a, b)))
+			verifyEquality(t, LogicXor(LogicOf(a), LogicOf(b)), LogicOf(Xor(a, b)))
+			out0, out1 := LogicDmux(LogicOf(a), LogicOf(b))
+			expected0, expected1 := Dmux(a, b)
+			verifyEquality(t, out0, LogicOf(expected0))
+			verifyEquality(t, out1, LogicOf(expected1))
+			for _, sel := range []bool{false, true} {
+				verifyEquality(t, LogicMux(LogicOf(a), LogicOf(b), LogicOf(sel)), LogicOf(Mux(a, b, sel)))
+			}
+		}
+	}
+}
+
+func TestLogicGates(t *testing.T) {
+	X, Z := LogicX, LogicZ
+	tests := []struct {
+		name     string
+		got      Logic
+		expected Logic
+	}{
+		{"and(0,X)", LogicAnd(Logic0, X), Logic0},
+		{"and(1,X)", LogicAnd(Logic1, X), X},
+		{"and(Z,0)", LogicAnd(Z, Logic0), Logic0},
+		{"or(X,1)", LogicOr(X, Logic1), Logic1},
+		{"or(0,Z)", LogicOr(Logic0, Z), X},
+		{"nand(X,0)", LogicNand(X, Logic0), Logic1},
+		{"xor(1,X)", LogicXor(Logic1, X), X},
+		{"not(Z)", LogicNot(Z), X},
+		{"mux(1,1,X)", LogicMux(Logic1, Logic1, X), Logic1},
+		{"mux(0,1,X)", LogicMux(Logic0, Logic1, X), X},
+		{"mux(X,X,Z)", LogicMux(X, X, Z), X},
+		{"mux(Z,0,1)", LogicMux(Z, Logic0, Logic1), X},
+	}
+	for _, tc := range tests {
+		if tc.got != tc.expected {
+			t.Errorf("%v = %v, expected %v", tc.name, tc.got, tc.expected)
+		}
+	}
+
+	out0, out1 := LogicDmux(Logic0, X)
+	if out0 != Logic0 || out1 != Logic0 {
+		t.Errorf("dmux(0,X) = %v,%v, expected 0,0", out0, out1)
+	}
+	out0, out1 = LogicDmux(Logic1, X)
+	if out0 != X || out1 != X {
+		t.Errorf("dmux(1,X) = %v,%v, expected X,X", out0, out1)
+	}
+}
+
+func TestEvaluateLogic(t *testing.T) {
+	tests := []struct {
+		expression string
+		args       map[string]Logic
+		expected   string
+	}{
+		{"and(a,b)", map[string]Logic{"a": Logic0}, "0"},
+		{"and(a,b)", map[string]Logic{"a": Logic1}, "X"},
+		{"or(a,not(a))", map[string]Logic{}, "X"}, // X-pessimism, the value is actually always 1
+		{"mux(a,a,s)", map[string]Logic{"a": Logic1, "s": LogicZ}, "1"},
+		{"dmux(a,s)", map[string]Logic{"a": Logic0}, "00"},
+		{"a", map[string]Logic{"a": LogicZ}, "Z"},
+		{"dmux4way(x,s[0..1])", map[string]Logic{"x": Logic1, "s[0]": Logic1}, "0X0X"},
+		{"and16(a[0..15],b[0..15])", map[string]Logic{"a[0]": Logic0, "a[15]": Logic1, "b[15]": Logic1}, "0XXXXXXXXXXXXXX1"},
+		{"dff(a)", map[string]Logic{"a": Logic1}, "X"}, // the state isn't known
+	}
+	for _, tc := range tests {
+		got, err := EvaluateLogicExpression(tc.expression, tc.args)
+		if err != nil {
+			t.Fatalf("failed to evaluate %v: %v", tc.expression, err)
+		}
+		gotString := ""
+		for _, l := range got {
+			gotString += l.String()
+		}
+		if gotString != tc.expected {
+			t.Errorf("%v with %v = %v, expected %v", tc.expression, tc.args, gotString, tc.expected)
+		}
+	}
+}
+
+// Known outputs for a partial assignment must match the boolean evaluation of every completion of it
+func TestEvaluateLogicIsSound(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, input := range append([]string{"mux(xor(a,b),nand(a,c),or(b,c))", "dmux(mux(a,b,c),xor(a,c))"}, busExpressions...) {
+		expr, vars, err := ParseExpression(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 50; i++ {
+			partial := map[string]Logic{}
+			for v := range vars {
+				partial[v] = logicValues[rng.Intn(len(logicValues))]
+			}
+			got, err := EvaluateLogic(expr, partial)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			args := map[string]bool{}
+			complete := map[string]Logic{}
+			for v, l := range partial {
+				args[v] = l == Logic1 || (!l.Known() && rng.Intn(2) == 1)
+				complete[v] = LogicOf(args[v])
+			}
+			expected, err := expr.Evaluate(args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for j := range got {
+				if got[j].Known() && got[j] != LogicOf(expected[j]) {
+					t.Fatalf("%v: output %d is %v for %v, but %v for %v", input, j, got[j], partial, expected[j], args)
+				}
+			}
+
+			gotComplete, err := EvaluateLogic(expr, complete)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expectedComplete := []Logic{}
+			for _, b := range expected {
+				expectedComplete = append(expectedComplete, LogicOf(b))
+			}
+			if !reflect.DeepEqual(gotComplete, expectedComplete) {
+				t.Fatalf("%v: four-valued evaluation %v differs from %v for %v", input, gotComplete, expected, args)
+			}
+		}
+	}
+}
+
+func TestParseLogic(t *testing.T) {
+	for _, l := range logicValues {
+		parsed, err := ParseLogic(l.String())
+		if err != nil || parsed != l {
+			t.Errorf("%v doesn't round trip: %v", l, err)
+		}
+	}
+	if _, err := ParseLogic("2"); err == nil {
+		t.Errorf("expected error parsing 2")
+	}
+}
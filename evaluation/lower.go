@@ -0,0 +1,141 @@
+package evaluation
+
+import "fmt"
+
+// circuitBuilder is implemented by the alternative circuit representations (AIG, exporters, ...).
+// lower walks an Expression and rebuilds it out of these primitive gates, one value per output.
+type circuitBuilder[T any] interface {
+	Const(value bool) T
+	Var(name string) T
+	Not(a T) T
+	And(a, b T) T
+	Or(a, b T) T
+	Nand(a, b T) T
+	Xor(a, b T) T
+	Mux(a, b, sel T) T
+}
+
+func lower[T any](expr Expression, b circuitBuilder[T]) ([]T, error) {
+	l := &lowering[T]{b: b, memo: map[Expression][]T{}}
+	return l.lower(expr)
+}
+
+// lowering remembers the values of the nodes it already lowered, so subexpressions shared by
+// HashCons are only built once
+type lowering[T any] struct {
+	b    circuitBuilder[T]
+	memo map[Expression][]T
+}
+
+func (l *lowering[T]) lower(expr Expression) ([]T, error) {
+	if result, ok := l.memo[expr]; ok {
+		return result, nil
+	}
+	result, err := l.lowerNode(expr)
+	if err != nil {
+		return nil, err
+	}
+	l.memo[expr] = result
+	return result, nil
+}
+
+func (l *lowering[T]) lowerNode(expr Expression) ([]T, error) {
+	b := l.b
+	switch e := expr.(type) {
+	case *LiteralExpression:
+		return []T{b.Const(e.value)}, nil
+	case *VariableExpression:
+		return []T{b.Var(e.variableName)}, nil
+	case *BusVariableExpression:
+		result := []T{}
+		for i := e.low; i <= e.high; i++ {
+			result = append(result, b.Var(bitName(e.name, i)))
+		}
+		return result, nil
+	case *NotExpression:
+		in, err := l.inputs([]Expression{e.expression}, 1)
+		if err != nil {
+			return nil, err
+		}
+		return []T{b.Not(in[0])}, nil
+	case *BinaryExpression:
+		in, err := l.inputs(e.expressions, 2)
+		if err != nil {
+			return nil, err
+		}
+		switch e.op {
+		case TokenNand:
+			return []T{b.Nand(in[0], in[1])}, nil
+		case TokenAnd:
+			return []T{b.And(in[0], in[1])}, nil
+		case TokenOr:
+			return []T{b.Or(in[0], in[1])}, nil
+		case TokenXor:
+			return []T{b.Xor(in[0], in[1])}, nil
+		default:
+			return nil, fmt.Errorf("lowering of binary expression %d not implemented", e.op)
+		}
+	case *MuxExpression:
+		in, err := l.inputs(e.expressions, 3)
+		if err != nil {
+			return nil, err
+		}
+		return []T{b.Mux(in[0], in[1], in[2])}, nil
+	case *DmuxExpression:
+		in, err := l.inputs(e.expressions, 2)
+		if err != nil {
+			return nil, err
+		}
+		return []T{b.And(in[0], b.Not(in[1])), b.And(in[0], in[1])}, nil
+	case *BusGateExpression:
+		in, err := l.inputs(e.expressions, gateInputs[e.op])
+		if err != nil {
+			return nil, err
+		}
+		return lowerBusGate(e.op, in, b), nil
+	case *StateExpression:
+		// the combinational view: current state bits are inputs
+		result := []T{}
+		for _, name := range e.state {
+			result = append(result, b.Var(name))
+		}
+		return result, nil
+	default:
+		return nil, fmt.Errorf("cannot lower expression of type %T", expr)
+	}
+}
+
+func (l *lowering[T]) inputs(expressions []Expression, expected int) ([]T, error) {
+	result := []T{}
+	for _, expr := range expressions {
+		outs, err := l.lower(expr)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, outs...)
+	}
+	if len(result) != expected {
+		return nil, fmt.Errorf("expected %d inputs, but got %d", expected, len(result))
+	}
+	return result, nil
+}
+
+// variableCollector is a circuitBuilder that only records the variable names it encounters
+type variableCollector VariableSet
+
+func (c variableCollector) Const(value bool) struct{}       { return struct{}{} }
+func (c variableCollector) Var(name string) struct{}        { c[name] = struct{}{}; return struct{}{} }
+func (c variableCollector) Not(a struct{}) struct{}         { return a }
+func (c variableCollector) And(a, b struct{}) struct{}      { return a }
+func (c variableCollector) Or(a, b struct{}) struct{}       { return a }
+func (c variableCollector) Nand(a, b struct{}) struct{}     { return a }
+func (c variableCollector) Xor(a, b struct{}) struct{}      { return a }
+func (c variableCollector) Mux(a, b, sel struct{}) struct{} { return a }
+
+func collectVariables(expr Expression) (VariableSet, error) {
+	vars := VariableSet{}
+	if _, err := lower[struct{}](expr, variableCollector(vars)); err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
@@ -3,6 +3,10 @@ package evaluation
 import (
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -19,41 +23,249 @@ type Expression interface {
 type VariableSet map[string]struct{}
 
 func ParseExpression(input string) (Expression, VariableSet, error) {
-	tokens, err := ParseTokens(input)
+	expression, variableSet, _, err := ParseExpressionWithWires(input)
+	return expression, variableSet, err
+}
+
+// Wire is a name bound with let. Every use of the name refers to the same Expression node.
+type Wire struct {
+	Name       string
+	Expression Expression
+}
+
+// ParseExpressionWithWires also returns the let bindings of the expression, in the order they appear.
+// Bound names are not part of the VariableSet.
+func ParseExpressionWithWires(input string) (Expression, VariableSet, []Wire, error) {
+	return parseExpression(input, nil, nil)
+}
+
+// SyntaxError is an error in the text of an expression. Start and End are the byte offsets of the text it is
+// about: the characters the lexer couldn't read, or the token the parser stopped at. Errors at the end of the
+// text are empty ranges there.
+type SyntaxError struct {
+	Start, End int
+	err        error
+}
+
+func (e *SyntaxError) Error() string {
+	return e.err.Error()
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return e.err
+}
+
+// definedExpression is an expression parsed before that can be used by name in others, with its variables
+type definedExpression struct {
+	expression Expression
+	variables  VariableSet
+}
+
+func parseExpression(input string, library *Library, defined map[string]definedExpression) (Expression, VariableSet, []Wire, error) {
+	positions, err := ParseTokensWithPositions(input)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to extract tokens from input: %w", err)
+		syntaxErr := err.(*SyntaxError)
+		syntaxErr.err = fmt.Errorf("failed to extract tokens from input: %w", syntaxErr.err)
+		return nil, nil, nil, syntaxErr
 	}
-
-	if len(tokens) == 0 {
-		return nil, nil, fmt.Errorf("empty expression cannot be evaluated")
+	tokens := make([]Token, len(positions))
+	for i, tok := range positions {
+		tokens[i] = tok.Token
+	}
+	// at reports an error at a token, or at the end of the text after the last one
+	at := func(i int, err error) error {
+		if i >= len(positions) {
+			return &SyntaxError{Start: len(input), End: len(input), err: err}
+		}
+		return &SyntaxError{Start: positions[i].Start, End: positions[i].End, err: err}
 	}
 
-	gateTokens := map[TokenType]struct{}{
-		TokenNand: {},
-		TokenNot:  {},
-		TokenAnd:  {},
-		TokenOr:   {},
-		TokenXor:  {},
-		TokenMux:  {},
-		TokenDmux: {},
+	if len(tokens) == 0 {
+		return nil, nil, nil, at(0, fmt.Errorf("empty expression cannot be evaluated"))
 	}
 
-	if _, ok := gateTokens[tokens[0].tokenType]; !ok && len(tokens) > 1 {
-		return nil, nil, fmt.Errorf("Expression must either start with a gate name or contain exactly one literal or variable name")
+	if _, ok := gateInputs[tokens[0].tokenType]; !ok && tokens[0].tokenType != TokenLet && !library.isCall(tokens, 0) && len(tokens) > 1 {
+		return nil, nil, nil, at(0, fmt.Errorf("Expression must either start with a gate name or contain exactly one literal or variable name"))
 	}
 
-	parser := parser{tokens: tokens, pos: -1}
+	parser := parser{tokens: tokens, pos: -1, labels: map[string]struct{}{}, scope: map[string]Expression{}, used: map[string]bool{},
+		library: library, defined: defined}
 	variableSet := map[string]struct{}{}
 	expression, err := parser.parse(variableSet, true)
 	if err != nil {
-		return nil, nil, err
+		syntaxErr := at(max(parser.pos, 0), err).(*SyntaxError)
+		if errors.Is(err, errTrailingTokens) {
+			syntaxErr.End = positions[len(positions)-1].End // all of the text that follows
+		}
+		return nil, nil, nil, syntaxErr
+	}
+	if parser.pos != len(tokens)-1 {
+		syntaxErr := at(parser.pos+1, errors.New("tokens found after the root expression ended. Remove text following the root expression")).(*SyntaxError)
+		syntaxErr.End = positions[len(positions)-1].End
+		return nil, nil, nil, syntaxErr
+	}
+	if err := checkBusUsage(variableSet); err != nil {
+		return nil, nil, nil, err
+	}
+	if err := checkWires(parser.wires, variableSet); err != nil {
+		return nil, nil, nil, err
+	}
+	renameWires(parser.wires, variableSet)
+
+	h := newHashConser()
+	expression = h.share(expression)
+	for i := range parser.wires {
+		parser.wires[i].Expression = h.share(parser.wires[i].Expression)
+	}
+	return expression, variableSet, parser.wires, nil
+}
+
+// checkWires rejects names that are bound in one place and used as inputs outside of their scope
+func checkWires(wires []Wire, variables VariableSet) error {
+	bound := map[string]struct{}{}
+	for _, w := range wires {
+		bound[w.Name] = struct{}{}
+	}
+	for _, v := range getVarsSlice(variables) {
+		name, _, _ := splitBitName(v)
+		if _, ok := bound[name]; ok {
+			return fmt.Errorf("%s is bound with let, but also used as an input outside of the let", name)
+		}
+	}
+	return nil
+}
+
+// renameWires numbers names that are bound again in sibling scopes, as in and(let t = a in t, let t = b in t), so
+// that every wire has a name of its own in truth tables and formatted expressions: t and t2. Numbers that would
+// clash with another name are skipped.
+func renameWires(wires []Wire, variables VariableSet) {
+	taken := map[string]bool{}
+	for v := range variables {
+		name, _, _ := splitBitName(v)
+		taken[name] = true
+	}
+	for _, w := range wires {
+		taken[w.Name] = true
+	}
+	seen := map[string]bool{}
+	for i, w := range wires {
+		if !seen[w.Name] {
+			seen[w.Name] = true
+			continue
+		}
+		n := 2
+		for taken[w.Name+strconv.Itoa(n)] {
+			n++
+		}
+		wires[i].Name = w.Name + strconv.Itoa(n)
+		taken[wires[i].Name] = true
+	}
+}
+
+// gateInputs is the number of inputs each gate expects
+var gateInputs = map[TokenType]int{
+	TokenNand: 2,
+	TokenNot:  1,
+	TokenAnd:  2,
+	TokenOr:   2,
+	TokenXor:  2,
+	TokenMux:  3,
+	TokenDmux: 2,
+
+	TokenNot16:     16,
+	TokenAnd16:     32,
+	TokenOr16:      32,
+	TokenXor16:     32,
+	TokenNand16:    32,
+	TokenMux16:     33,
+	TokenDmux16:    17,
+	TokenOr8Way:    8,
+	TokenMux4Way16: 66,
+	TokenMux8Way16: 131,
+	TokenDmux4Way:  3,
+	TokenDmux8Way:  4,
+
+	TokenDff:      1,
+	TokenBit:      2,
+	TokenRegister: 17,
+}
+
+// Gates lists the names of all gates, sorted
+func Gates() []string {
+	names := make([]string, 0, len(keywords))
+	for name := range keywords {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GateInputs is the number of input bits of a gate, or false if there is no gate with that name
+func GateInputs(name string) (int, bool) {
+	tokenType, ok := keywords[name]
+	if !ok {
+		return 0, false
+	}
+	return gateInputs[tokenType], true
+}
+
+// gateParameters names the inputs of the gates, as shown by GateSignature
+var gateParameters = map[TokenType][]string{
+	TokenNand: {"a", "b"},
+	TokenNot:  {"in"},
+	TokenAnd:  {"a", "b"},
+	TokenOr:   {"a", "b"},
+	TokenXor:  {"a", "b"},
+	TokenMux:  {"a", "b", "sel"},
+	TokenDmux: {"in", "sel"},
+
+	TokenNot16:     {"in[0..15]"},
+	TokenAnd16:     {"a[0..15]", "b[0..15]"},
+	TokenOr16:      {"a[0..15]", "b[0..15]"},
+	TokenXor16:     {"a[0..15]", "b[0..15]"},
+	TokenNand16:    {"a[0..15]", "b[0..15]"},
+	TokenMux16:     {"a[0..15]", "b[0..15]", "sel"},
+	TokenDmux16:    {"in[0..15]", "sel"},
+	TokenOr8Way:    {"in[0..7]"},
+	TokenMux4Way16: {"a[0..15]", "b[0..15]", "c[0..15]", "d[0..15]", "sel[0..1]"},
+	TokenMux8Way16: {"a[0..15]", "b[0..15]", "c[0..15]", "d[0..15]", "e[0..15]", "f[0..15]", "g[0..15]", "h[0..15]", "sel[0..2]"},
+	TokenDmux4Way:  {"in", "sel[0..1]"},
+	TokenDmux8Way:  {"in", "sel[0..2]"},
+
+	TokenDff:      {"in"},
+	TokenBit:      {"in", "load"},
+	TokenRegister: {"in[0..15]", "load"},
+}
+
+// GateParameters names the inputs of a gate, with a slice for each bus input, or returns false if there is no gate
+// with that name
+func GateParameters(name string) ([]string, bool) {
+	tokenType, ok := keywords[name]
+	if !ok {
+		return nil, false
+	}
+	return gateParameters[tokenType], true
+}
+
+// GateSignature shows how a gate is called, e.g. mux(a, b, sel)
+func GateSignature(name string) (string, bool) {
+	parameters, ok := GateParameters(name)
+	if !ok {
+		return "", false
 	}
-	return expression, variableSet, nil
+	return name + "(" + strings.Join(parameters, ", ") + ")", true
 }
 
 type parser struct {
-	tokens []Token
-	pos    int
+	tokens    []Token
+	pos       int
+	stateBits int // number of state bits allocated to unnamed sequential gates so far
+	labels    map[string]struct{}
+	scope     map[string]Expression        // let bindings visible at the current position
+	used      map[string]bool              // let bindings that were referenced
+	wires     []Wire                       // all let bindings so far
+	library   *Library                     // circuits that can be called like gates, nil if there are none
+	defined   map[string]definedExpression // names usable like let bindings that are visible everywhere
 }
 
 func (p *parser) parse(variableCollector VariableSet, isRoot bool /*Sorry, Uncle Bob*/) (Expression, error) {
@@ -68,38 +280,86 @@ func (p *parser) parse(variableCollector VariableSet, isRoot bool /*Sorry, Uncle
 		value := tok.literal == "1"
 		return &LiteralExpression{value: value}, nil
 	case TokenVariable:
+		if p.library.isCall(p.tokens, p.pos) {
+			return p.parseCircuit(variableCollector, isRoot)
+		}
+		if value, ok := p.scope[tok.literal]; ok {
+			p.used[tok.literal] = true
+			return value, nil
+		}
+		if d, ok := p.defined[tok.literal]; ok {
+			maps.Copy(variableCollector, d.variables)
+			return d.expression, nil
+		}
 		variableCollector[tok.literal] = struct{}{}
 		return &VariableExpression{variableName: tok.literal}, nil
+	case TokenSlice:
+		name, low, high, err := parseBusIndex(tok.literal)
+		if err != nil {
+			return nil, err
+		}
+		for i := low; i <= high; i++ {
+			variableCollector[bitName(name, i)] = struct{}{}
+		}
+		return &BusVariableExpression{name: name, low: low, high: high}, nil
 	case TokenNot:
-		exprs, err := p.parseArgs(1, variableCollector, isRoot)
+		exprs, err := p.parseArgs(gateInputs[tok.tokenType], variableCollector, isRoot)
 		if err != nil {
 			return nil, argsError(tok, err)
 		}
 		return &NotExpression{expression: exprs[0]}, nil
 	case TokenNand, TokenAnd, TokenOr, TokenXor:
-		exprs, err := p.parseArgs(2, variableCollector, isRoot)
+		exprs, err := p.parseArgs(gateInputs[tok.tokenType], variableCollector, isRoot)
 		if err != nil {
 			return nil, argsError(tok, err)
 		}
 		return &BinaryExpression{tok.tokenType, exprs}, nil
 	case TokenMux:
-		exprs, err := p.parseArgs(3, variableCollector, isRoot)
+		exprs, err := p.parseArgs(gateInputs[tok.tokenType], variableCollector, isRoot)
 		if err != nil {
 			return nil, argsError(tok, err)
 		}
 		return &MuxExpression{exprs}, nil
 	case TokenDmux:
-		exprs, err := p.parseArgs(2, variableCollector, isRoot)
+		exprs, err := p.parseArgs(gateInputs[tok.tokenType], variableCollector, isRoot)
 		if err != nil {
 			return nil, argsError(tok, err)
 		}
 		return &DmuxExpression{exprs}, nil
+	case TokenNot16, TokenAnd16, TokenOr16, TokenXor16, TokenNand16, TokenMux16, TokenDmux16,
+		TokenOr8Way, TokenMux4Way16, TokenMux8Way16, TokenDmux4Way, TokenDmux8Way:
+		exprs, err := p.parseArgs(gateInputs[tok.tokenType], variableCollector, isRoot)
+		if err != nil {
+			return nil, argsError(tok, err)
+		}
+		return &BusGateExpression{tok.tokenType, exprs}, nil
+	case TokenDff, TokenBit, TokenRegister:
+		label, err := p.parseStateLabel()
+		if err != nil {
+			return nil, argsError(tok, err)
+		}
+		exprs, err := p.parseArgs(gateInputs[tok.tokenType], variableCollector, isRoot)
+		if err != nil {
+			return nil, argsError(tok, err)
+		}
+		expr := newStateExpression(tok.tokenType, exprs, label, p.stateBits)
+		if label == "" {
+			p.stateBits += len(expr.state)
+		}
+		for _, name := range expr.state {
+			variableCollector[name] = struct{}{}
+		}
+		return expr, nil
+	case TokenLet:
+		return p.parseLet(variableCollector, isRoot)
 	default:
 		errorString := fmt.Sprintf("invalid token type: %v", tok)
 		return nil, errors.New(errorString)
 	}
 }
 
+var errTrailingTokens = errors.New("tokens found after root gate expression ended. Remove text following the root expression's closing paran")
+
 func argsError(tok Token, err error) error {
 	return fmt.Errorf("error parsing arguments for %s gate: %w", tok.literal, err)
 }
@@ -137,12 +397,100 @@ func (p *parser) parseArgs(expectedInputs int, variableCollector VariableSet, is
 	}
 
 	if isRoot && p.pos != len(p.tokens)-1 {
-		return nil, errors.New("tokens found after root gate expression ended. Remove text following the root expression's closing paran")
+		p.pos++ // the error is about the first token that follows
+		return nil, errTrailingTokens
 	}
 
 	return result, nil
 }
 
+// parseLet parses let s = value, t = value in body. Each name can be used in the values that follow it and in the body.
+func (p *parser) parseLet(variableCollector VariableSet, isRoot bool) (Expression, error) {
+	names := []string{}
+	for {
+		if err := p.expect(TokenVariable); err != nil {
+			return nil, fmt.Errorf("error parsing let binding: %w", err)
+		}
+		name := p.tokens[p.pos].literal
+		if strings.Contains(name, "[") || name == "in" {
+			return nil, fmt.Errorf("%s can't be bound with let", name)
+		}
+		if slices.Contains(names, name) {
+			return nil, fmt.Errorf("%s is bound more than once in the same let", name)
+		}
+		if _, ok := p.scope[name]; ok {
+			return nil, fmt.Errorf("let binding of %s shadows an outer binding of the same name", name)
+		}
+		if err := p.expect(TokenEquals); err != nil {
+			return nil, fmt.Errorf("error parsing let binding of %s: %w", name, err)
+		}
+		value, err := p.parse(variableCollector, false)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing let binding of %s: %w", name, err)
+		}
+		p.scope[name] = value
+		delete(p.used, name) // the name may have been bound and used in a sibling scope before
+		p.wires = append(p.wires, Wire{Name: name, Expression: value})
+		names = append(names, name)
+
+		p.pos++
+		if p.pos >= len(p.tokens) {
+			return nil, fmt.Errorf("expected , or in after the value of %s, but reached end of string", name)
+		}
+		if tok := p.tokens[p.pos]; tok.tokenType == TokenVariable && tok.literal == "in" {
+			break
+		} else if tok.tokenType != TokenComma {
+			return nil, fmt.Errorf("expected , or in after the value of %s, but found %v at %s^", name, tok.literal, tokenString(p.tokens, p.pos))
+		}
+	}
+
+	body, err := p.parse(variableCollector, isRoot)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if !p.used[name] {
+			return nil, fmt.Errorf("%s is bound with let but never used", name)
+		}
+		delete(p.scope, name)
+	}
+	return body, nil
+}
+
+// parseCircuit parses a call of a circuit of the library, which is replaced by the expression of the circuit
+func (p *parser) parseCircuit(variableCollector VariableSet, isRoot bool) (Expression, error) {
+	tok := p.tokens[p.pos]
+	c, err := p.library.resolve(tok.literal)
+	if err != nil {
+		return nil, err
+	}
+	exprs, err := p.parseArgs(len(c.bits), variableCollector, isRoot)
+	if err != nil {
+		return nil, argsError(tok, err)
+	}
+	return c.instantiate(tok.literal, exprs)
+}
+
+// parseStateLabel parses the optional name of a sequential gate, as in dff:q(in)
+func (p *parser) parseStateLabel() (string, error) {
+	if p.pos+1 >= len(p.tokens) || p.tokens[p.pos+1].tokenType != TokenColon {
+		return "", nil
+	}
+	p.pos++
+	if err := p.expect(TokenVariable); err != nil {
+		return "", err
+	}
+	label := p.tokens[p.pos].literal
+	if strings.Contains(label, "[") {
+		return "", fmt.Errorf("state name %s must not be indexed", label)
+	}
+	if _, exists := p.labels[label]; exists {
+		return "", fmt.Errorf("state name %s is used by more than one gate", label)
+	}
+	p.labels[label] = struct{}{}
+	return label, nil
+}
+
 func (p *parser) expect(expected TokenType) error {
 	p.pos++
 	if p.pos >= len(p.tokens) {
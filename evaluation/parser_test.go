@@ -1,6 +1,7 @@
 package evaluation
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 )
@@ -260,3 +261,144 @@ func TestParsingAndEvaluation(t *testing.T) {
 		})
 	}
 }
+
+func TestLet(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  string // the expression with every wire replaced by its value
+		variables []string
+		wires     []string
+	}{
+		{"let s = xor(a, b) in and(s, or(s, c))", "and(xor(a,b),or(xor(a,b),c))", []string{"a", "b", "c"}, []string{"s"}},
+		{"let s = xor(a,b), t = and(s,c) in or(t, s)", "or(and(xor(a,b),c),xor(a,b))", []string{"a", "b", "c"}, []string{"s", "t"}},
+		{"and(let s = not(a) in s, let t = not(b) in t)", "and(not(a),not(b))", []string{"a", "b"}, []string{"s", "t"}},
+		{"let\n  s = and(in, load)\nin\n  or(s, in)", "or(and(in,load),in)", []string{"in", "load"}, []string{"s"}},
+		{"let d = dmux(a, sel) in or(d)", "or(dmux(a,sel))", []string{"a", "sel"}, []string{"d"}},
+		{"let s = 1 in not(s)", "not(1)", []string{}, []string{"s"}},
+		{"let w = a[0..15] in not16(and16(w, w))", "not16(and16(a[0..15],a[0..15]))", nil, []string{"w"}},
+		// the same name in sibling scopes, numbered to tell the wires apart
+		{"and(let t = a in not(t), let t = b in not(t))", "and(not(a),not(b))", []string{"a", "b"}, []string{"t", "t2"}},
+		{"or(or(let t = a in not(t), let t = b in t), or(let t = c in t, t2))", "or(or(not(a),b),or(c,t2))", nil, []string{"t", "t3", "t4"}},
+	}
+	for _, tc := range tests {
+		expr, vars, wires, err := ParseExpressionWithWires(tc.input)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %v", tc.input, err)
+		}
+		expected, expectedVars, _ := ParseExpression(tc.expected)
+		verifyEquality(t, Format(expr), Format(expected))
+		if tc.variables != nil {
+			if !reflect.DeepEqual(getVarsSlice(vars), tc.variables) {
+				t.Errorf("variables of %q are %v, expected %v", tc.input, getVarsSlice(vars), tc.variables)
+			}
+		} else {
+			verifyEquality(t, len(vars), len(expectedVars))
+		}
+		names := []string{}
+		for _, w := range wires {
+			names = append(names, w.Name)
+		}
+		if !reflect.DeepEqual(names, tc.wires) {
+			t.Errorf("wires of %q are %v, expected %v", tc.input, names, tc.wires)
+		}
+	}
+
+	// every use of a wire is the same node
+	expr, _, _ := ParseExpression("let s = dff(a) in xor(s, s)")
+	xor := expr.(*BinaryExpression)
+	if xor.expressions[0] != xor.expressions[1] {
+		t.Errorf("uses of a wire aren't shared")
+	}
+	verifyEquality(t, len(stateExpressions(expr)), 1)
+}
+
+func TestLetErrors(t *testing.T) {
+	testCases := []string{
+		"let s = a in let s = b in and(s, s)",      // shadowing
+		"and(let s = a in not(s), let s = b in c)", // unused, although a sibling binding of s is used
+		"let s = a, s = b in s",                    // bound twice in the same let
+		"let s = a in b",                           // never used
+		"and(let s = a in s, s)",                   // used outside of its scope
+		"let s = and(s, a) in s",                   // used in its own value
+		"let a = b in and(a[0], a)",                // bus bit of a wire name
+		"let q = a in dff:q(q)",                    // sequential gate name
+		"let s[0] = a in s[0]",
+		"let in = a in in",
+		"let s = a",
+		"let s = a and(s, s)",
+		"let s a in s",
+		"let s = a in s b",
+		"let = a in a",
+	}
+	for _, tc := range testCases {
+		if _, _, err := ParseExpression(tc); err == nil {
+			t.Errorf("expected error for %q", tc)
+		}
+	}
+}
+
+func TestGates(t *testing.T) {
+	gates := Gates()
+	verifyEquality(t, len(gates), len(keywords))
+	verifyEquality(t, gates[0], "and")
+	for _, name := range gates {
+		if _, ok := GateInputs(name); !ok {
+			t.Errorf("no inputs for gate %v", name)
+		}
+	}
+	inputs, ok := GateInputs("mux")
+	verifyEquality(t, ok, true)
+	verifyEquality(t, inputs, 3)
+	_, ok = GateInputs("let")
+	verifyEquality(t, ok, false)
+}
+
+func TestGateSignature(t *testing.T) {
+	for _, name := range Gates() {
+		parameters, _ := GateParameters(name)
+		inputs, _ := GateInputs(name)
+		width := 0
+		for _, p := range parameters {
+			_, low, high, err := parseBusIndex(p)
+			if err != nil {
+				width++ // a single bit
+			} else {
+				width += high - low + 1
+			}
+		}
+		verifyEquality(t, width, inputs)
+	}
+	signature, _ := GateSignature("mux")
+	verifyEquality(t, signature, "mux(a, b, sel)")
+	_, ok := GateSignature("foo")
+	verifyEquality(t, ok, false)
+}
+
+func TestSyntaxErrorRange(t *testing.T) {
+	testCases := []struct {
+		text       string
+		start, end int
+	}{
+		{"and(a, b) $", 10, 11},
+		{"and(a, ✓)", 7, 10},
+		{"and(a[1..x], b)", 4, 10},
+		{"and(a, b c)", 9, 10},
+		{"and(a,", 6, 6},
+		{"", 0, 0},
+		{"a b", 0, 1},
+		{"and(a, b) or(c, d)", 10, 18},
+		{"let s = a in and(s, q, r)", 21, 22},
+		{"let s = a b in s", 10, 11},
+	}
+	for _, tc := range testCases {
+		_, _, err := ParseExpression(tc.text)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("%q: expected a syntax error, got %v", tc.text, err)
+			continue
+		}
+		if syntaxErr.Start != tc.start || syntaxErr.End != tc.end {
+			t.Errorf("%q: error %q is at %d..%d, expected %d..%d", tc.text, err, syntaxErr.Start, syntaxErr.End, tc.start, tc.end)
+		}
+	}
+}
@@ -0,0 +1,458 @@
+package evaluation
+
+import (
+	"fmt"
+)
+
+// Restrict substitutes known values for some of the variables of an expression (its cofactor) and
+// folds the constants through the gates, e.g. mux(a, b, 1) becomes a and and(x, 0) becomes 0.
+// Gates whose outputs fall apart into independent values, like dmux(a, 1) into a and 0, are split up,
+// so the residual is a list of parts whose outputs, in order, are the outputs of the expression. It has
+// a single part unless the outputs of the root fall apart. Identical nodes of the residual are shared.
+func Restrict(expr Expression, values map[string]bool) []Expression {
+	r := restriction{values: values, done: map[Expression][]Expression{}, shared: newHashConser()}
+	return r.restrict(expr)
+}
+
+// RestrictExpression parses an expression and restricts it. It returns the parts of the residual expression and
+// their variables.
+func RestrictExpression(expression string, values map[string]bool) ([]Expression, VariableSet, error) {
+	expr, vars, err := ParseExpression(expression)
+	if err != nil {
+		return nil, nil, err
+	}
+	return restrictParsed(expr, vars, values)
+}
+
+// RestrictExpression is like the function of the same name, for an expression that can call the circuits of
+// the library
+func (l *Library) RestrictExpression(expression string, values map[string]bool) ([]Expression, VariableSet, error) {
+	expr, vars, _, err := l.ParseExpression(expression)
+	if err != nil {
+		return nil, nil, err
+	}
+	return restrictParsed(expr, vars, values)
+}
+
+func restrictParsed(expr Expression, vars VariableSet, values map[string]bool) ([]Expression, VariableSet, error) {
+	for name := range values {
+		if _, ok := vars[name]; !ok {
+			return nil, nil, fmt.Errorf("%v is not a variable of the expression", name)
+		}
+	}
+	parts := Restrict(expr, values)
+	residualVars := VariableSet{}
+	for _, part := range parts {
+		partVars, err := collectVariables(part)
+		if err != nil {
+			return nil, nil, err
+		}
+		for name := range partVars {
+			residualVars[name] = struct{}{}
+		}
+	}
+	for name := range values {
+		if _, ok := residualVars[name]; ok {
+			// only the state of a sequential gate is kept when part of it is assigned
+			return nil, nil, fmt.Errorf("%v can only be assigned together with the rest of the state of its gate", name)
+		}
+	}
+	return parts, residualVars, nil
+}
+
+// ComputeParts computes the truth table of the parts of a residual expression, with the outputs of each part
+// after those of the previous one
+func ComputeParts(parts []Expression, vars VariableSet) (*Result, error) {
+	var result *Result
+	widths := []int{}
+	buses := false
+	for _, part := range parts {
+		partResult, err := ComputeExpression(part, vars)
+		if err != nil {
+			return nil, err
+		}
+		if partResult.OutputWidths == nil {
+			for range part.NumOutputs() {
+				widths = append(widths, 1)
+			}
+		} else {
+			widths = append(widths, partResult.OutputWidths...)
+			buses = true
+		}
+		if result == nil {
+			result = partResult
+			continue
+		}
+		for row := range result.Outputs {
+			result.Outputs[row] = append(result.Outputs[row], partResult.Outputs[row]...)
+		}
+	}
+	result.OutputWidths = nil
+	if buses {
+		result.OutputWidths = widths
+	}
+	return result, nil
+}
+
+// restriction restricts the nodes of an expression once each, since nodes bound with let are shared, and
+// hash-conses the nodes it builds, so identical inputs of a gate are the same node
+type restriction struct {
+	values map[string]bool
+	done   map[Expression][]Expression
+	shared *hashConser
+}
+
+// restrict returns the restricted expression, split into parts when the outputs no longer belong to one gate
+func (r restriction) restrict(expr Expression) []Expression {
+	if parts, ok := r.done[expr]; ok {
+		return parts
+	}
+	parts := r.fold(expr)
+	for i, part := range parts {
+		parts[i] = r.shared.share(part)
+	}
+	r.done[expr] = parts
+	return parts
+}
+
+// fold restricts a node whose inputs haven't been restricted yet
+func (r restriction) fold(expr Expression) []Expression {
+	switch e := expr.(type) {
+	case *LiteralExpression:
+		return []Expression{e}
+	case *VariableExpression:
+		if val, ok := r.values[e.variableName]; ok {
+			return []Expression{&LiteralExpression{value: val}}
+		}
+		return []Expression{e}
+	case *BusVariableExpression:
+		bits := []Expression{}
+		for i := e.low; i <= e.high; i++ {
+			bits = append(bits, r.restrict(&VariableExpression{variableName: bitName(e.name, i)})...)
+		}
+		return compactBits(bits)
+	case *NotExpression:
+		parts, in := r.inputs([]Expression{e.expression})
+		if in == nil {
+			return []Expression{withInputs(e, parts)}
+		}
+		return []Expression{foldNot(in[0])}
+	case *BinaryExpression:
+		parts, in := r.inputs(e.expressions)
+		if in == nil {
+			return []Expression{withInputs(e, parts)}
+		}
+		return []Expression{foldBinary(e.op, in[0], in[1])}
+	case *MuxExpression:
+		parts, in := r.inputs(e.expressions)
+		if in == nil {
+			return []Expression{withInputs(e, parts)}
+		}
+		return []Expression{foldMux(in[0], in[1], in[2])}
+	case *DmuxExpression:
+		parts, in := r.inputs(e.expressions)
+		if in != nil {
+			if sel, ok := literalValue(in[1]); ok {
+				zero := &LiteralExpression{value: false}
+				if sel {
+					return []Expression{zero, in[0]}
+				}
+				return []Expression{in[0], zero}
+			}
+			if val, ok := literalValue(in[0]); ok && !val {
+				return []Expression{in[0], in[0]}
+			}
+		}
+		return []Expression{withInputs(e, parts)}
+	case *BusGateExpression:
+		parts, in := r.inputs(e.expressions)
+		if in != nil {
+			if folded := foldBusGate(e.op, in); folded != nil {
+				return compactBits(folded)
+			}
+		}
+		return []Expression{withInputs(e, parts)}
+	case *StateExpression:
+		state := []Expression{}
+		for _, name := range e.state {
+			val, ok := r.values[name]
+			if !ok {
+				parts, _ := r.inputs(e.expressions)
+				return []Expression{withInputs(e, parts)}
+			}
+			state = append(state, &LiteralExpression{value: val})
+		}
+		return state
+	default:
+		panic(fmt.Sprintf("restriction of %T not implemented", expr))
+	}
+}
+
+// inputs restricts the arguments of a gate. It returns them as parts for rebuilding the gate, and
+// as one expression per input bit when none of them has multiple outputs (nil otherwise).
+func (r restriction) inputs(expressions []Expression) ([]Expression, []Expression) {
+	parts := []Expression{}
+	for _, expr := range expressions {
+		parts = append(parts, r.restrict(expr)...)
+	}
+	bits := []Expression{}
+	for _, part := range parts {
+		switch p := part.(type) {
+		case *BusVariableExpression:
+			for i := p.low; i <= p.high; i++ {
+				bits = append(bits, r.shared.share(&VariableExpression{variableName: bitName(p.name, i)}))
+			}
+		default:
+			if part.NumOutputs() != 1 {
+				return compactBits(parts), nil
+			}
+			bits = append(bits, part)
+		}
+	}
+	return compactBits(parts), bits
+}
+
+// withInputs copies a gate with new arguments
+func withInputs(expr Expression, expressions []Expression) Expression {
+	switch e := expr.(type) {
+	case *NotExpression:
+		return &NotExpression{expression: expressions[0]}
+	case *BinaryExpression:
+		return &BinaryExpression{op: e.op, expressions: expressions}
+	case *MuxExpression:
+		return &MuxExpression{expressions: expressions}
+	case *DmuxExpression:
+		return &DmuxExpression{expressions: expressions}
+	case *BusGateExpression:
+		return &BusGateExpression{op: e.op, expressions: expressions}
+	case *StateExpression:
+		return &StateExpression{op: e.op, expressions: expressions, label: e.label, state: e.state}
+	}
+	return expr
+}
+
+// compactBits joins consecutive bits of the same bus back into slices
+func compactBits(parts []Expression) []Expression {
+	result := []Expression{}
+	for _, part := range parts {
+		bus, index, isBit := "", 0, false
+		if v, ok := part.(*VariableExpression); ok {
+			bus, index, isBit = splitBitName(v.variableName)
+		}
+		if len(result) > 0 && isBit {
+			switch last := result[len(result)-1].(type) {
+			case *VariableExpression:
+				if lastBus, lastIndex, ok := splitBitName(last.variableName); ok && lastBus == bus && lastIndex+1 == index {
+					result[len(result)-1] = &BusVariableExpression{name: bus, low: lastIndex, high: index}
+					continue
+				}
+			case *BusVariableExpression:
+				if last.name == bus && last.high+1 == index {
+					result[len(result)-1] = &BusVariableExpression{name: bus, low: last.low, high: index}
+					continue
+				}
+			}
+		}
+		result = append(result, part)
+	}
+	return result
+}
+
+func literalValue(expr Expression) (bool, bool) {
+	if l, ok := expr.(*LiteralExpression); ok {
+		return l.value, true
+	}
+	return false, false
+}
+
+func foldNot(a Expression) Expression {
+	if val, ok := literalValue(a); ok {
+		return &LiteralExpression{value: !val}
+	}
+	if not, ok := a.(*NotExpression); ok && not.expression.NumOutputs() == 1 {
+		return not.expression
+	}
+	return &NotExpression{expression: a}
+}
+
+func foldBinary(op TokenType, a, b Expression) Expression {
+	valA, constA := literalValue(a)
+	valB, constB := literalValue(b)
+	if constA && constB {
+		return &LiteralExpression{value: binaryGate(op, valA, valB)}
+	}
+	if constB {
+		a, b, valA, constA = b, a, valB, constB
+	}
+	if constA {
+		switch {
+		case op == TokenAnd && !valA, op == TokenOr && valA:
+			return a
+		case op == TokenNand && !valA:
+			return &LiteralExpression{value: true}
+		case op == TokenXor && valA, op == TokenNand:
+			return foldNot(b)
+		default:
+			// and(1, x), or(0, x) and xor(0, x)
+			return b
+		}
+	}
+	if a == b {
+		switch op {
+		case TokenXor:
+			return &LiteralExpression{value: false}
+		case TokenNand:
+			return foldNot(a)
+		}
+		return a
+	}
+	return &BinaryExpression{op: op, expressions: []Expression{a, b}}
+}
+
+func binaryGate(op TokenType, a, b bool) bool {
+	switch op {
+	case TokenNand:
+		return Nand(a, b)
+	case TokenAnd:
+		return And(a, b)
+	case TokenOr:
+		return Or(a, b)
+	case TokenXor:
+		return Xor(a, b)
+	}
+	panic(fmt.Sprintf("binary gate %d not implemented", op))
+}
+
+func foldMux(a, b, sel Expression) Expression {
+	if val, ok := literalValue(sel); ok {
+		if val {
+			return a
+		}
+		return b
+	}
+	if a == b {
+		return a
+	}
+	valA, constA := literalValue(a)
+	valB, constB := literalValue(b)
+	switch {
+	case constA && constB:
+		// mux(1, 0, s) and mux(0, 1, s)
+		if valA {
+			return sel
+		}
+		return foldNot(sel)
+	case constB && !valB:
+		return foldBinary(TokenAnd, a, sel)
+	case constA && valA:
+		return foldBinary(TokenOr, sel, b)
+	}
+	return &MuxExpression{expressions: []Expression{a, b, sel}}
+}
+
+// foldBusGate simplifies a bus gate given one expression per input bit. It returns one expression
+// per output bit, or nil when the gate stays. Bitwise gates are only split up when every output bit
+// becomes a constant or a variable, so restricting doesn't grow the expression.
+func foldBusGate(op TokenType, in []Expression) []Expression {
+	literals := 0
+	for _, e := range in {
+		if _, ok := literalValue(e); ok {
+			literals++
+		}
+	}
+	if literals == len(in) {
+		values := []bool{}
+		for _, e := range in {
+			val, _ := literalValue(e)
+			values = append(values, val)
+		}
+		result := []Expression{}
+		for _, val := range evaluateBusGate(op, values) {
+			result = append(result, &LiteralExpression{value: val})
+		}
+		return result
+	}
+
+	// selectIndex reads the select inputs starting at position from, or returns -1 if they aren't constant
+	selectIndex := func(from int) int {
+		index := 0
+		for i, e := range in[from:] {
+			val, ok := literalValue(e)
+			if !ok {
+				return -1
+			}
+			if val {
+				index |= 1 << i
+			}
+		}
+		return index
+	}
+
+	switch op {
+	case TokenMux16, TokenMux4Way16, TokenMux8Way16:
+		buses := gateInputs[op] / 16
+		index := selectIndex(16 * buses)
+		if index < 0 {
+			return nil
+		}
+		if op == TokenMux16 {
+			// mux16 picks its first input when sel is 1
+			index = 1 - index
+		}
+		return in[16*index : 16*(index+1)]
+	case TokenDmux16, TokenDmux4Way, TokenDmux8Way:
+		width := 1
+		if op == TokenDmux16 {
+			width = 16
+		}
+		index := selectIndex(width)
+		if index < 0 {
+			return nil
+		}
+		result := []Expression{}
+		for i := 0; i < busGates[op]/width; i++ {
+			for bit := 0; bit < width; bit++ {
+				if i == index {
+					result = append(result, in[bit])
+				} else {
+					result = append(result, &LiteralExpression{value: false})
+				}
+			}
+		}
+		return result
+	case TokenOr8Way:
+		remaining := []Expression{}
+		for _, e := range in {
+			val, ok := literalValue(e)
+			if ok && val {
+				return []Expression{e}
+			}
+			if !ok {
+				remaining = append(remaining, e)
+			}
+		}
+		if len(remaining) == 1 {
+			return remaining
+		}
+		return nil
+	case TokenNot16, TokenAnd16, TokenOr16, TokenXor16, TokenNand16:
+		ops := map[TokenType]TokenType{TokenAnd16: TokenAnd, TokenOr16: TokenOr, TokenXor16: TokenXor, TokenNand16: TokenNand}
+		result := []Expression{}
+		for i := 0; i < 16; i++ {
+			var bit Expression
+			if op == TokenNot16 {
+				bit = foldNot(in[i])
+			} else {
+				bit = foldBinary(ops[op], in[i], in[16+i])
+			}
+			switch bit.(type) {
+			case *LiteralExpression, *VariableExpression:
+				result = append(result, bit)
+			default:
+				return nil
+			}
+		}
+		return result
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package evaluation
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRestrict(t *testing.T) {
+	tests := []struct {
+		expression string
+		values     map[string]bool
+		expected   string
+	}{
+		{"mux(a,b,s)", map[string]bool{"s": true}, "a"},
+		{"mux(a,b,s)", map[string]bool{"s": false}, "b"},
+		{"mux(a,b,s)", map[string]bool{"a": true, "b": false}, "s"},
+		{"mux(a,b,s)", map[string]bool{"a": false, "b": true}, "not(s)"},
+		{"mux(a,b,s)", map[string]bool{"b": false}, "and(a, s)"},
+		{"and(x,y)", map[string]bool{"y": false}, "0"},
+		{"and(x,y)", map[string]bool{"x": true}, "y"},
+		{"or(x,y)", map[string]bool{"x": true}, "1"},
+		{"xor(x,y)", map[string]bool{"x": true}, "not(y)"},
+		{"nand(x,y)", map[string]bool{"y": false}, "1"},
+		{"nand(x,y)", map[string]bool{"y": true}, "not(x)"},
+		{"xor(and(a,b),and(b,a))", map[string]bool{"a": true}, "0"},
+		{"not(not(and(a,c)))", map[string]bool{"c": true}, "a"},
+		{"or(dmux(a,s))", map[string]bool{"s": true}, "a"},
+		{"and(dmux(a,s))", map[string]bool{"a": false}, "0"},
+		// the outputs of the root fall apart into parts
+		{"dmux(a,s)", map[string]bool{"s": true}, "0, a"},
+		{"dmux(and(a,b),s)", map[string]bool{"s": true, "b": true}, "0, a"},
+		{"dmux8way(x,s[0..2])", map[string]bool{"s[0]": true, "s[1]": false, "s[2]": true}, "0, 0, 0, 0, 0, x, 0, 0"},
+		{"mux16(a[0..15],b[0..15],s)", map[string]bool{"s": false, "b[3]": true}, "b[0..2], 1, b[4..15]"},
+		{"a[0..15]", map[string]bool{"a[0]": false}, "0, a[1..15]"},
+		{"a[0..3]", map[string]bool{"a[1]": true}, "a[0], 1, a[2..3]"},
+		{"and(dmux(a,s))", map[string]bool{}, "and(dmux(a, s))"},
+		{"or8way(a[0..7])", map[string]bool{"a[3]": false, "a[5]": true}, "1"},
+		{"or8way(a[0..7])", map[string]bool{"a[0]": false, "a[1]": false, "a[2]": false, "a[3]": false, "a[4]": false, "a[5]": false, "a[7]": false}, "a[6]"},
+		{"or8way(a[0..7])", map[string]bool{"a[3]": false}, "or8way(a[0..2], 0, a[4..7])"},
+		{"mux16(a[0..15],b[0..15],s)", map[string]bool{"s": false}, "b[0..15]"},
+		{"mux4way16(a[0..15],b[0..15],c[0..15],d[0..15],s[0..1])", map[string]bool{"s[0]": false, "s[1]": true}, "c[0..15]"},
+		{"and16(a[0..15],b[0..15])", map[string]bool{"b[0]": true}, "and16(a[0..15], 1, b[1..15])"},
+		{"or8way(dmux8way(x,s[0..2]))", map[string]bool{"s[0]": true, "s[1]": false, "s[2]": true}, "x"},
+		{"or8way(dmux4way(x,s[0..1]),dmux4way(y,s[0..1]))", map[string]bool{"s[0]": true, "s[1]": false}, "or8way(0, x, 0, 0, 0, y, 0, 0)"},
+		{"and(dff(a),b)", map[string]bool{"$q0": true}, "b"},
+		{"and(dff(a),b)", map[string]bool{"b": true}, "dff(a)"},
+	}
+	for _, tc := range tests {
+		expr, _, err := ParseExpression(tc.expression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		verifyEquality(t, formatRestricted(Restrict(expr, tc.values)), tc.expected)
+	}
+}
+
+// The residual expression must agree with the original for every completion of the partial assignment
+func TestRestrictMatchesEvaluation(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	expressions := append([]string{
+		"mux(xor(a,b),nand(a,c),or(b,c))",
+		"dmux(mux(a,b,c),xor(a,c))",
+		"or(and(a,not(b)),mux(c,a,b))",
+	}, busExpressions...)
+	for _, input := range expressions {
+		expr, vars, err := ParseExpression(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 50; i++ {
+			values := map[string]bool{}
+			args := map[string]bool{}
+			for v := range vars {
+				args[v] = rng.Intn(2) == 1
+				if rng.Intn(2) == 1 {
+					values[v] = args[v]
+				}
+			}
+			parts := Restrict(expr, values)
+			expected, err := expr.Evaluate(args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []bool{}
+			for _, part := range parts {
+				outputs, err := part.Evaluate(args)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, outputs...)
+			}
+			if !reflect.DeepEqual(got, expected) {
+				t.Fatalf("%v restricted with %v to %v evaluates to %v instead of %v", input, values, formatRestricted(parts), got, expected)
+			}
+		}
+	}
+}
+
+func TestRestrictExpression(t *testing.T) {
+	parts, vars, err := RestrictExpression("mux(a,and(b,c),s)", map[string]bool{"s": false, "c": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifyEquality(t, formatRestricted(parts), "b")
+	verifyEquality(t, len(vars), 1)
+
+	result, err := ComputeParts(parts, vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifyEquality(t, len(result.Assignments), 2)
+
+	if _, _, err := RestrictExpression("and(a,b)", map[string]bool{"c": true}); err == nil {
+		t.Errorf("expected error restricting a variable that isn't used")
+	}
+
+	// the residual of a bus slice doesn't keep the assigned bits, and its parts make up the outputs
+	parts, vars, err = RestrictExpression("a[0..3]", map[string]bool{"a[1]": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifyEquality(t, len(vars), 3)
+	result, err = ComputeParts(parts, vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifyEquality(t, result.Table(RadixBinary), "a[3..2]\ta[0]\tOutput\n"+
+		"00\t0\t0  1  00\n00\t1\t1  1  00\n01\t0\t0  1  01\n01\t1\t1  1  01\n"+
+		"10\t0\t0  1  10\n10\t1\t1  1  10\n11\t0\t0  1  11\n11\t1\t1  1  11\n")
+}
+
+// Nodes bound with let are restricted once, however often they are used
+func TestRestrictSharedNodes(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("let w0 = and(z, a)")
+	for i := 1; i <= 40; i++ {
+		fmt.Fprintf(&sb, ", w%d = xor(w%d, not(w%d))", i, i-1, i-1)
+	}
+	sb.WriteString(" in w40")
+	for _, values := range []map[string]bool{{"z": true}, {"a": true}} {
+		parts, _, err := RestrictExpression(sb.String(), values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// and(z, a) folds away, leaving a not and a xor per wire
+		verifyEquality(t, len(parts), 1)
+		verifyEquality(t, NewDAG(parts[0]).NumGates(), 80)
+	}
+}
+
+func formatRestricted(parts []Expression) string {
+	formatted := []string{}
+	for _, part := range parts {
+		formatted = append(formatted, Format(part))
+	}
+	return strings.Join(formatted, ", ")
+}
@@ -0,0 +1,107 @@
+package evaluation
+
+import (
+	"errors"
+	"fmt"
+)
+
+// errStop ends ForEachRow early once a search has found what it was looking for
+var errStop = errors.New("stop")
+
+// ForEachRow evaluates the expression for every row of its truth table, in the order of Compute, without keeping
+// the rows in memory. The assignment holds the values of the variables in the order of Result.Variables. An error
+// returned by fn stops the iteration and is returned.
+func ForEachRow(expr Expression, vars VariableSet, fn func(assignment, outputs []bool) error) error {
+	return ForEachRowWith(expr, vars, ComputeOptions{}, fn)
+}
+
+// ForEachRowWith is ForEachRow with options, like a larger limit on the input bits
+func ForEachRowWith(expr Expression, vars VariableSet, opts ComputeOptions, fn func(assignment, outputs []bool) error) error {
+	variables := getVarsSlice(vars)
+	if err := opts.check(variables); err != nil {
+		return err
+	}
+	dag := NewDAG(expr)
+	for i := 0; i < 1<<len(variables); i++ {
+		assignment := combination(i, len(variables))
+		outputs, err := dag.Evaluate(getArgs(variables, assignment))
+		if err != nil {
+			return err
+		}
+		if err := fn(assignment, outputs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Variables lists the variables in the order of the columns of a truth table, bus bits from the most significant
+func Variables(vars VariableSet) []string {
+	return getVarsSlice(vars)
+}
+
+// Satisfy looks for an assignment of the variables for which an output of the expression is 1. It returns the first
+// one in truth table order, and false if the expression is always 0.
+func Satisfy(expr Expression, vars VariableSet) (map[string]bool, bool, error) {
+	return SatisfyWith(expr, vars, ComputeOptions{})
+}
+
+// SatisfyWith is Satisfy with options, like a larger limit on the input bits
+func SatisfyWith(expr Expression, vars VariableSet, opts ComputeOptions) (map[string]bool, bool, error) {
+	variables := getVarsSlice(vars)
+	var found map[string]bool
+	err := ForEachRowWith(expr, vars, opts, func(assignment, outputs []bool) error {
+		for _, out := range outputs {
+			if out {
+				found = getArgs(variables, assignment)
+				return errStop
+			}
+		}
+		return nil
+	})
+	if err != nil && err != errStop {
+		return nil, false, err
+	}
+	return found, found != nil, nil
+}
+
+// Equivalent tells whether two expressions have the same outputs for every assignment of the variables of both.
+// If they don't, it also returns the first assignment where they differ.
+func Equivalent(a Expression, aVars VariableSet, b Expression, bVars VariableSet) (map[string]bool, bool, error) {
+	return EquivalentWith(a, aVars, b, bVars, ComputeOptions{})
+}
+
+// EquivalentWith is Equivalent with options, like a larger limit on the input bits
+func EquivalentWith(a Expression, aVars VariableSet, b Expression, bVars VariableSet, opts ComputeOptions) (map[string]bool, bool, error) {
+	if a.NumOutputs() != b.NumOutputs() {
+		return nil, false, fmt.Errorf("the expressions have %d and %d outputs", a.NumOutputs(), b.NumOutputs())
+	}
+	vars := VariableSet{}
+	for v := range aVars {
+		vars[v] = struct{}{}
+	}
+	for v := range bVars {
+		vars[v] = struct{}{}
+	}
+	variables := getVarsSlice(vars)
+	other := NewDAG(b)
+	var counterexample map[string]bool
+	err := ForEachRowWith(a, vars, opts, func(assignment, outputs []bool) error {
+		args := getArgs(variables, assignment)
+		otherOutputs, err := other.Evaluate(args)
+		if err != nil {
+			return err
+		}
+		for i := range outputs {
+			if outputs[i] != otherOutputs[i] {
+				counterexample = args
+				return errStop
+			}
+		}
+		return nil
+	})
+	if err != nil && err != errStop {
+		return nil, false, err
+	}
+	return counterexample, counterexample == nil, nil
+}
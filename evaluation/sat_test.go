@@ -0,0 +1,108 @@
+package evaluation
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSatisfy(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   map[string]bool // nil when the expression is never 1
+	}{
+		{"and(a, b)", map[string]bool{"a": true, "b": true}},
+		{"or(a, b)", map[string]bool{"a": false, "b": true}},
+		{"and(a, not(a))", nil},
+		{"0", nil},
+		{"1", map[string]bool{}},
+		{"dmux(a, s)", map[string]bool{"a": true, "s": false}},
+		{"and(x[0], not(x[1]))", map[string]bool{"x[0]": true, "x[1]": false}},
+	}
+	for _, tc := range tests {
+		expr, vars, err := ParseExpression(tc.expression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assignment, ok, err := Satisfy(expr, vars)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != (tc.expected != nil) || !reflect.DeepEqual(assignment, tc.expected) {
+			t.Errorf("Satisfy(%s) = %v, %v, expected %v", tc.expression, assignment, ok, tc.expected)
+		}
+	}
+}
+
+func TestEquivalent(t *testing.T) {
+	tests := []struct {
+		a, b           string
+		counterexample map[string]bool // nil when the expressions are equivalent
+	}{
+		{"nand(a, b)", "or(not(a), not(b))", nil},
+		{"xor(a, b)", "or(and(a, not(b)), and(not(a), b))", nil},
+		{"and(a, b)", "a", map[string]bool{"a": true, "b": false}},
+		{"or(a, b)", "or(a, c)", map[string]bool{"a": false, "b": false, "c": true}},
+		{"mux(a, b, 1)", "a", nil},
+		{"dmux(a, s)", "dmux(a, not(s))", map[string]bool{"a": true, "s": false}},
+	}
+	for _, tc := range tests {
+		a, aVars, err := ParseExpression(tc.a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, bVars, err := ParseExpression(tc.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		counterexample, ok, err := Equivalent(a, aVars, b, bVars)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != (tc.counterexample == nil) || !reflect.DeepEqual(counterexample, tc.counterexample) {
+			t.Errorf("Equivalent(%s, %s) = %v, %v, expected %v", tc.a, tc.b, counterexample, ok, tc.counterexample)
+		}
+	}
+
+	a, aVars, _ := ParseExpression("dmux(a, s)")
+	b, bVars, _ := ParseExpression("a")
+	if _, _, err := Equivalent(a, aVars, b, bVars); err == nil {
+		t.Error("expected an error for expressions with different numbers of outputs")
+	}
+}
+
+func TestForEachRowMatchesCompute(t *testing.T) {
+	expressions := []string{
+		"1",
+		"mux(xor(a,b),nand(a,c),or(b,c))",
+		"dmux(mux(a,b,c),xor(a,c))",
+		"or8way(a[8..15])",
+		"or8way(dmux4way(x,s[0..1]),dmux4way(y,s[0..1]))",
+	}
+	for _, input := range expressions {
+		expr, vars, err := ParseExpression(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := ComputeExpression(expr, vars)
+		if err != nil {
+			t.Fatal(err)
+		}
+		row := 0
+		err = ForEachRow(expr, vars, func(assignment, outputs []bool) error {
+			if len(result.Variables) > 0 && !reflect.DeepEqual(assignment, result.Assignments[row]) {
+				t.Errorf("%s: row %d has assignment %v, expected %v", input, row, assignment, result.Assignments[row])
+			}
+			if !reflect.DeepEqual(outputs, result.Outputs[row]) {
+				t.Errorf("%s: row %d has outputs %v, expected %v", input, row, outputs, result.Outputs[row])
+			}
+			row++
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if row != len(result.Outputs) {
+			t.Errorf("%s: %d rows, expected %d", input, row, len(result.Outputs))
+		}
+	}
+}
@@ -0,0 +1,332 @@
+package evaluation
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// A script checks expressions line by line, so that specifications of circuits can be kept in files and run like
+// tests. Every line is one of:
+//
+//	# a comment, which can also follow the other kinds of lines
+//	sum = xor(a, b)                     a definition, used by its name like a let binding
+//	half(x, y) = dmux(xor(x, y), 0)     a definition with the inputs it is called with, like a circuit
+//	and(sum, c)                         an expression, whose truth table is computed
+//	assert equiv(sum, or(a, b))         the expressions have the same outputs for every assignment
+//	assert taut(or(a, not(a)))          every output is 1 for every assignment
+//	assert table(sum) == 0110           the outputs of the truth table, row after row
+//
+// Every definition can be used by name, as in and(sum, c), and called with arguments in place of its inputs, as in
+// sum(p, q). Without a list of inputs, calls take the inputs sorted by name, as circuits of a Library do.
+// Definitions are parsed on their own, so their lets don't clash, and they must be combinational.
+
+// ScriptStep is the outcome of a line of a script that isn't empty or a comment
+type ScriptStep struct {
+	Line      int    // counting from 1
+	Source    string // the line without its comment
+	Assertion bool
+	Table     string // the truth table of an expression line
+	Err       error  // why the line can't be run, or why its assertion doesn't hold
+}
+
+// Failed tells whether the line is an error or an assertion that doesn't hold
+func (s ScriptStep) Failed() bool {
+	return s.Err != nil
+}
+
+// AssertionError is an assertion that was checked and doesn't hold, as opposed to one that can't be checked
+type AssertionError struct {
+	message string
+}
+
+func (e *AssertionError) Error() string {
+	return e.message
+}
+
+func assertionFailed(format string, args ...any) error {
+	return &AssertionError{message: fmt.Sprintf(format, args...)}
+}
+
+type script struct {
+	library *Library                     // the definitions, to call them like gates
+	defined map[string]definedExpression // the definitions, to use them by name
+	lines   map[string]int               // the line of every definition in the script, including later ones
+	line    int                          // the line being run
+}
+
+// RunScript runs every line of a script. Lines that fail don't stop the script, but definitions that fail aren't
+// defined.
+func RunScript(source string) []ScriptStep {
+	s := &script{library: NewLibrary(), defined: map[string]definedExpression{}, lines: map[string]int{}}
+	lines := strings.Split(source, "\n")
+	for i := range lines {
+		lines[i], _, _ = strings.Cut(lines[i], "#")
+		lines[i] = strings.TrimSpace(lines[i])
+		if name, _, _, ok := splitDefinition(lines[i]); ok && !strings.HasPrefix(lines[i], "assert ") {
+			if _, exists := s.lines[name]; !exists {
+				s.lines[name] = i + 1
+			}
+		}
+	}
+
+	steps := []ScriptStep{}
+	for i, line := range lines {
+		if line == "" {
+			continue
+		}
+		s.line = i + 1
+		step := ScriptStep{Line: i + 1, Source: line}
+		if assertion, ok := strings.CutPrefix(line, "assert "); ok {
+			step.Assertion = true
+			step.Err = s.assert(strings.TrimSpace(assertion))
+		} else if name, inputs, expression, ok := splitDefinition(line); ok {
+			step.Err = s.define(name, inputs, expression)
+		} else {
+			step.Table, step.Err = s.table(line)
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+// splitDefinition splits name = expression or name(inputs) = expression
+func splitDefinition(line string) (string, []string, string, bool) {
+	left, expression, found := strings.Cut(line, "=")
+	if !found {
+		return "", nil, "", false
+	}
+	tokens, err := ParseTokens(left)
+	// names of gates are taken, so that defining them is an error rather than an invalid expression
+	if err != nil || len(tokens) == 0 || tokens[0].tokenType != TokenVariable && !tokens[0].tokenType.IsGate() {
+		return "", nil, "", false // like let s = a in s
+	}
+	if len(tokens) == 1 {
+		return tokens[0].literal, nil, strings.TrimSpace(expression), true
+	}
+	// the inputs are between parentheses, separated by commas
+	if len(tokens) < 3 || tokens[1].tokenType != TokenLparan || tokens[len(tokens)-1].tokenType != TokenRparan {
+		return "", nil, "", false
+	}
+	inputs := []string{}
+	for i := 2; i < len(tokens)-1; i++ {
+		expected := TokenComma
+		if i%2 == 0 {
+			expected = TokenVariable
+		}
+		if tokens[i].tokenType != expected && (expected != TokenVariable || tokens[i].tokenType != TokenSlice) {
+			return "", nil, "", false
+		}
+		if expected == TokenVariable {
+			inputs = append(inputs, tokens[i].literal)
+		}
+	}
+	return tokens[0].literal, inputs, strings.TrimSpace(expression), true
+}
+
+func (s *script) define(name string, inputs []string, expression string) error {
+	if line := s.lines[name]; line != s.line {
+		return fmt.Errorf("%s is already defined on line %d", name, line)
+	}
+	if err := checkCircuitName(name); err != nil {
+		return err
+	}
+	expr, vars, _, err := s.parse(expression)
+	if err != nil {
+		return err
+	}
+	if len(stateExpressions(expr)) > 0 {
+		return fmt.Errorf("%s has sequential gates, but definitions must be combinational", name)
+	}
+	if err := s.library.addExpression(name, expr, vars, inputs); err != nil {
+		return err
+	}
+	s.defined[name] = definedExpression{expression: expr, variables: vars}
+	return nil
+}
+
+// parse parses an expression that can use the definitions before the current line. Names that are only defined
+// further down are errors rather than inputs, since they are most likely used too early.
+func (s *script) parse(expression string) (Expression, VariableSet, []Wire, error) {
+	tokens, _ := ParseTokens(expression) // errors are reported when the expression is parsed
+	for _, tok := range tokens {
+		if tok.tokenType != TokenVariable {
+			continue
+		}
+		if line, ok := s.lines[tok.literal]; ok && line > s.line {
+			return nil, nil, nil, fmt.Errorf("%s is defined later, on line %d", tok.literal, line)
+		}
+	}
+	return parseExpression(expression, s.library, s.defined)
+}
+
+func (s *script) table(expression string) (string, error) {
+	expr, vars, _, err := s.parse(expression)
+	if err != nil {
+		return "", err
+	}
+	result, err := ComputeExpression(expr, vars)
+	if err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
+
+func (s *script) assert(assertion string) error {
+	name, args, rest, err := splitCall(assertion)
+	if err != nil {
+		return err
+	}
+	switch name {
+	case "equiv":
+		if rest != "" {
+			return fmt.Errorf("unexpected %q after equiv(...)", rest)
+		}
+		return s.assertEquivalent(args)
+	case "taut":
+		if rest != "" {
+			return fmt.Errorf("unexpected %q after taut(...)", rest)
+		}
+		return s.assertTautology(args)
+	case "table":
+		expected, ok := strings.CutPrefix(rest, "==")
+		if !ok {
+			return errors.New("expected == and the outputs after table(...), like table(f) == 0110")
+		}
+		return s.assertTable(args, expected)
+	default:
+		return fmt.Errorf("unknown assertion %q, expected equiv, taut or table", name)
+	}
+}
+
+// splitCall splits name(args) rest at the parenthesis that closes the arguments
+func splitCall(text string) (string, string, string, error) {
+	name, _, found := strings.Cut(text, "(")
+	if !found {
+		return "", "", "", fmt.Errorf("expected an assertion like equiv(f, g), taut(f) or table(f) == 0110, got %q", text)
+	}
+	depth := 0
+	for i := len(name); i < len(text); i++ {
+		switch text[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return strings.TrimSpace(name), text[len(name)+1 : i], strings.TrimSpace(text[i+1:]), nil
+			}
+		}
+	}
+	return "", "", "", fmt.Errorf("missing ) in %q", text)
+}
+
+func (s *script) assertEquivalent(args string) error {
+	// the expressions can have commas of their own, in let bindings, so the split is where the first one parses
+	var firstErr error
+	for i := range args {
+		if args[i] != ',' {
+			continue
+		}
+		a, aVars, _, err := s.parse(args[:i])
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		b, bVars, _, err := s.parse(args[i+1:])
+		if err != nil {
+			return fmt.Errorf("second expression: %w", err)
+		}
+		counterexample, ok, err := Equivalent(a, aVars, b, bVars)
+		if err != nil || ok {
+			return err
+		}
+		aOut, _ := a.Evaluate(counterexample)
+		bOut, _ := b.Evaluate(counterexample)
+		return assertionFailed("not equivalent: %s gives %s and %s", formatAssignment(counterexample), formatBits(aOut), formatBits(bOut))
+	}
+	if firstErr != nil {
+		return fmt.Errorf("first expression: %w", firstErr)
+	}
+	return errors.New("equiv expects two expressions")
+}
+
+func (s *script) assertTautology(args string) error {
+	expr, vars, _, err := s.parse(args)
+	if err != nil {
+		return err
+	}
+	variables := getVarsSlice(vars)
+	var failed error
+	err = ForEachRow(expr, vars, func(assignment, outputs []bool) error {
+		for _, out := range outputs {
+			if !out {
+				failed = assertionFailed("not a tautology: %s gives %s", formatAssignment(getArgs(variables, assignment)), formatBits(outputs))
+				return errStop
+			}
+		}
+		return nil
+	})
+	if err != nil && err != errStop {
+		return err
+	}
+	return failed
+}
+
+// assertTable compares the outputs of every row of the truth table, in its order, with the expected bits. Spaces
+// and underscores can group the bits.
+func (s *script) assertTable(args, expected string) error {
+	expected = strings.NewReplacer(" ", "", "\t", "", "_", "").Replace(expected)
+	if strings.Trim(expected, "01") != "" || expected == "" {
+		return fmt.Errorf("expected the outputs as 0s and 1s after ==, got %q", expected)
+	}
+	expr, vars, _, err := s.parse(args)
+	if err != nil {
+		return err
+	}
+	variables := getVarsSlice(vars)
+	var actual strings.Builder
+	var firstDifference map[string]bool
+	err = ForEachRow(expr, vars, func(assignment, outputs []bool) error {
+		start := actual.Len()
+		actual.WriteString(formatBits(outputs))
+		if firstDifference == nil && (actual.Len() > len(expected) || actual.String()[start:] != expected[start:actual.Len()]) {
+			firstDifference = getArgs(variables, assignment)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if actual.Len() != len(expected) {
+		return assertionFailed("the table has %d output bits, but %d are expected: %s", actual.Len(), len(expected), actual.String())
+	}
+	if firstDifference != nil {
+		return assertionFailed("the table is %s, expected %s, first differing at %s", actual.String(), expected, formatAssignment(firstDifference))
+	}
+	return nil
+}
+
+// formatAssignment shows values like a=1 b=0, in the order of the columns of a truth table
+func formatAssignment(args map[string]bool) string {
+	vars := VariableSet{}
+	for name := range args {
+		vars[name] = struct{}{}
+	}
+	parts := []string{}
+	for _, name := range getVarsSlice(vars) {
+		parts = append(parts, name+"="+boolToString(args[name]))
+	}
+	if len(parts) == 0 {
+		return "no inputs"
+	}
+	return strings.Join(parts, " ")
+}
+
+func formatBits(bits []bool) string {
+	var sb strings.Builder
+	for _, bit := range bits {
+		sb.WriteString(boolToString(bit))
+	}
+	return sb.String()
+}
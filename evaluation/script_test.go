@@ -0,0 +1,103 @@
+package evaluation
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRunScript(t *testing.T) {
+	steps := RunScript(`# a half adder
+sum = xor(a, b)
+carry = and(a, b)  # the carry bit
+half(x, y) = dmux(xor(x, y), 0)
+both = and(sum, carry)
+
+assert equiv(sum, or(and(a, not(b)), and(not(a), b)))
+assert equiv(let s = a, t = b in xor(s, t), sum)
+assert equiv(half(a, b), dmux(sum, 0))
+assert taut(or(a, not(a)))
+assert table(sum) == 0110
+assert table(half(p, q)) == 00 10 10 00
+assert table(both) == 0000
+and(sum, c)
+`)
+	verifyEquality(t, len(steps), 12)
+	for _, step := range steps {
+		if step.Failed() {
+			t.Errorf("line %d %q failed: %v", step.Line, step.Source, step.Err)
+		}
+	}
+	verifyEquality(t, steps[1].Source, "carry = and(a, b)")
+	verifyEquality(t, steps[4].Assertion, true)
+	verifyEquality(t, steps[11].Line, 14)
+	if !strings.HasPrefix(steps[11].Table, "a\tb\tc\tOutput\n") {
+		t.Errorf("unexpected table of an expression line:\n%s", steps[11].Table)
+	}
+}
+
+func TestRunScriptFailures(t *testing.T) {
+	testCases := []struct {
+		line      string
+		assertion bool // the assertion was checked, as opposed to failing to run
+		message   string
+	}{
+		{"assert taut(or(a, b))", true, "not a tautology: a=0 b=0 gives 0"},
+		{"assert equiv(or(a, b), xor(a, b))", true, "not equivalent: a=1 b=1 gives 1 and 0"},
+		{"assert table(and(a, b)) == 0110", true, "the table is 0001, expected 0110, first differing at a=0 b=1"},
+		{"assert table(and(a, b)) == 01", true, "the table has 4 output bits, but 2 are expected: 0001"},
+		{"assert table(and(a, b)) = 0001", false, "expected == and the outputs"},
+		{"assert table(and(a, b)) == 0002", false, "expected the outputs as 0s and 1s"},
+		{"assert equiv(a, dmux(a, b))", false, "the expressions have 1 and 2 outputs"},
+		{"assert equiv(a)", false, "equiv expects two expressions"},
+		{"assert equiv(and(a, b, c), a)", false, "first expression"},
+		{"assert sat(a)", false, "unknown assertion"},
+		{"assert taut(and(a, b)", false, "missing )"},
+		{"f = and(a", false, "reached end of string"},
+		{"and = or(a, b)", false, "reserved name"},
+		{"f(a) = and(a, b)", false, "inputs"},
+	}
+	for _, tc := range testCases {
+		steps := RunScript(tc.line)
+		if len(steps) != 1 || !steps[0].Failed() {
+			t.Errorf("%q: expected a failure, got %v", tc.line, steps)
+			continue
+		}
+		var assertionErr *AssertionError
+		if errors.As(steps[0].Err, &assertionErr) != tc.assertion || !strings.Contains(steps[0].Err.Error(), tc.message) {
+			t.Errorf("%q: got %v, expected %q", tc.line, steps[0].Err, tc.message)
+		}
+	}
+}
+
+func TestRunScriptDefinitions(t *testing.T) {
+	steps := RunScript(`f = and(g, a)
+g = or(a, b)
+f2 = and(g, a)
+f2 = a
+assert table(f2) == 0011
+assert equiv(f2(p, q), p)
+s1 = let t = a in not(t)
+s2 = let t = b in not(t)
+assert table(and(s1, s2)) == 1000
+toggle = dff:q(xor(q, en))
+`)
+	messages := []string{
+		"g is defined later, on line 2",
+		"",
+		"",
+		"f2 is already defined on line 3",
+		"",
+		"",
+		"",
+		"",
+		"", // the definitions have lets of their own, which don't clash
+		"definitions must be combinational",
+	}
+	verifyEquality(t, len(steps), len(messages))
+	for i, step := range steps {
+		if (messages[i] == "") != (step.Err == nil) || step.Err != nil && !strings.Contains(step.Err.Error(), messages[i]) {
+			t.Errorf("line %d %q: got %v, expected %q", step.Line, step.Source, step.Err, messages[i])
+		}
+	}
+}
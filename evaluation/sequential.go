@@ -0,0 +1,241 @@
+package evaluation
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+)
+
+// Sequential gates keep state across clock cycles. Their outputs are the current state, which is
+// passed to Evaluate like any other variable. This way every expression stays a combinational
+// function of its inputs and current state, and a Simulator feeds the next state back in on every
+// clock tick. Unnamed gates get state names that can't clash with user variables ($q0, $q1, ...).
+// A named gate like dff:q(in) uses its name instead (q, or q[0] to q[15] for registers), so its
+// output can be referenced anywhere in the expression, including its own input for feedback.
+
+const statePrefix = "$q"
+
+func stateName(bit int) string {
+	return fmt.Sprintf("%s%d", statePrefix, bit)
+}
+
+type StateExpression struct {
+	op          TokenType
+	expressions []Expression
+	label       string
+	state       []string
+}
+
+func newStateExpression(op TokenType, expressions []Expression, label string, firstBit int) *StateExpression {
+	width := 1
+	if op == TokenRegister {
+		width = 16
+	}
+	state := make([]string, width)
+	for i := range state {
+		switch {
+		case label == "":
+			state[i] = stateName(firstBit + i)
+		case width == 1:
+			state[i] = label
+		default:
+			state[i] = bitName(label, i)
+		}
+	}
+	return &StateExpression{op: op, expressions: expressions, label: label, state: state}
+}
+
+func (e *StateExpression) NumOutputs() int {
+	return len(e.state)
+}
+
+func (e *StateExpression) Evaluate(args map[string]bool) ([]bool, error) {
+	result := []bool{}
+	for _, name := range e.state {
+		val, ok := args[name]
+		if !ok {
+			return nil, fmt.Errorf("cannot evaluate expression: no value provided for state %v of %s gate", name, gateName(e.op))
+		}
+		result = append(result, val)
+	}
+	return result, nil
+}
+
+// NextState computes the state after the next clock tick from the inputs and current state in args
+func (e *StateExpression) NextState(args map[string]bool) ([]bool, error) {
+	in, err := collectInputs(e.expressions, args)
+	if err != nil {
+		return nil, err
+	}
+	if e.op == TokenDff {
+		return in, nil
+	}
+	current, err := e.Evaluate(args)
+	if err != nil {
+		return nil, err
+	}
+	load := in[len(in)-1]
+	result := make([]bool, len(current))
+	for i := range current {
+		result[i] = Mux(in[i], current[i], load)
+	}
+	return result, nil
+}
+
+// stateExpressions lists the sequential gates of an expression in the order they were parsed.
+// A gate that is used several times, like a let-bound dff, is listed once.
+func stateExpressions(expr Expression) []*StateExpression {
+	result := []*StateExpression{}
+	seen := map[Expression]bool{}
+	var visit func(Expression)
+	visit = func(expr Expression) {
+		if seen[expr] {
+			return
+		}
+		seen[expr] = true
+		_, args := formatParts(expr)
+		for _, arg := range args {
+			visit(arg)
+		}
+		if e, ok := expr.(*StateExpression); ok {
+			result = append(result, e)
+		}
+	}
+	visit(expr)
+	return result
+}
+
+// Step is one clock cycle of a simulation: the inputs applied, the state during the cycle
+// and the resulting outputs
+type Step struct {
+	Cycle   int
+	Inputs  map[string]bool
+	State   map[string]bool
+	Outputs []bool
+}
+
+// Simulator runs an expression containing sequential gates over clock cycles. All state starts out as 0.
+type Simulator struct {
+	expr   Expression
+	inputs []string
+	gates  []*StateExpression
+	state  map[string]bool
+	cycle  int
+}
+
+func NewSimulator(expression string) (*Simulator, error) {
+	expr, vars, err := ParseExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+	return NewSimulatorFor(expr, vars), nil
+}
+
+// NewSimulator is like the function of the same name, for an expression that can call the circuits of the library
+func (l *Library) NewSimulator(expression string) (*Simulator, error) {
+	expr, vars, _, err := l.ParseExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+	return NewSimulatorFor(expr, vars), nil
+}
+
+func NewSimulatorFor(expr Expression, vars VariableSet) *Simulator {
+	s := &Simulator{expr: expr, gates: stateExpressions(expr)}
+	stateVars := map[string]struct{}{}
+	for _, name := range s.StateVariables() {
+		stateVars[name] = struct{}{}
+	}
+	for _, v := range getVarsSlice(vars) {
+		if _, isState := stateVars[v]; !isState {
+			s.inputs = append(s.inputs, v)
+		}
+	}
+	s.Reset()
+	return s
+}
+
+// Inputs are the variables that have to be provided on every step, in sorted order
+func (s *Simulator) Inputs() []string {
+	return s.inputs
+}
+
+// StateVariables are the names of all state bits, in the order of the gates in the expression
+func (s *Simulator) StateVariables() []string {
+	result := []string{}
+	for _, g := range s.gates {
+		result = append(result, g.state...)
+	}
+	return result
+}
+
+func (s *Simulator) State() map[string]bool {
+	return maps.Clone(s.state)
+}
+
+func (s *Simulator) Cycle() int {
+	return s.cycle
+}
+
+func (s *Simulator) Reset() {
+	s.cycle = 0
+	s.state = map[string]bool{}
+	for _, g := range s.gates {
+		for _, name := range g.state {
+			s.state[name] = false
+		}
+	}
+}
+
+// Step evaluates the outputs for the given inputs and current state, then ticks the clock.
+func (s *Simulator) Step(inputs map[string]bool) (Step, error) {
+	args := maps.Clone(s.state)
+	for _, name := range s.inputs {
+		val, ok := inputs[name]
+		if !ok {
+			return Step{}, fmt.Errorf("no value provided for input %v in cycle %d", name, s.cycle)
+		}
+		args[name] = val
+	}
+
+	outputs, err := s.expr.Evaluate(args)
+	if err != nil {
+		return Step{}, err
+	}
+	next := map[string]bool{}
+	for _, g := range s.gates {
+		values, err := g.NextState(args)
+		if err != nil {
+			return Step{}, err
+		}
+		for i, name := range g.state {
+			next[name] = values[i]
+		}
+	}
+
+	step := Step{Cycle: s.cycle, Inputs: maps.Clone(inputs), State: s.state, Outputs: outputs}
+	s.state = next
+	s.cycle++
+	return step, nil
+}
+
+// Simulate runs an expression from the reset state with one input assignment per clock cycle
+// and returns the trace of all cycles.
+func Simulate(expression string, inputs []map[string]bool) ([]Step, error) {
+	sim, err := NewSimulator(expression)
+	if err != nil {
+		return nil, err
+	}
+	if len(inputs) == 0 {
+		return nil, errors.New("at least one clock cycle of inputs is required")
+	}
+	trace := []Step{}
+	for _, in := range inputs {
+		step, err := sim.Step(in)
+		if err != nil {
+			return nil, err
+		}
+		trace = append(trace, step)
+	}
+	return trace, nil
+}
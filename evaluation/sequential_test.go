@@ -0,0 +1,159 @@
+package evaluation
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSimulate(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		inputs     []map[string]bool
+		expected   [][]bool
+	}{
+		{
+			name:       "dff delays its input by one cycle",
+			expression: "dff(a)",
+			inputs:     []map[string]bool{{"a": true}, {"a": false}, {"a": true}, {"a": true}},
+			expected:   [][]bool{{false}, {true}, {false}, {true}},
+		},
+		{
+			name:       "shift register",
+			expression: "dff(dff(a))",
+			inputs:     []map[string]bool{{"a": true}, {"a": false}, {"a": false}, {"a": true}},
+			expected:   [][]bool{{false}, {false}, {true}, {false}},
+		},
+		{
+			name:       "bit only stores when load is set",
+			expression: "bit(in,load)",
+			inputs: []map[string]bool{
+				{"in": true, "load": false},
+				{"in": true, "load": true},
+				{"in": false, "load": false},
+				{"in": false, "load": true},
+				{"in": true, "load": false},
+			},
+			expected: [][]bool{{false}, {false}, {true}, {true}, {false}},
+		},
+		{
+			name:       "toggle flip-flop with feedback through a named dff",
+			expression: "dff:q(xor(q,t))",
+			inputs:     []map[string]bool{{"t": true}, {"t": true}, {"t": false}, {"t": true}, {"t": false}},
+			expected:   [][]bool{{false}, {true}, {false}, {false}, {true}},
+		},
+		{
+			name:       "two bit counter",
+			expression: "or(and(dff:low(xor(low,1)),0),dff:high(xor(high,low)))",
+			inputs:     []map[string]bool{{}, {}, {}, {}, {}},
+			expected:   [][]bool{{false}, {false}, {true}, {true}, {false}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			trace, err := Simulate(tc.expression, tc.inputs)
+			if err != nil {
+				t.Fatalf("Simulate() returned unexpected error: %v", err)
+			}
+			got := [][]bool{}
+			for i, step := range trace {
+				verifyEquality(t, step.Cycle, i)
+				got = append(got, step.Outputs)
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected outputs %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	sim, err := NewSimulator("register:r(xor16(r[0..15],in[0..15]),load)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sim.Inputs()) != 17 || len(sim.StateVariables()) != 16 {
+		t.Fatalf("unexpected inputs %v and state %v", sim.Inputs(), sim.StateVariables())
+	}
+
+	inputs := func(value uint64, load bool) map[string]bool {
+		args := map[string]bool{"load": load}
+		bus := busFromUint(value)
+		for i := range bus {
+			args[bitName("in", i)] = bus[i]
+		}
+		return args
+	}
+	steps := []struct {
+		value    uint64
+		load     bool
+		expected uint64
+	}{
+		{0x00FF, true, 0},
+		{0xFFFF, false, 0x00FF},
+		{0x0F0F, true, 0x00FF},
+		{0, true, 0x0FF0},
+	}
+	for _, s := range steps {
+		step, err := sim.Step(inputs(s.value, s.load))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if busToUint(step.Outputs) != s.expected {
+			t.Errorf("cycle %d: expected %#x, got %#x", step.Cycle, s.expected, busToUint(step.Outputs))
+		}
+	}
+
+	sim.Reset()
+	verifyEquality(t, sim.Cycle(), 0)
+	step, err := sim.Step(inputs(0, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifyEquality(t, busToUint(step.Outputs), 0)
+}
+
+func TestSimulatorErrors(t *testing.T) {
+	if _, err := Simulate("dff(a)", []map[string]bool{{}}); err == nil {
+		t.Errorf("expected error for missing input")
+	}
+	if _, err := Simulate("dff(a)", nil); err == nil {
+		t.Errorf("expected error for missing clock cycles")
+	}
+	for _, expression := range []string{"and(dff:q(a),dff:q(b))", "dff:(a)", "dff:q[1](a)", "register:r(a[0..15],r)"} {
+		if _, _, err := ParseExpression(expression); err == nil {
+			t.Errorf("expected error parsing %v", expression)
+		}
+	}
+}
+
+func TestSequentialComputeAndRoundTrips(t *testing.T) {
+	result, err := Compute("and(dff(a),bit:b(a,c))")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the current state is an input of the truth table
+	if !reflect.DeepEqual(result.Variables, []string{"$q0", "a", "b", "c"}) {
+		t.Errorf("unexpected variables %v", result.Variables)
+	}
+
+	for _, input := range []string{"dff(a)", "and(dff(a),bit:b(a,c))", "register:r(register(x[0..15],l),not(r[3]))"} {
+		expr, _, err := ParseExpression(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reparsed, _, err := ParseExpression(Format(expr))
+		if err != nil || !reflect.DeepEqual(reparsed, expr) {
+			t.Errorf("%v doesn't round trip through Format: %v", input, err)
+		}
+		data, err := MarshalExpression(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, _, err := UnmarshalExpression(data)
+		if err != nil || !reflect.DeepEqual(decoded, expr) {
+			t.Errorf("%v doesn't round trip through JSON: %v", input, err)
+		}
+	}
+}
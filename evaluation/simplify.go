@@ -0,0 +1,275 @@
+package evaluation
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"strings"
+)
+
+// Rule rewrites expressions matching Pattern into Replacement. Both are written in the calculator's
+// syntax. Variables in the pattern match any subexpression with a single output, and a variable used
+// twice must match the same subexpression both times. The binary gates are commutative, so and(0, a)
+// also matches and(x, 0), and and(a, a) matches and(or(x, y), or(y, x)).
+type Rule struct {
+	Name        string
+	Pattern     Expression
+	Replacement Expression
+}
+
+// ParseRule reads a rule written as "pattern -> replacement". Without a name, the rule is named after its text.
+func ParseRule(name, text string) (Rule, error) {
+	lhs, rhs, found := strings.Cut(strings.ReplaceAll(text, "→", "->"), "->")
+	if !found {
+		return Rule{}, fmt.Errorf("rule %q must have the form pattern -> replacement", text)
+	}
+	pattern, patternVars, err := ParseExpression(lhs)
+	if err != nil {
+		return Rule{}, fmt.Errorf("invalid pattern: %w", err)
+	}
+	replacement, replacementVars, err := ParseExpression(rhs)
+	if err != nil {
+		return Rule{}, fmt.Errorf("invalid replacement: %w", err)
+	}
+
+	if _, args := formatParts(pattern); len(args) == 0 {
+		return Rule{}, fmt.Errorf("pattern %v must be a gate", strings.TrimSpace(lhs))
+	}
+	if pattern.NumOutputs() != replacement.NumOutputs() {
+		return Rule{}, fmt.Errorf("pattern has %d outputs, but the replacement has %d", pattern.NumOutputs(), replacement.NumOutputs())
+	}
+	for v := range replacementVars {
+		if _, ok := patternVars[v]; !ok {
+			return Rule{}, fmt.Errorf("replacement uses %v, which doesn't appear in the pattern", v)
+		}
+	}
+	for _, e := range []Expression{pattern, replacement} {
+		if len(stateExpressions(e)) > 0 {
+			return Rule{}, errors.New("rules can't contain sequential gates")
+		}
+	}
+
+	if name == "" {
+		name = Format(pattern) + " -> " + Format(replacement)
+	}
+	return Rule{Name: name, Pattern: pattern, Replacement: replacement}, nil
+}
+
+// defaultRules are boolean identities that never make an expression bigger
+var defaultRules = []struct{ name, rule string }{
+	{"constant folding", "not(0) -> 1"},
+	{"constant folding", "not(1) -> 0"},
+	{"constant folding", "and(0, a) -> 0"},
+	{"constant folding", "and(1, a) -> a"},
+	{"constant folding", "or(1, a) -> 1"},
+	{"constant folding", "or(0, a) -> a"},
+	{"constant folding", "xor(0, a) -> a"},
+	{"constant folding", "xor(1, a) -> not(a)"},
+	{"constant folding", "nand(0, a) -> 1"},
+	{"constant folding", "nand(1, a) -> not(a)"},
+	{"constant folding", "mux(a, b, 1) -> a"},
+	{"constant folding", "mux(a, b, 0) -> b"},
+	{"double negation", "not(not(a)) -> a"},
+	{"idempotence", "and(a, a) -> a"},
+	{"idempotence", "or(a, a) -> a"},
+	{"complement", "and(a, not(a)) -> 0"},
+	{"complement", "or(a, not(a)) -> 1"},
+	{"complement", "nand(a, not(a)) -> 1"},
+	{"xor self", "xor(a, a) -> 0"},
+	{"xor self", "xor(a, not(a)) -> 1"},
+	{"absorption", "and(a, or(a, b)) -> a"},
+	{"absorption", "or(a, and(a, b)) -> a"},
+	{"De Morgan", "and(not(a), not(b)) -> not(or(a, b))"},
+	{"De Morgan", "or(not(a), not(b)) -> nand(a, b)"},
+	{"nand", "not(and(a, b)) -> nand(a, b)"},
+	{"nand", "not(nand(a, b)) -> and(a, b)"},
+	{"mux equal branches", "mux(a, a, s) -> a"},
+	{"mux constant branches", "mux(1, 0, s) -> s"},
+	{"mux constant branches", "mux(0, 1, s) -> not(s)"},
+	{"mux constant branches", "mux(a, 0, s) -> and(a, s)"},
+	{"mux constant branches", "mux(1, b, s) -> or(s, b)"},
+}
+
+// DefaultRules returns the built-in rule set of the simplifier
+func DefaultRules() []Rule {
+	result := []Rule{}
+	for _, r := range defaultRules {
+		rule, err := ParseRule(r.name, r.rule)
+		if err != nil {
+			panic(fmt.Sprintf("invalid built-in rule %v: %v", r.rule, err))
+		}
+		result = append(result, rule)
+	}
+	return result
+}
+
+// maxRewriteSteps stops rule sets that never reach a fixpoint, like and(a, b) -> and(b, a)
+const maxRewriteSteps = 10000
+
+// RewriteStep is one rule application while simplifying. Expression is the whole expression after the rewrite.
+type RewriteStep struct {
+	Rule       string
+	Before     Expression
+	After      Expression
+	Expression Expression
+}
+
+type Simplifier struct {
+	rules []Rule
+}
+
+// NewSimplifier creates a simplifier with the built-in rules
+func NewSimplifier() *Simplifier {
+	return &Simplifier{rules: DefaultRules()}
+}
+
+// Rules lists the rules in the order they are tried
+func (s *Simplifier) Rules() []Rule {
+	return s.rules
+}
+
+// AddRule parses a rule and adds it after the existing ones
+func (s *Simplifier) AddRule(name, text string) error {
+	rule, err := ParseRule(name, text)
+	if err != nil {
+		return err
+	}
+	s.rules = append(s.rules, rule)
+	return nil
+}
+
+// Simplify rewrites the expression until no rule matches anymore. Subexpressions are simplified
+// before the gates using them, and the first matching rule is applied.
+func (s *Simplifier) Simplify(expr Expression) (Expression, []RewriteStep, error) {
+	steps := []RewriteStep{}
+	for len(steps) < maxRewriteSteps {
+		rewritten, step, ok := s.rewrite(expr)
+		if !ok {
+			return expr, steps, nil
+		}
+		expr = rewritten
+		step.Expression = expr
+		steps = append(steps, step)
+	}
+	return expr, steps, fmt.Errorf("no fixpoint reached after %d rewrites, the rules may be cyclic", maxRewriteSteps)
+}
+
+// Simplify simplifies an expression with the built-in rules
+func Simplify(expr Expression) (Expression, error) {
+	result, _, err := NewSimplifier().Simplify(expr)
+	return result, err
+}
+
+// rewrite applies a single rule to the innermost, leftmost subexpression it matches
+func (s *Simplifier) rewrite(expr Expression) (Expression, RewriteStep, bool) {
+	_, args := formatParts(expr)
+	for i, arg := range args {
+		if rewritten, step, ok := s.rewrite(arg); ok {
+			newArgs := append([]Expression{}, args...)
+			newArgs[i] = rewritten
+			return withInputs(expr, newArgs), step, true
+		}
+	}
+	for _, rule := range s.rules {
+		if bindings, ok := match(rule.Pattern, expr); ok {
+			after := substitute(rule.Replacement, bindings)
+			return after, RewriteStep{Rule: rule.Name, Before: expr, After: after}, true
+		}
+	}
+	return expr, RewriteStep{}, false
+}
+
+// match checks whether expr has the shape of pattern and returns the subexpressions bound to the pattern variables
+func match(pattern, expr Expression) (map[string]Expression, bool) {
+	results := matches(pattern, expr, map[string]Expression{})
+	if len(results) == 0 {
+		return nil, false
+	}
+	return results[0], true
+}
+
+// matches returns every way of extending bindings so that expr matches pattern
+func matches(pattern, expr Expression, bindings map[string]Expression) []map[string]Expression {
+	if v, ok := pattern.(*VariableExpression); ok {
+		if expr.NumOutputs() != 1 {
+			return nil
+		}
+		if bound, ok := bindings[v.variableName]; ok {
+			if sameExpression(bound, expr) {
+				return []map[string]Expression{bindings}
+			}
+			return nil
+		}
+		extended := maps.Clone(bindings)
+		extended[v.variableName] = expr
+		return []map[string]Expression{extended}
+	}
+
+	patternName, patternArgs := formatParts(pattern)
+	name, args := formatParts(expr)
+	if len(patternArgs) == 0 {
+		if reflect.DeepEqual(pattern, expr) {
+			return []map[string]Expression{bindings}
+		}
+		return nil
+	}
+	if patternName != name || len(patternArgs) != len(args) {
+		return nil
+	}
+	results := matchAll(patternArgs, args, bindings)
+	if _, ok := pattern.(*BinaryExpression); ok && len(args) == 2 {
+		// binary gates are commutative, so try both orders. With a bus argument like dmux(a, b) there is only one
+		results = append(results, matchAll(patternArgs, []Expression{args[1], args[0]}, bindings)...)
+	}
+	return results
+}
+
+func matchAll(patterns, expressions []Expression, bindings map[string]Expression) []map[string]Expression {
+	results := []map[string]Expression{bindings}
+	for i := range patterns {
+		next := []map[string]Expression{}
+		for _, b := range results {
+			next = append(next, matches(patterns[i], expressions[i], b)...)
+		}
+		results = next
+	}
+	return results
+}
+
+// sameExpression compares expressions up to the order of the arguments of binary gates
+func sameExpression(a, b Expression) bool {
+	nameA, argsA := formatParts(a)
+	nameB, argsB := formatParts(b)
+	if len(argsA) == 0 || len(argsB) == 0 {
+		return reflect.DeepEqual(a, b)
+	}
+	if nameA != nameB || len(argsA) != len(argsB) {
+		return false
+	}
+	if _, ok := a.(*BinaryExpression); ok && len(argsA) == 2 && sameExpression(argsA[0], argsB[1]) && sameExpression(argsA[1], argsB[0]) {
+		return true
+	}
+	for i := range argsA {
+		if !sameExpression(argsA[i], argsB[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// substitute builds the replacement of a rule from the bound subexpressions
+func substitute(replacement Expression, bindings map[string]Expression) Expression {
+	if v, ok := replacement.(*VariableExpression); ok {
+		return bindings[v.variableName]
+	}
+	_, args := formatParts(replacement)
+	if len(args) == 0 {
+		return replacement
+	}
+	newArgs := []Expression{}
+	for _, arg := range args {
+		newArgs = append(newArgs, substitute(arg, bindings))
+	}
+	return withInputs(replacement, newArgs)
+}
@@ -0,0 +1,138 @@
+package evaluation
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSimplify(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   string
+		rules      []string // rules that must have fired
+	}{
+		{"not(not(a))", "a", []string{"double negation"}},
+		{"and(a,a)", "a", []string{"idempotence"}},
+		{"or(and(b,c),and(c,b))", "and(b, c)", []string{"idempotence"}},
+		{"and(or(x,y),or(y,x))", "or(x, y)", []string{"idempotence"}},
+		{"or(a,and(b,a))", "a", []string{"absorption"}},
+		{"and(or(y,x),x)", "x", []string{"absorption"}},
+		{"and(not(a),not(b))", "not(or(a, b))", []string{"De Morgan"}},
+		{"or(not(a),not(b))", "nand(a, b)", []string{"De Morgan"}},
+		{"xor(a,a)", "0", []string{"xor self"}},
+		{"xor(not(a),a)", "1", []string{"xor self"}},
+		{"mux(and(a,b),and(b,a),s)", "and(a, b)", []string{"mux equal branches"}},
+		{"and(x,or(1,y))", "x", []string{"constant folding", "constant folding"}},
+		{"mux(a,b,not(0))", "a", []string{"constant folding", "constant folding"}},
+		{"or(and(a,not(a)),b)", "b", []string{"complement", "constant folding"}},
+		{"xor(a,b)", "xor(a, b)", []string{}},
+		{"and(dmux(a,b))", "and(dmux(a, b))", []string{}}, // variables only match single outputs
+	}
+	simplifier := NewSimplifier()
+	for _, tc := range tests {
+		expr, _, err := ParseExpression(tc.expression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, steps, err := simplifier.Simplify(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		verifyEquality(t, Format(got), tc.expected)
+		fired := []string{}
+		for _, step := range steps {
+			fired = append(fired, step.Rule)
+		}
+		if !reflect.DeepEqual(fired, tc.rules) {
+			t.Errorf("simplifying %v fired %v, expected %v", tc.expression, fired, tc.rules)
+		}
+		if len(steps) > 0 && steps[len(steps)-1].Expression != got {
+			t.Errorf("the last step of %v doesn't end in the result", tc.expression)
+		}
+	}
+}
+
+func TestSimplifyPreservesTruthTables(t *testing.T) {
+	expressions := []string{
+		"or(and(a,not(b)),and(a,b))",
+		"mux(or(a,not(a)),and(b,c),nand(c,c))",
+		"not(and(not(and(a,b)),not(or(a,c))))",
+		"xor(mux(a,0,s),mux(1,b,s))",
+	}
+	for _, input := range expressions {
+		expr, vars, err := ParseExpression(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		simplified, err := Simplify(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, assignment := range generateCombinations(len(vars)) {
+			args := getArgs(getVarsSlice(vars), assignment)
+			expected, _ := expr.Evaluate(args)
+			got, err := simplified.Evaluate(args)
+			if err != nil || !reflect.DeepEqual(got, expected) {
+				t.Errorf("%v simplified to %v, which differs for %v", input, Format(simplified), args)
+			}
+		}
+	}
+}
+
+func TestUserRules(t *testing.T) {
+	simplifier := NewSimplifier()
+	if err := simplifier.AddRule("xor expansion", "or(and(a, not(b)), and(not(a), b)) -> xor(a, b)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := simplifier.AddRule("", "nand(a, a) → not(a)"); err != nil {
+		t.Fatal(err)
+	}
+	expr, _, _ := ParseExpression("or(and(not(y),x),and(y,not(x)))")
+	got, steps, err := simplifier.Simplify(expr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifyEquality(t, Format(got), "xor(x, y)")
+	verifyEquality(t, steps[0].Rule, "xor expansion")
+
+	expr, _, _ = ParseExpression("nand(c,c)")
+	_, steps, _ = simplifier.Simplify(expr)
+	verifyEquality(t, steps[0].Rule, "nand(a, a) -> not(a)")
+
+	// a single argument with two outputs, which can't be swapped
+	bus := NewSimplifier()
+	if err := bus.AddRule("", "and(dmux(a, b)) -> 0"); err != nil {
+		t.Fatal(err)
+	}
+	expr, _, _ = ParseExpression("and(dmux(x, y))")
+	got, _, err = bus.Simplify(expr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifyEquality(t, Format(got), "0")
+
+	cyclic := NewSimplifier()
+	if err := cyclic.AddRule("swap", "xor(a, b) -> xor(b, a)"); err != nil {
+		t.Fatal(err)
+	}
+	expr, _, _ = ParseExpression("xor(p,q)")
+	if _, _, err := cyclic.Simplify(expr); err == nil {
+		t.Errorf("expected an error for a rule set without fixpoint")
+	}
+}
+
+func TestParseRuleErrors(t *testing.T) {
+	testCases := []string{
+		"and(a, b)",           // no replacement
+		"a -> not(not(a))",    // pattern matching everything
+		"and(a, b) -> c",      // unbound variable
+		"dmux(a, b) -> a",     // different number of outputs
+		"and(a, b) -> dff(a)", // sequential gate
+		"and(a, -> b",         // invalid syntax
+	}
+	for _, tc := range testCases {
+		if _, err := ParseRule("", tc); err == nil {
+			t.Errorf("expected error for rule %q", tc)
+		}
+	}
+}
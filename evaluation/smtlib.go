@@ -0,0 +1,322 @@
+package evaluation
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ExportSMTLIB renders a single-output expression as an SMT-LIB 2 script that asserts the expression
+// is true, so that a solver answering sat has found an assignment for which it evaluates to 1.
+func ExportSMTLIB(expr Expression) (string, error) {
+	terms, err := lower[string](expr, smtBuilder{})
+	if err != nil {
+		return "", err
+	}
+	if len(terms) != 1 {
+		return "", fmt.Errorf("only single-output expressions can be asserted, got %d outputs", len(terms))
+	}
+	return smtScript([]Expression{expr}, terms[0])
+}
+
+// ExportEquivalenceSMTLIB renders an SMT-LIB 2 script that is unsat exactly when both expressions are equivalent.
+func ExportEquivalenceSMTLIB(a, b Expression) (string, error) {
+	termsA, err := lower[string](a, smtBuilder{})
+	if err != nil {
+		return "", err
+	}
+	termsB, err := lower[string](b, smtBuilder{})
+	if err != nil {
+		return "", err
+	}
+	if len(termsA) != len(termsB) {
+		return "", fmt.Errorf("expressions have different numbers of outputs: %d and %d", len(termsA), len(termsB))
+	}
+	distinct := []string{}
+	for i := range termsA {
+		distinct = append(distinct, fmt.Sprintf("(distinct %s %s)", termsA[i], termsB[i]))
+	}
+	assertion := distinct[0]
+	if len(distinct) > 1 {
+		assertion = fmt.Sprintf("(or %s)", strings.Join(distinct, " "))
+	}
+	return smtScript([]Expression{a, b}, assertion)
+}
+
+func smtScript(exprs []Expression, assertion string) (string, error) {
+	vars := VariableSet{}
+	for _, expr := range exprs {
+		exprVars, err := collectVariables(expr)
+		if err != nil {
+			return "", err
+		}
+		for v := range exprVars {
+			vars[v] = struct{}{}
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("(set-logic QF_UF)\n")
+	for _, v := range getVarsSlice(vars) {
+		fmt.Fprintf(&sb, "(declare-const %s Bool)\n", smtSymbol(v))
+	}
+	fmt.Fprintf(&sb, "(assert %s)\n", assertion)
+	sb.WriteString("(check-sat)\n")
+	return sb.String(), nil
+}
+
+func smtSymbol(name string) string {
+	for i := 0; i < len(name); i++ {
+		if !isLetter(name[i]) {
+			return "|" + name + "|"
+		}
+	}
+	return name
+}
+
+type smtBuilder struct{}
+
+func (b smtBuilder) Const(value bool) string {
+	if value {
+		return "true"
+	}
+	return "false"
+}
+
+func (b smtBuilder) Var(name string) string      { return smtSymbol(name) }
+func (b smtBuilder) Not(x string) string         { return fmt.Sprintf("(not %s)", x) }
+func (b smtBuilder) And(x, y string) string      { return fmt.Sprintf("(and %s %s)", x, y) }
+func (b smtBuilder) Or(x, y string) string       { return fmt.Sprintf("(or %s %s)", x, y) }
+func (b smtBuilder) Nand(x, y string) string     { return fmt.Sprintf("(not (and %s %s))", x, y) }
+func (b smtBuilder) Xor(x, y string) string      { return fmt.Sprintf("(xor %s %s)", x, y) }
+func (b smtBuilder) Mux(x, y, sel string) string { return fmt.Sprintf("(ite %s %s %s)", sel, x, y) }
+
+// ImportSMTLIB reads the pure Bool fragment of an SMT-LIB 2 script. All assertions are combined with
+// and into a single expression. Commands that don't affect the formula (check-sat, set-option, ...) are ignored.
+func ImportSMTLIB(script string) (Expression, VariableSet, error) {
+	nodes, err := parseSExpressions(script)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	imp := smtImporter{declared: map[string]struct{}{}, defined: map[string]Expression{}}
+	var result Expression
+	for _, node := range nodes {
+		if node.isAtom() || len(node.list) == 0 || !node.list[0].isAtom() {
+			return nil, nil, fmt.Errorf("expected an SMT-LIB command, but found %v", node)
+		}
+		args := node.list[1:]
+		switch node.list[0].atom {
+		case "declare-const", "declare-fun":
+			name, err := imp.declaration(node.list[0].atom, args)
+			if err != nil {
+				return nil, nil, err
+			}
+			imp.declared[name] = struct{}{}
+		case "define-fun":
+			if len(args) != 4 || !args[0].isAtom() || args[1].isAtom() || len(args[1].list) != 0 || args[2].atom != "Bool" {
+				return nil, nil, fmt.Errorf("only nullary Bool definitions are supported: %v", node)
+			}
+			body, err := imp.term(args[3])
+			if err != nil {
+				return nil, nil, err
+			}
+			imp.defined[args[0].atom] = body
+		case "assert":
+			if len(args) != 1 {
+				return nil, nil, fmt.Errorf("assert expects exactly one term: %v", node)
+			}
+			term, err := imp.term(args[0])
+			if err != nil {
+				return nil, nil, err
+			}
+			if result == nil {
+				result = term
+			} else {
+				result = &BinaryExpression{TokenAnd, []Expression{result, term}}
+			}
+		case "set-logic", "set-info", "set-option", "check-sat", "get-model", "get-value", "push", "pop", "exit":
+			continue
+		default:
+			return nil, nil, fmt.Errorf("unsupported SMT-LIB command: %s", node.list[0].atom)
+		}
+	}
+	if result == nil {
+		return nil, nil, errors.New("SMT-LIB script contains no assertions")
+	}
+
+	vars, err := collectVariables(result)
+	if err != nil {
+		return nil, nil, err
+	}
+	return result, vars, nil
+}
+
+type smtImporter struct {
+	declared map[string]struct{}
+	defined  map[string]Expression
+}
+
+func (imp smtImporter) declaration(command string, args []sExpression) (string, error) {
+	sort := args
+	if command == "declare-fun" {
+		if len(args) != 3 || args[1].isAtom() || len(args[1].list) != 0 {
+			return "", fmt.Errorf("only nullary functions can be declared: %v", args)
+		}
+		sort = []sExpression{args[0], args[2]}
+	}
+	if len(sort) != 2 || !sort[0].isAtom() {
+		return "", fmt.Errorf("invalid %s command", command)
+	}
+	if sort[1].atom != "Bool" {
+		return "", fmt.Errorf("constant %s has unsupported sort %v, only Bool is supported", sort[0].atom, sort[1])
+	}
+	return sort[0].atom, nil
+}
+
+func (imp smtImporter) term(node sExpression) (Expression, error) {
+	if node.isAtom() {
+		switch node.atom {
+		case "true":
+			return &LiteralExpression{value: true}, nil
+		case "false":
+			return &LiteralExpression{value: false}, nil
+		}
+		if def, ok := imp.defined[node.atom]; ok {
+			return def, nil
+		}
+		if _, ok := imp.declared[node.atom]; !ok {
+			return nil, fmt.Errorf("undeclared symbol: %s", node.atom)
+		}
+		return &VariableExpression{variableName: node.atom}, nil
+	}
+
+	if len(node.list) == 0 || !node.list[0].isAtom() {
+		return nil, fmt.Errorf("unsupported term: %v", node)
+	}
+	op := node.list[0].atom
+	args := []Expression{}
+	for _, arg := range node.list[1:] {
+		expr, err := imp.term(arg)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, expr)
+	}
+
+	switch op {
+	case "not":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("not expects 1 argument, got %d", len(args))
+		}
+		return &NotExpression{expression: args[0]}, nil
+	case "and", "or", "xor":
+		if len(args) < 2 {
+			return nil, fmt.Errorf("%s expects at least 2 arguments, got %d", op, len(args))
+		}
+		opType := keywords[op]
+		result := args[0]
+		for _, arg := range args[1:] {
+			result = &BinaryExpression{opType, []Expression{result, arg}}
+		}
+		return result, nil
+	case "=>":
+		if len(args) < 2 {
+			return nil, fmt.Errorf("=> expects at least 2 arguments, got %d", len(args))
+		}
+		// right associative: (=> a b c) is (=> a (=> b c))
+		result := args[len(args)-1]
+		for i := len(args) - 2; i >= 0; i-- {
+			result = &BinaryExpression{TokenOr, []Expression{&NotExpression{expression: args[i]}, result}}
+		}
+		return result, nil
+	case "=":
+		if len(args) < 2 {
+			return nil, fmt.Errorf("= expects at least 2 arguments, got %d", len(args))
+		}
+		var result Expression
+		for i := 1; i < len(args); i++ {
+			eq := &NotExpression{expression: &BinaryExpression{TokenXor, []Expression{args[i-1], args[i]}}}
+			if result == nil {
+				result = eq
+			} else {
+				result = &BinaryExpression{TokenAnd, []Expression{result, eq}}
+			}
+		}
+		return result, nil
+	case "distinct":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("distinct over Bool is only supported with 2 arguments, got %d", len(args))
+		}
+		return &BinaryExpression{TokenXor, args}, nil
+	case "ite":
+		if len(args) != 3 {
+			return nil, fmt.Errorf("ite expects 3 arguments, got %d", len(args))
+		}
+		return &MuxExpression{[]Expression{args[1], args[2], args[0]}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported SMT-LIB function: %s", op)
+	}
+}
+
+type sExpression struct {
+	atom string
+	list []sExpression
+}
+
+func (s sExpression) isAtom() bool {
+	return s.list == nil
+}
+
+func (s sExpression) String() string {
+	if s.isAtom() {
+		return s.atom
+	}
+	parts := []string{}
+	for _, item := range s.list {
+		parts = append(parts, item.String())
+	}
+	return "(" + strings.Join(parts, " ") + ")"
+}
+
+func parseSExpressions(text string) ([]sExpression, error) {
+	stack := [][]sExpression{{}}
+	for i := 0; i < len(text); i++ {
+		ch := text[i]
+		switch {
+		case isWhitespace(ch):
+			continue
+		case ch == ';':
+			for i < len(text) && text[i] != '\n' {
+				i++
+			}
+		case ch == '(':
+			stack = append(stack, []sExpression{})
+		case ch == ')':
+			if len(stack) == 1 {
+				return nil, fmt.Errorf("unbalanced ')' at offset %d", i)
+			}
+			list := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			stack[len(stack)-1] = append(stack[len(stack)-1], sExpression{list: list})
+		case ch == '|':
+			end := strings.IndexByte(text[i+1:], '|')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quoted symbol at offset %d", i)
+			}
+			stack[len(stack)-1] = append(stack[len(stack)-1], sExpression{atom: text[i+1 : i+1+end]})
+			i += end + 1
+		default:
+			start := i
+			for i < len(text) && !isWhitespace(text[i]) && text[i] != '(' && text[i] != ')' && text[i] != ';' {
+				i++
+			}
+			stack[len(stack)-1] = append(stack[len(stack)-1], sExpression{atom: text[start:i]})
+			i--
+		}
+	}
+	if len(stack) != 1 {
+		return nil, errors.New("unbalanced '(' in SMT-LIB input")
+	}
+	return stack[0], nil
+}
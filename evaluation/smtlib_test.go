@@ -0,0 +1,159 @@
+package evaluation
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExportSMTLIB(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "literal",
+			input:    "1",
+			expected: "(set-logic QF_UF)\n(assert true)\n(check-sat)\n",
+		},
+		{
+			name:     "variables are declared in sorted order",
+			input:    "nand(b,a)",
+			expected: "(set-logic QF_UF)\n(declare-const a Bool)\n(declare-const b Bool)\n(assert (not (and b a)))\n(check-sat)\n",
+		},
+		{
+			name:     "mux becomes ite",
+			input:    "mux(a,not(b),s)",
+			expected: "(set-logic QF_UF)\n(declare-const a Bool)\n(declare-const b Bool)\n(declare-const s Bool)\n(assert (ite s a (not b)))\n(check-sat)\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			expr, _, err := ParseExpression(tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ExportSMTLIB(expr)
+			if err != nil {
+				t.Fatalf("ExportSMTLIB() returned unexpected error: %v", err)
+			}
+			verifyEquality(t, got, tc.expected)
+		})
+	}
+}
+
+func TestExportSMTLIBMultipleOutputs(t *testing.T) {
+	expr, _, err := ParseExpression("dmux(a,b)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ExportSMTLIB(expr); err == nil {
+		t.Errorf("expected error exporting a multi-output expression")
+	}
+}
+
+func TestExportEquivalenceSMTLIB(t *testing.T) {
+	a, _, err := ParseExpression("nand(a,b)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _, err := ParseExpression("or(not(a),not(c))")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ExportEquivalenceSMTLIB(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "(set-logic QF_UF)\n(declare-const a Bool)\n(declare-const b Bool)\n(declare-const c Bool)\n" +
+		"(assert (distinct (not (and a b)) (or (not a) (not c))))\n(check-sat)\n"
+	verifyEquality(t, got, expected)
+}
+
+func TestImportSMTLIB(t *testing.T) {
+	tests := []struct {
+		name              string
+		script            string
+		expectedVariables VariableSet
+		equivalentTo      string
+	}{
+		{
+			name:              "exported script round trips",
+			script:            "(set-logic QF_UF)\n(declare-const a Bool)\n(declare-const s Bool)\n(assert (ite s a (not (and a s))))\n(check-sat)\n",
+			expectedVariables: VariableSet{"a": {}, "s": {}},
+			equivalentTo:      "mux(a,nand(a,s),s)",
+		},
+		{
+			name: "n-ary operators, comments and multiple assertions",
+			script: `; a comment
+(declare-fun x () Bool)
+(declare-const y Bool)
+(declare-const z Bool)
+(assert (or x y z)) ; trailing comment
+(assert (=> x (= y z)))`,
+			expectedVariables: VariableSet{"x": {}, "y": {}, "z": {}},
+			equivalentTo:      "and(or(or(x,y),z),or(not(x),not(xor(y,z))))",
+		},
+		{
+			name:              "definitions, distinct and quoted symbols",
+			script:            "(declare-const |a b| Bool)(declare-const c Bool)(define-fun d () Bool (xor |a b| c))(assert (distinct d false))",
+			expectedVariables: VariableSet{"a b": {}, "c": {}},
+			equivalentTo:      "",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			expr, vars, err := ImportSMTLIB(tc.script)
+			if err != nil {
+				t.Fatalf("ImportSMTLIB() returned unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(vars, tc.expectedVariables) {
+				t.Errorf("expected variables %v, got %v", tc.expectedVariables, vars)
+			}
+			if tc.equivalentTo == "" {
+				return
+			}
+			expected, _, err := ParseExpression(tc.equivalentTo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			variables := getVarsSlice(vars)
+			for _, assignment := range generateCombinations(len(variables)) {
+				args := getArgs(variables, assignment)
+				got, err := expr.Evaluate(args)
+				if err != nil {
+					t.Fatal(err)
+				}
+				want, err := expected.Evaluate(args)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("imported expression evaluates to %v instead of %v for %v", got, want, args)
+				}
+			}
+		})
+	}
+}
+
+func TestImportSMTLIBErrors(t *testing.T) {
+	testCases := []string{
+		"",
+		"(check-sat)",
+		"(declare-const x Int)(assert x)",
+		"(assert y)",
+		"(declare-const x Bool)(assert (not x x))",
+		"(declare-const x Bool)(assert (bvand x x))",
+		"(declare-const x Bool)(assert x",
+		"(declare-const x Bool))",
+		"(declare-fun f (Bool) Bool)",
+		"(declare-const x Bool)(assert (distinct x x x))",
+	}
+	for _, tc := range testCases {
+		if expr, _, err := ImportSMTLIB(tc); err == nil {
+			t.Errorf("expected error importing %q, but got %v", tc, expr)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package evaluation
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+)
+
+// TraceNode records the evaluation of one node of an expression for a single assignment
+type TraceNode struct {
+	Name     string // gate, variable or literal, as written by Format
+	Wire     string // the name the node is bound to with let, if any
+	Inputs   []bool // outputs of the children, in order
+	Outputs  []bool
+	Children []*TraceNode
+}
+
+// Trace evaluates the expression for one assignment and records the inputs and outputs of every node.
+// Shared subexpressions are evaluated once, and the same TraceNode appears wherever they are used.
+func Trace(expr Expression, args map[string]bool) (*TraceNode, error) {
+	t := tracer{args: args, nodes: map[Expression]*TraceNode{}, wires: map[Expression]string{}}
+	return t.trace(expr)
+}
+
+// TraceExpression parses an expression and traces it for the given values. Nodes bound with let are labelled
+// with their names, and the state of sequential gates is 0 unless it's given.
+func TraceExpression(expression string, values map[string]bool) (*TraceNode, error) {
+	expr, vars, wires, err := ParseExpressionWithWires(expression)
+	if err != nil {
+		return nil, err
+	}
+	return traceParsed(expr, vars, wires, values)
+}
+
+// TraceExpression is like the function of the same name, for an expression that can call the circuits of the
+// library. The nodes of a circuit are traced like those of the expression it stands for.
+func (l *Library) TraceExpression(expression string, values map[string]bool) (*TraceNode, error) {
+	expr, vars, wires, err := l.ParseExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+	return traceParsed(expr, vars, wires, values)
+}
+
+func traceParsed(expr Expression, vars VariableSet, wires []Wire, values map[string]bool) (*TraceNode, error) {
+	for name := range values {
+		if _, ok := vars[name]; !ok {
+			return nil, fmt.Errorf("%v is not a variable of the expression", name)
+		}
+	}
+	args := maps.Clone(values)
+	for _, g := range stateExpressions(expr) {
+		for _, name := range g.state {
+			if _, ok := args[name]; !ok {
+				args[name] = false
+			}
+		}
+	}
+
+	t := tracer{args: args, nodes: map[Expression]*TraceNode{}, wires: map[Expression]string{}}
+	for _, w := range wires {
+		if _, ok := t.wires[w.Expression]; !ok {
+			t.wires[w.Expression] = w.Name
+		}
+	}
+	return t.trace(expr)
+}
+
+type tracer struct {
+	args  map[string]bool
+	nodes map[Expression]*TraceNode
+	wires map[Expression]string
+}
+
+func (t tracer) trace(expr Expression) (*TraceNode, error) {
+	if node, ok := t.nodes[expr]; ok {
+		return node, nil
+	}
+	name, args := formatParts(expr)
+	node := &TraceNode{Name: name, Wire: t.wires[expr]}
+	for _, arg := range args {
+		child, err := t.trace(arg)
+		if err != nil {
+			return nil, err
+		}
+		node.Children = append(node.Children, child)
+		node.Inputs = append(node.Inputs, child.Outputs...)
+	}
+
+	var err error
+	if len(args) == 0 {
+		node.Outputs, err = expr.Evaluate(t.args)
+	} else {
+		node.Outputs, err = applyGate(expr, node.Inputs, t.args)
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.nodes[expr] = node
+	return node, nil
+}
+
+// String renders the trace as a tree indented by two spaces per level, with the outputs of every node
+func (n *TraceNode) String() string {
+	var sb strings.Builder
+	for _, line := range n.Lines() {
+		sb.WriteString(line)
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// Lines renders the trace one node per line, like String
+func (n *TraceNode) Lines() []string {
+	lines := []string{}
+	n.appendLines(&lines, "")
+	return lines
+}
+
+func (n *TraceNode) appendLines(lines *[]string, indent string) {
+	*lines = append(*lines, indent+n.label())
+	for _, child := range n.Children {
+		child.appendLines(lines, indent+"  ")
+	}
+}
+
+// Diagram draws the trace as an ASCII tree, with lines connecting every gate to its inputs:
+//
+//	or = 1
+//	+-- and = 1
+//	|   +-- a = 1
+//	|   `-- b = 1
+//	`-- c = 0
+func (n *TraceNode) Diagram() string {
+	var sb strings.Builder
+	sb.WriteString(n.label() + "\n")
+	n.drawChildren(&sb, "")
+	return sb.String()
+}
+
+func (n *TraceNode) drawChildren(sb *strings.Builder, prefix string) {
+	for i, child := range n.Children {
+		branch, continuation := "+-- ", "|   "
+		if i == len(n.Children)-1 {
+			branch, continuation = "`-- ", "    "
+		}
+		sb.WriteString(prefix + branch + child.label() + "\n")
+		child.drawChildren(sb, prefix+continuation)
+	}
+}
+
+// label is the line of a node: its wire name, gate or variable and its outputs
+func (n *TraceNode) label() string {
+	label := ""
+	if n.Wire != "" {
+		label = n.Wire + ": "
+	}
+	if len(n.Children) == 0 && len(n.Outputs) == 1 && n.Name == boolToString(n.Outputs[0]) {
+		return label + n.Name // a literal
+	}
+	return label + n.Name + " = " + n.value()
+}
+
+// value lists the output bits in order, separated by spaces
+func (n *TraceNode) value() string {
+	bits := make([]string, len(n.Outputs))
+	for i, out := range n.Outputs {
+		bits[i] = boolToString(out)
+	}
+	return strings.Join(bits, " ")
+}
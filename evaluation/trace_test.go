@@ -0,0 +1,102 @@
+package evaluation
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTrace(t *testing.T) {
+	tests := []struct {
+		expression string
+		values     map[string]bool
+		expected   string
+	}{
+		{
+			expression: "and(a,b)",
+			values:     map[string]bool{"a": true, "b": false},
+			expected:   "and = 0\n  a = 1\n  b = 0\n",
+		},
+		{
+			expression: "mux(not(a),1,s)",
+			values:     map[string]bool{"a": false, "s": true},
+			expected:   "mux = 1\n  not = 1\n    a = 0\n  1\n  s = 1\n",
+		},
+		{
+			expression: "let s = xor(a,b) in and(s, or(s, c))",
+			values:     map[string]bool{"a": true, "b": true, "c": true},
+			expected:   "and = 0\n  s: xor = 0\n    a = 1\n    b = 1\n  or = 1\n    s: xor = 0\n      a = 1\n      b = 1\n    c = 1\n",
+		},
+		{
+			expression: "dmux(x,sel)",
+			values:     map[string]bool{"x": true, "sel": true},
+			expected:   "dmux = 0 1\n  x = 1\n  sel = 1\n",
+		},
+		{
+			expression: "or(dff:q(a),q)",
+			values:     map[string]bool{"a": true},
+			expected:   "or = 0\n  dff:q = 0\n    a = 1\n  q = 0\n",
+		},
+		{
+			expression: "or8way(a[0..7])",
+			values:     map[string]bool{"a[0]": true, "a[1]": false, "a[2]": false, "a[3]": false, "a[4]": false, "a[5]": false, "a[6]": false, "a[7]": false},
+			expected:   "or8way = 1\n  a[0..7] = 1 0 0 0 0 0 0 0\n",
+		},
+	}
+	for _, tc := range tests {
+		trace, err := TraceExpression(tc.expression, tc.values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		verifyEquality(t, trace.String(), tc.expected)
+	}
+}
+
+func TestTraceRecordsEveryNode(t *testing.T) {
+	expr, _, _ := ParseExpression("mux(xor(a,b),nand(a,c),or(b,c))")
+	args := map[string]bool{"a": true, "b": false, "c": true}
+	trace, err := Trace(expr, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := expr.Evaluate(args)
+	if !reflect.DeepEqual(trace.Outputs, expected) {
+		t.Errorf("trace outputs %v, but the expression evaluates to %v", trace.Outputs, expected)
+	}
+	if !reflect.DeepEqual(trace.Inputs, []bool{true, false, true}) {
+		t.Errorf("unexpected inputs of the root: %v", trace.Inputs)
+	}
+	// a is shared by xor and nand after hash-consing, so both record the same node
+	if trace.Children[0].Children[0] != trace.Children[1].Children[0] {
+		t.Errorf("shared subexpressions should have a single trace node")
+	}
+}
+
+func TestTraceErrors(t *testing.T) {
+	if _, err := TraceExpression("and(a,b)", map[string]bool{"a": true}); err == nil {
+		t.Errorf("expected error for a missing value")
+	}
+	if _, err := TraceExpression("and(a,b)", map[string]bool{"a": true, "b": true, "c": true}); err == nil {
+		t.Errorf("expected error for a variable that isn't used")
+	}
+	if _, err := TraceExpression("and(a,", map[string]bool{}); err == nil {
+		t.Errorf("expected error for an invalid expression")
+	}
+}
+
+func TestTraceDiagram(t *testing.T) {
+	trace, err := TraceExpression("let s = and(a,b) in or(s, mux(s, 1, c))", map[string]bool{"a": true, "b": false, "c": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "or = 0\n" +
+		"+-- s: and = 0\n" +
+		"|   +-- a = 1\n" +
+		"|   `-- b = 0\n" +
+		"`-- mux = 0\n" +
+		"    +-- s: and = 0\n" +
+		"    |   +-- a = 1\n" +
+		"    |   `-- b = 0\n" +
+		"    +-- 1\n" +
+		"    `-- c = 1\n"
+	verifyEquality(t, trace.Diagram(), expected)
+}
@@ -0,0 +1,239 @@
+package evaluation
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// VCDOptions configures the Value Change Dump output of truth table walks and simulations
+type VCDOptions struct {
+	// Timescale is the duration of one time step, 1ns by default
+	Timescale string
+	// Internal also records the outputs of every gate inside the expression
+	Internal bool
+}
+
+// WriteTruthTableVCD writes a waveform that steps through all rows of the truth table of an
+// expression, one row per time step.
+func WriteTruthTableVCD(w io.Writer, expression string, opts VCDOptions) error {
+	expr, vars, err := ParseExpression(expression)
+	if err != nil {
+		return err
+	}
+	variables := getVarsSlice(vars)
+	if err := (ComputeOptions{}).check(variables); err != nil {
+		return err
+	}
+
+	signals := inputSignals("", variables)
+	signals = append(signals, outputSignals(expr, opts)...)
+	vcd := newVCDWriter(w, signals, opts)
+	for time, assignment := range generateCombinations(len(variables)) {
+		if err := vcd.sample(time, getArgs(variables, assignment)); err != nil {
+			return err
+		}
+	}
+	return vcd.end(1 << len(variables))
+}
+
+// WriteSimulationVCD writes the trace of a clocked simulation with a clk signal. Every cycle takes
+// two time steps and the clock rises in the middle of the cycle. The signals keep their values for the
+// whole cycle, so the state computed at the rising edge shows up with the next cycle, at the falling edge.
+func WriteSimulationVCD(w io.Writer, sim *Simulator, trace []Step, opts VCDOptions) error {
+	signals := []vcdSignal{{
+		reference: "clk",
+		width:     1,
+		value:     func(args map[string]bool) ([]bool, error) { return []bool{args[clockSignal]}, nil },
+	}}
+	signals = append(signals, inputSignals("", sim.Inputs())...)
+	signals = append(signals, inputSignals("state", sim.StateVariables())...)
+	signals = append(signals, outputSignals(sim.expr, opts)...)
+
+	vcd := newVCDWriter(w, signals, opts)
+	for _, step := range trace {
+		args := map[string]bool{}
+		for name, val := range step.Inputs {
+			args[name] = val
+		}
+		for name, val := range step.State {
+			args[name] = val
+		}
+		for edge := 0; edge < 2; edge++ {
+			args[clockSignal] = edge == 1
+			if err := vcd.sample(2*step.Cycle+edge, args); err != nil {
+				return err
+			}
+		}
+	}
+	return vcd.end(2 * len(trace))
+}
+
+// clockSignal is passed along with the variables to sample the clock. It can't clash with variable names.
+const clockSignal = "$clk"
+
+type vcdSignal struct {
+	scope     string
+	reference string // the name, followed by the bit range for vectors
+	width     int
+	value     func(args map[string]bool) ([]bool, error) // least significant bit first
+}
+
+// inputSignals has one signal per column of the truth table, with the bits of a bus slice combined into a vector
+func inputSignals(scope string, variables []string) []vcdSignal {
+	result := []vcdSignal{}
+	for _, c := range busColumns(variables) {
+		if c.indices == nil {
+			name := c.header
+			// unnamed state bits start with $, which VCD readers take for a keyword. Variable names never contain
+			// _, so $q0 becomes _q0 rather than q0, which could be an input.
+			result = append(result, vcdSignal{scope: scope, reference: strings.Replace(name, "$", "_", 1), width: 1,
+				value: func(args map[string]bool) ([]bool, error) { return []bool{args[name]}, nil }})
+			continue
+		}
+
+		bus, _, _ := splitBitName(variables[c.start])
+		high, low := c.indices[0], c.indices[len(c.indices)-1]
+		if len(c.indices) == 1 {
+			name := variables[c.start]
+			result = append(result, vcdSignal{scope: scope, reference: fmt.Sprintf("%s [%d]", bus, high), width: 1,
+				value: func(args map[string]bool) ([]bool, error) { return []bool{args[name]}, nil }})
+			continue
+		}
+		result = append(result, vcdSignal{scope: scope, reference: fmt.Sprintf("%s [%d:%d]", bus, high, low), width: len(c.indices),
+			value: func(args map[string]bool) ([]bool, error) {
+				bits := []bool{}
+				for i := low; i <= high; i++ {
+					bits = append(bits, args[bitName(bus, i)])
+				}
+				return bits, nil
+			}})
+	}
+	return result
+}
+
+// outputSignals records the outputs of the expression as out, and optionally the gates inside it
+func outputSignals(expr Expression, opts VCDOptions) []vcdSignal {
+	result := []vcdSignal{expressionSignal("", "out", expr)}
+	if !opts.Internal {
+		return result
+	}
+
+	nodes := 0
+	var walk func(e Expression)
+	walk = func(e Expression) {
+		name, args := formatParts(e)
+		if len(args) == 0 {
+			return
+		}
+		if e != expr {
+			nodes++
+			result = append(result, expressionSignal("nodes", fmt.Sprintf("n%d_%s", nodes, strings.ReplaceAll(name, ":", "_")), e))
+		}
+		for _, arg := range args {
+			walk(arg)
+		}
+	}
+	walk(expr)
+	return result
+}
+
+func expressionSignal(scope, name string, expr Expression) vcdSignal {
+	signal := vcdSignal{scope: scope, reference: name, width: expr.NumOutputs(), value: expr.Evaluate}
+	if signal.width > 1 {
+		signal.reference = fmt.Sprintf("%s [%d:0]", name, signal.width-1)
+	}
+	return signal
+}
+
+type vcdWriter struct {
+	w       *bufio.Writer
+	signals []vcdSignal
+	codes   []string
+	last    []string
+}
+
+func newVCDWriter(w io.Writer, signals []vcdSignal, opts VCDOptions) *vcdWriter {
+	vcd := &vcdWriter{w: bufio.NewWriter(w), signals: signals, last: make([]string, len(signals))}
+	for i := range signals {
+		vcd.codes = append(vcd.codes, vcdCode(i))
+	}
+
+	timescale := opts.Timescale
+	if timescale == "" {
+		timescale = "1ns"
+	}
+	fmt.Fprintf(vcd.w, "$version bool-calculator $end\n$timescale %s $end\n$scope module top $end\n", timescale)
+	scope := ""
+	for i, s := range signals {
+		if s.scope != scope {
+			if scope != "" {
+				fmt.Fprintln(vcd.w, "$upscope $end")
+			}
+			if s.scope != "" {
+				fmt.Fprintf(vcd.w, "$scope module %s $end\n", s.scope)
+			}
+			scope = s.scope
+		}
+		fmt.Fprintf(vcd.w, "$var wire %d %s %s $end\n", s.width, vcd.codes[i], s.reference)
+	}
+	if scope != "" {
+		fmt.Fprintln(vcd.w, "$upscope $end")
+	}
+	fmt.Fprintln(vcd.w, "$upscope $end\n$enddefinitions $end")
+	return vcd
+}
+
+// sample records the values of all signals at a point in time, writing only the ones that changed
+func (v *vcdWriter) sample(time int, args map[string]bool) error {
+	changes := []string{}
+	for i, s := range v.signals {
+		bits, err := s.value(args)
+		if err != nil {
+			return err
+		}
+		value := vcdValue(bits) + v.codes[i]
+		if len(bits) > 1 {
+			value = vcdValue(bits) + " " + v.codes[i]
+		}
+		if value != v.last[i] {
+			changes = append(changes, value)
+			v.last[i] = value
+		}
+	}
+
+	if time == 0 {
+		fmt.Fprintf(v.w, "#0\n$dumpvars\n%s\n$end\n", strings.Join(changes, "\n"))
+	} else if len(changes) > 0 {
+		fmt.Fprintf(v.w, "#%d\n%s\n", time, strings.Join(changes, "\n"))
+	}
+	return nil
+}
+
+// end marks the end of the last time step and flushes the output
+func (v *vcdWriter) end(time int) error {
+	fmt.Fprintf(v.w, "#%d\n", time)
+	return v.w.Flush()
+}
+
+func vcdValue(bits []bool) string {
+	if len(bits) == 1 {
+		return boolToString(bits[0])
+	}
+	var sb strings.Builder
+	sb.WriteString("b")
+	for i := len(bits) - 1; i >= 0; i-- {
+		sb.WriteString(boolToString(bits[i]))
+	}
+	return sb.String()
+}
+
+// vcdCode is the short identifier of a signal, made of the printable characters ! to ~
+func vcdCode(i int) string {
+	code := string(rune('!' + i%94))
+	for i /= 94; i > 0; i /= 94 {
+		code += string(rune('!' + i%94))
+	}
+	return code
+}
@@ -0,0 +1,140 @@
+package evaluation
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteTruthTableVCD(t *testing.T) {
+	var sb strings.Builder
+	if err := WriteTruthTableVCD(&sb, "and(a,b)", VCDOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	expected := `$version bool-calculator $end
+$timescale 1ns $end
+$scope module top $end
+$var wire 1 ! a $end
+$var wire 1 " b $end
+$var wire 1 # out $end
+$upscope $end
+$enddefinitions $end
+#0
+$dumpvars
+0!
+0"
+0#
+$end
+#1
+1"
+#2
+1!
+0"
+#3
+1"
+1#
+#4
+`
+	verifyEquality(t, sb.String(), expected)
+}
+
+func TestVCDSignals(t *testing.T) {
+	tests := []struct {
+		expression  string
+		opts        VCDOptions
+		expected    []string
+		notExpected []string
+	}{
+		{
+			expression: "dmux(xor(a[0],a[1]),not(s[3]))",
+			opts:       VCDOptions{Internal: true, Timescale: "10ps"},
+			expected: []string{
+				"$timescale 10ps $end",
+				"$var wire 2 ! a [1:0] $end",
+				"$var wire 1 \" s [3] $end",
+				"$var wire 2 # out [1:0] $end",
+				"$scope module nodes $end",
+				"$var wire 1 $ n1_xor $end",
+				"$var wire 1 % n2_not $end",
+				"#6\nb11 !\n0\"\nb00 #\n0$\n1%\n#7\n", // only the changed signals are written
+			},
+		},
+		{
+			expression:  "or(a,b)",
+			expected:    []string{"$var wire 1 # out $end"},
+			notExpected: []string{"nodes"},
+		},
+	}
+	for _, tc := range tests {
+		var sb strings.Builder
+		if err := WriteTruthTableVCD(&sb, tc.expression, tc.opts); err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range tc.expected {
+			if !strings.Contains(sb.String(), s) {
+				t.Errorf("VCD of %v doesn't contain %q:\n%v", tc.expression, s, sb.String())
+			}
+		}
+		for _, s := range tc.notExpected {
+			if strings.Contains(sb.String(), s) {
+				t.Errorf("VCD of %v shouldn't contain %q:\n%v", tc.expression, s, sb.String())
+			}
+		}
+	}
+}
+
+func TestWriteSimulationVCD(t *testing.T) {
+	sim, err := NewSimulator("and(dff(a),b)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	trace := []Step{}
+	for _, inputs := range []map[string]bool{{"a": true, "b": true}, {"a": false, "b": true}} {
+		step, err := sim.Step(inputs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		trace = append(trace, step)
+	}
+
+	var sb strings.Builder
+	if err := WriteSimulationVCD(&sb, sim, trace, VCDOptions{Internal: true}); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"$var wire 1 ! clk $end",
+		"$scope module state $end\n$var wire 1 $ _q0 $end\n$upscope $end",
+		"$var wire 1 & n1_dff $end",
+		"#1\n1!\n#2\n0!\n0\"\n1$\n1%\n1&\n#3\n1!\n#4\n",
+	} {
+		if !strings.Contains(sb.String(), s) {
+			t.Errorf("simulation VCD doesn't contain %q:\n%v", s, sb.String())
+		}
+	}
+}
+
+func TestSimulationVCDStateNames(t *testing.T) {
+	// the unnamed state $q0 and the input q0 get different names
+	sim, err := NewSimulator("and(dff(a),q0)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	step, err := sim.Step(map[string]bool{"a": true, "q0": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sb strings.Builder
+	if err := WriteSimulationVCD(&sb, sim, []Step{step}, VCDOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"$var wire 1 # q0 $end", "$scope module state $end\n$var wire 1 $ _q0 $end"} {
+		if !strings.Contains(sb.String(), s) {
+			t.Errorf("simulation VCD doesn't contain %q:\n%v", s, sb.String())
+		}
+	}
+}
+
+func TestVCDCode(t *testing.T) {
+	verifyEquality(t, vcdCode(0), "!")
+	verifyEquality(t, vcdCode(93), "~")
+	verifyEquality(t, vcdCode(94), "!\"")
+}
@@ -0,0 +1,241 @@
+package lsp
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/VladMinzatu/bool-calculator/evaluation"
+)
+
+// document is an open .bool file, with the results of lexing and parsing its text
+type document struct {
+	uri     string
+	text    string
+	library *evaluation.Library        // the circuits the document can call
+	tokens  []evaluation.TokenPosition // the tokens up to the first lexing error
+	err     error                      // why the text can't be parsed, nil if it can
+}
+
+func newDocument(uri, text string, library *evaluation.Library) *document {
+	d := &document{uri: uri, text: text, library: library}
+	d.tokens, _ = evaluation.ParseTokensWithPositions(text)
+	_, _, _, d.err = library.ParseExpression(text)
+	return d
+}
+
+// circuitAt tells whether the token is the name of a circuit of the library that is called
+func (d *document) circuitAt(token int) bool {
+	if d.tokens[token].Type() != evaluation.TokenVariable || token+1 >= len(d.tokens) ||
+		d.tokens[token+1].Type() != evaluation.TokenLparan {
+		return false
+	}
+	_, err := d.library.Signature(d.tokens[token].Literal())
+	return err == nil
+}
+
+// diagnostics reports the parse error of the document. Errors that aren't about a part of the text, like a bus
+// that's used with different widths, cover the whole document.
+func (d *document) diagnostics() []Diagnostic {
+	if d.err == nil {
+		return []Diagnostic{}
+	}
+	start, end := 0, len(d.text)
+	var syntaxErr *evaluation.SyntaxError
+	if errors.As(d.err, &syntaxErr) {
+		start, end = syntaxErr.Start, syntaxErr.End
+	}
+	return []Diagnostic{{
+		Range:    d.rangeOf(start, end),
+		Severity: severityError,
+		Source:   "bool-calculator",
+		Message:  d.err.Error(),
+	}}
+}
+
+// position converts a byte offset of the text to a line and UTF-16 character
+func (d *document) position(offset int) Position {
+	offset = min(offset, len(d.text))
+	line := strings.Count(d.text[:offset], "\n")
+	lineStart := strings.LastIndex(d.text[:offset], "\n") + 1
+	character := 0
+	for _, r := range d.text[lineStart:offset] {
+		character += utf16.RuneLen(r)
+	}
+	return Position{Line: line, Character: character}
+}
+
+func (d *document) rangeOf(start, end int) Range {
+	return Range{Start: d.position(start), End: d.position(end)}
+}
+
+// offset converts a position to a byte offset of the text. Positions past the end of a line are at its end.
+func (d *document) offset(pos Position) int {
+	lineStart := 0
+	for i := 0; i < pos.Line; i++ {
+		next := strings.IndexByte(d.text[lineStart:], '\n')
+		if next < 0 {
+			return len(d.text)
+		}
+		lineStart += next + 1
+	}
+	offset, character := lineStart, 0
+	for offset < len(d.text) && d.text[offset] != '\n' && character < pos.Character {
+		r, size := utf8.DecodeRuneInString(d.text[offset:])
+		character += utf16.RuneLen(r)
+		offset += size
+	}
+	return offset
+}
+
+// tokenAt is the index of the token at a byte offset, including the offset right after its end, or false if there
+// is no token there
+func (d *document) tokenAt(offset int) (int, bool) {
+	for i, tok := range d.tokens {
+		if tok.Start <= offset && offset <= tok.End {
+			// the cursor between two tokens, as in a(b, is on the one that starts there
+			if offset == tok.End && i+1 < len(d.tokens) && d.tokens[i+1].Start == offset {
+				return i + 1, true
+			}
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// definition is a name the document defines: a let binding or the name of a sequential gate
+type definition struct {
+	name     string
+	token    int  // the token of the name where it is defined
+	state    bool // the name of a sequential gate, as in dff:q(in)
+	from, to int  // the tokens after from and before to can use a let binding
+}
+
+// definitions finds the names the document defines from its tokens, so that they are found even when the
+// expression is incomplete
+func (d *document) definitions() []definition {
+	definitions := []definition{}
+	for i, tok := range d.tokens {
+		if tok.Type() != evaluation.TokenVariable || i+1 >= len(d.tokens) {
+			continue
+		}
+		next := d.tokens[i+1].Type()
+		// = is only used in let bindings
+		if next == evaluation.TokenEquals {
+			from, to := d.scope(i)
+			definitions = append(definitions, definition{name: tok.Literal(), token: i, from: from, to: to})
+		}
+		if i >= 2 && d.tokens[i-1].Type() == evaluation.TokenColon && d.tokens[i-2].Type().IsGate() {
+			definitions = append(definitions, definition{name: tok.Literal(), token: i, state: true})
+		}
+	}
+	return definitions
+}
+
+// scope finds the tokens that can use the let binding at a token: those after the , or in that ends its value, up
+// to the end of the body of its let, which is the , or ) that ends the argument the let is in
+func (d *document) scope(binding int) (from, to int) {
+	from = len(d.tokens)
+	depth, nested, body := 0, 0, false
+	for i := binding + 2; i < len(d.tokens); i++ {
+		tok := d.tokens[i]
+		switch {
+		case tok.Type() == evaluation.TokenLparan:
+			depth++
+		case tok.Type() == evaluation.TokenRparan:
+			if depth--; depth < 0 {
+				return from, i
+			}
+		case depth > 0:
+		case tok.Type() == evaluation.TokenLet:
+			nested++
+		case tok.Type() == evaluation.TokenVariable && tok.Literal() == "in":
+			if nested > 0 {
+				nested--
+				continue
+			}
+			from, body = min(from, i), true
+		case tok.Type() == evaluation.TokenComma:
+			if body {
+				return from, i
+			}
+			if nested == 0 {
+				from = min(from, i)
+			}
+		}
+	}
+	return from, len(d.tokens)
+}
+
+// definitionOf finds where the name used by a token is defined: the innermost let binding of the name whose scope
+// the token is in, or else the sequential gate of that name. The bits of a register, and slices of them, are
+// defined by its name.
+func (d *document) definitionOf(token int) (definition, bool) {
+	tok := d.tokens[token]
+	if tok.Type() != evaluation.TokenVariable && tok.Type() != evaluation.TokenSlice {
+		return definition{}, false
+	}
+	name, _, _ := strings.Cut(tok.Literal(), "[")
+	var found definition
+	ok := false
+	for _, def := range d.definitions() {
+		switch {
+		case def.name != name:
+		case def.token == token:
+			return def, true
+		case def.state:
+			if !ok {
+				found, ok = def, true
+			}
+		case def.from < token && token < def.to && (!ok || found.state || def.from > found.from):
+			found, ok = def, true
+		}
+	}
+	return found, ok
+}
+
+// closing is the index of the ) that closes the arguments of the gate at a token, skipping the name of a
+// sequential gate, or false if the arguments aren't closed
+func (d *document) closing(gate int) (int, bool) {
+	i := gate + 1
+	if i+1 < len(d.tokens) && d.tokens[i].Type() == evaluation.TokenColon {
+		i += 2
+	}
+	if i >= len(d.tokens) || d.tokens[i].Type() != evaluation.TokenLparan {
+		return 0, false
+	}
+	depth := 0
+	for ; i < len(d.tokens); i++ {
+		switch d.tokens[i].Type() {
+		case evaluation.TokenLparan:
+			depth++
+		case evaluation.TokenRparan:
+			if depth--; depth == 0 {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// valueOf is the text of the value bound to the let binding at a token, up to the , or in that follows it
+func (d *document) valueOf(binding int) (string, bool) {
+	start := binding + 2
+	depth := 0
+	for i := start; i < len(d.tokens); i++ {
+		tok := d.tokens[i]
+		switch {
+		case tok.Type() == evaluation.TokenLparan:
+			depth++
+		case tok.Type() == evaluation.TokenRparan:
+			depth--
+		case depth == 0 && (tok.Type() == evaluation.TokenComma || tok.Type() == evaluation.TokenVariable && tok.Literal() == "in"):
+			if i == start {
+				return "", false
+			}
+			return d.text[d.tokens[start].Start:d.tokens[i-1].End], true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,38 @@
+package lsp
+
+import (
+	"testing"
+
+	"github.com/VladMinzatu/bool-calculator/evaluation"
+)
+
+func TestPositions(t *testing.T) {
+	d := newDocument("file:///a.bool", "and(a,\n  é, 😀b)\n", evaluation.NewLibrary())
+	testCases := []struct {
+		offset   int
+		position Position
+	}{
+		{0, Position{0, 0}},
+		{6, Position{0, 6}},
+		{7, Position{1, 0}},
+		{11, Position{1, 3}}, // after é, two bytes but one UTF-16 unit
+		{17, Position{1, 7}}, // after the emoji, four bytes and two UTF-16 units
+		{20, Position{2, 0}}, // the end of the text
+	}
+	for _, tc := range testCases {
+		if pos := d.position(tc.offset); pos != tc.position {
+			t.Errorf("offset %d is at %v, expected %v", tc.offset, pos, tc.position)
+		}
+		if offset := d.offset(tc.position); offset != tc.offset {
+			t.Errorf("position %v is at offset %d, expected %d", tc.position, offset, tc.offset)
+		}
+	}
+
+	// positions past the end of a line or the text are at the end
+	if offset := d.offset(Position{0, 40}); offset != 6 {
+		t.Errorf("the end of the first line is at %d", offset)
+	}
+	if offset := d.offset(Position{5, 0}); offset != 20 {
+		t.Errorf("a line after the text is at %d", offset)
+	}
+}
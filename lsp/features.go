@@ -0,0 +1,140 @@
+package lsp
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/VladMinzatu/bool-calculator/evaluation"
+)
+
+// hoverRows is the number of rows of a truth table shown on hover
+const hoverRows = 16
+
+// formatWidth is the line width of formatted documents, like the default of the fmt command
+const formatWidth = 80
+
+// hover describes the token at a position. Gates and circuits of the library show their signature and the truth
+// table of the call with its arguments, let bindings the truth table of their value, and the name of a sequential
+// gate which gate it names. Names bound outside of the part that is shown are inputs of its truth table.
+func (d *document) hover(pos Position) *Hover {
+	i, ok := d.tokenAt(d.offset(pos))
+	if !ok {
+		return nil
+	}
+	tok := d.tokens[i]
+	var sections []string
+	switch {
+	case tok.Type().IsGate() || d.circuitAt(i):
+		signature, ok := evaluation.GateSignature(tok.Literal())
+		if !ok {
+			signature, _ = d.library.Signature(tok.Literal())
+		}
+		sections = append(sections, codeBlock(signature))
+		if end, ok := d.closing(i); ok {
+			sections = append(sections, d.truthTable(d.text[tok.Start:d.tokens[end].End]))
+		}
+	case tok.Type() == evaluation.TokenVariable || tok.Type() == evaluation.TokenSlice:
+		def, ok := d.definitionOf(i)
+		if !ok {
+			sections = append(sections, fmt.Sprintf("input `%s`", tok.Literal()))
+			break
+		}
+		if def.state {
+			gate := d.tokens[def.token-2]
+			sections = append(sections, fmt.Sprintf("state of `%s:%s`, the output of the gate on the last clock tick",
+				gate.Literal(), def.name))
+			break
+		}
+		value, ok := d.valueOf(def.token)
+		if !ok {
+			return nil
+		}
+		sections = append(sections, codeBlock(fmt.Sprintf("let %s = %s", def.name, value)), d.truthTable(value))
+	default:
+		return nil
+	}
+	r := d.rangeOf(tok.Start, tok.End)
+	return &Hover{Contents: MarkupContent{Kind: "markdown", Value: strings.Join(sections, "\n\n")}, Range: &r}
+}
+
+// truthTable shows the truth table of an expression in a code block, or why it can't be computed
+func (d *document) truthTable(expression string) string {
+	result, err := d.library.Compute(expression)
+	if err != nil {
+		return err.Error()
+	}
+	var sb strings.Builder
+	tw := tabwriter.NewWriter(&sb, 0, 4, 2, ' ', 0)
+	fmt.Fprint(tw, result.String())
+	tw.Flush()
+	lines := strings.Split(strings.TrimRight(sb.String(), "\n"), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], " ")
+	}
+	if rows := len(result.Outputs); rows > hoverRows {
+		lines = append(lines[:len(lines)-rows+hoverRows], fmt.Sprintf("... %d more rows", rows-hoverRows))
+	}
+	return codeBlock(strings.Join(lines, "\n"))
+}
+
+func codeBlock(text string) string {
+	return "```\n" + text + "\n```"
+}
+
+// completion offers the gates, the circuits of the library, let and the names the document defines. Clients filter
+// them by what was typed.
+func (d *document) completion() []CompletionItem {
+	items := []CompletionItem{}
+	for _, name := range evaluation.Gates() {
+		signature, _ := evaluation.GateSignature(name)
+		items = append(items, CompletionItem{Label: name, Kind: completionFunction, Detail: signature})
+	}
+	for _, name := range d.library.Names() {
+		if signature, err := d.library.Signature(name); err == nil {
+			items = append(items, CompletionItem{Label: name, Kind: completionFunction, Detail: signature})
+		}
+	}
+	items = append(items, CompletionItem{Label: "let", Kind: completionKeyword, Detail: "let name = value in expression"})
+	seen := map[string]bool{}
+	for _, def := range d.definitions() {
+		if seen[def.name] {
+			continue
+		}
+		seen[def.name] = true
+		detail := "let binding"
+		if def.state {
+			detail = "state of " + d.tokens[def.token-2].Literal() + ":" + def.name
+		}
+		items = append(items, CompletionItem{Label: def.name, Kind: completionVariable, Detail: detail})
+	}
+	return items
+}
+
+// definition finds where the let binding or sequential gate used at a position is defined
+func (d *document) definition(pos Position) *Location {
+	i, ok := d.tokenAt(d.offset(pos))
+	if !ok {
+		return nil
+	}
+	def, ok := d.definitionOf(i)
+	if !ok {
+		return nil
+	}
+	tok := d.tokens[def.token]
+	return &Location{URI: d.uri, Range: d.rangeOf(tok.Start, tok.End)}
+}
+
+// formatting replaces the whole document with its formatted expression, like the fmt command. Documents that
+// can't be parsed aren't changed, and neither are those that call circuits, which would be formatted expanded.
+func (d *document) formatting() []TextEdit {
+	expr, _, wires, err := evaluation.ParseExpressionWithWires(d.text)
+	if err != nil {
+		return nil
+	}
+	formatted := evaluation.FormatWithWires(expr, wires, evaluation.FormatOptions{Width: formatWidth}) + "\n"
+	if formatted == d.text {
+		return []TextEdit{}
+	}
+	return []TextEdit{{Range: d.rangeOf(0, len(d.text)), NewText: formatted}}
+}
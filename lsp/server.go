@@ -0,0 +1,235 @@
+// Package lsp is a language server for .bool files, which hold one expression each. It speaks JSON-RPC over a pair
+// of streams, usually stdin and stdout, and offers diagnostics, hover, completion, go-to-definition and formatting.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+
+	"github.com/VladMinzatu/bool-calculator/evaluation"
+)
+
+// errExit is returned by handlers once the client asked the server to exit
+var errExit = errors.New("exit")
+
+// Server keeps the open documents of a client. It handles one message at a time.
+type Server struct {
+	out         io.Writer
+	library     *evaluation.Library
+	documents   map[string]*document
+	initialized bool
+	shutdown    bool
+}
+
+// New creates a server for documents that can call the circuits of a library, like those of a workspace. The
+// library can be nil.
+func New(library *evaluation.Library) *Server {
+	if library == nil {
+		library = evaluation.NewLibrary()
+	}
+	return &Server{library: library, documents: map[string]*document{}}
+}
+
+// Serve handles the messages read from r and writes the responses and notifications to w, until the client sends
+// exit or closes r. Like other language servers, it returns an error if the client didn't shut the server down
+// before.
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	s.out = w
+	reader := bufio.NewReader(r)
+	for {
+		data, err := readMessage(reader)
+		if err == io.EOF {
+			if s.shutdown {
+				return nil
+			}
+			return errors.New("the client closed the connection without shutting down the server")
+		}
+		if err != nil {
+			return err
+		}
+		if err := s.handle(data); err == errExit {
+			if s.shutdown {
+				return nil
+			}
+			return errors.New("exit without shutdown")
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// readMessage reads the content of a message, which follows headers like those of HTTP
+func readMessage(r *bufio.Reader) ([]byte, error) {
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) && len(header) == 0 {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("invalid message header: %w", err)
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length %q", header.Get("Content-Length"))
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, fmt.Errorf("incomplete message: %w", err)
+	}
+	return data, nil
+}
+
+func (s *Server) write(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(data), data)
+	return err
+}
+
+// handle dispatches a message. Errors of requests are sent to the client, only failing to write ends the server.
+func (s *Server) handle(data []byte) error {
+	var msg message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return s.write(errorResponse{JSONRPC: "2.0", Error: &responseError{Code: codeParseError, Message: err.Error()}})
+	}
+	if msg.ID == nil {
+		return s.notify(msg)
+	}
+
+	result, err := s.request(msg)
+	var respErr *responseError
+	if errors.As(err, &respErr) {
+		return s.write(errorResponse{JSONRPC: "2.0", ID: msg.ID, Error: respErr})
+	}
+	if err != nil {
+		return s.write(errorResponse{JSONRPC: "2.0", ID: msg.ID, Error: &responseError{Code: codeInvalidParams, Message: err.Error()}})
+	}
+	return s.write(response{JSONRPC: "2.0", ID: msg.ID, Result: result})
+}
+
+func (s *Server) request(msg message) (any, error) {
+	if msg.Method == "initialize" {
+		s.initialized = true
+		result := InitializeResult{Capabilities: ServerCapabilities{
+			TextDocumentSync:           syncFull,
+			HoverProvider:              true,
+			CompletionProvider:         struct{}{},
+			DefinitionProvider:         true,
+			DocumentFormattingProvider: true,
+		}}
+		result.ServerInfo.Name = "bool-calculator"
+		return result, nil
+	}
+	if !s.initialized {
+		return nil, &responseError{Code: codeServerNotInitialized, Message: "the server has not been initialized"}
+	}
+	if s.shutdown {
+		return nil, &responseError{Code: codeInvalidRequest, Message: "the server is shutting down"}
+	}
+
+	switch msg.Method {
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/hover":
+		var params TextDocumentPositionParams
+		d, err := s.document(msg.Params, &params, &params.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+		return orNull(d.hover(params.Position)), nil
+	case "textDocument/completion":
+		var params TextDocumentPositionParams
+		d, err := s.document(msg.Params, &params, &params.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+		return d.completion(), nil
+	case "textDocument/definition":
+		var params TextDocumentPositionParams
+		d, err := s.document(msg.Params, &params, &params.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+		return orNull(d.definition(params.Position)), nil
+	case "textDocument/formatting":
+		var params DocumentFormattingParams
+		d, err := s.document(msg.Params, &params, &params.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+		return d.formatting(), nil
+	default:
+		return nil, &responseError{Code: codeMethodNotFound, Message: fmt.Sprintf("method %s is not supported", msg.Method)}
+	}
+}
+
+// orNull turns nil pointers into nil, so that they are sent as null results
+func orNull[T any](v *T) any {
+	if v == nil {
+		return nil
+	}
+	return v
+}
+
+// document decodes the parameters of a request and finds the document they are about
+func (s *Server) document(data json.RawMessage, params any, id *TextDocumentIdentifier) (*document, error) {
+	if err := json.Unmarshal(data, params); err != nil {
+		return nil, err
+	}
+	d, ok := s.documents[id.URI]
+	if !ok {
+		return nil, &responseError{Code: codeInvalidParams, Message: fmt.Sprintf("document %s is not open", id.URI)}
+	}
+	return d, nil
+}
+
+// notify handles a notification. They have no response, so invalid ones are ignored.
+func (s *Server) notify(msg message) error {
+	switch msg.Method {
+	case "exit":
+		return errExit
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if json.Unmarshal(msg.Params, &params) != nil {
+			return nil
+		}
+		return s.update(params.TextDocument.URI, params.TextDocument.Text)
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if json.Unmarshal(msg.Params, &params) != nil || len(params.ContentChanges) == 0 {
+			return nil
+		}
+		// with full sync the last change is the whole text
+		return s.update(params.TextDocument.URI, params.ContentChanges[len(params.ContentChanges)-1].Text)
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if json.Unmarshal(msg.Params, &params) != nil {
+			return nil
+		}
+		delete(s.documents, params.TextDocument.URI)
+		return s.publish(params.TextDocument.URI, []Diagnostic{})
+	}
+	return nil
+}
+
+// update parses the new text of a document and publishes its diagnostics
+func (s *Server) update(uri, text string) error {
+	d := newDocument(uri, text, s.library)
+	s.documents[uri] = d
+	return s.publish(uri, d.diagnostics())
+}
+
+func (s *Server) publish(uri string, diagnostics []Diagnostic) error {
+	return s.write(notification{
+		JSONRPC: "2.0",
+		Method:  "textDocument/publishDiagnostics",
+		Params:  PublishDiagnosticsParams{URI: uri, Diagnostics: diagnostics},
+	})
+}
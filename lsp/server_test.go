@@ -0,0 +1,288 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/VladMinzatu/bool-calculator/evaluation"
+)
+
+// session runs the server on a sequence of messages, initializing it first and shutting it down after them, and
+// returns what it sent back
+func session(t *testing.T, messages ...string) []map[string]any {
+	t.Helper()
+	return sessionWith(t, nil, messages...)
+}
+
+// sessionWith runs a session of a server whose documents can call the circuits of a library
+func sessionWith(t *testing.T, library *evaluation.Library, messages ...string) []map[string]any {
+	t.Helper()
+	all := append([]string{`{"jsonrpc": "2.0", "id": 0, "method": "initialize", "params": {}}`}, messages...)
+	all = append(all, `{"jsonrpc": "2.0", "id": 999, "method": "shutdown"}`, `{"jsonrpc": "2.0", "method": "exit"}`)
+	var out bytes.Buffer
+	if err := New(library).Serve(strings.NewReader(frame(all...)), &out); err != nil {
+		t.Fatal(err)
+	}
+	received := read(t, out.Bytes())
+	return received[1 : len(received)-1] // without the responses to initialize and shutdown
+}
+
+func frame(messages ...string) string {
+	var sb strings.Builder
+	for _, m := range messages {
+		fmt.Fprintf(&sb, "Content-Length: %d\r\n\r\n%s", len(m), m)
+	}
+	return sb.String()
+}
+
+func read(t *testing.T, data []byte) []map[string]any {
+	t.Helper()
+	reader := bufio.NewReader(bytes.NewReader(data))
+	received := []map[string]any{}
+	for {
+		content, err := readMessage(reader)
+		if err != nil {
+			break
+		}
+		var msg map[string]any
+		if err := json.Unmarshal(content, &msg); err != nil {
+			t.Fatal(err)
+		}
+		received = append(received, msg)
+	}
+	return received
+}
+
+func open(text string) string {
+	data, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "method": "textDocument/didOpen",
+		"params": map[string]any{"textDocument": map[string]any{"uri": "file:///a.bool", "version": 1, "text": text}}})
+	return string(data)
+}
+
+func request(id int, method string, line, character int) string {
+	return fmt.Sprintf(`{"jsonrpc": "2.0", "id": %d, "method": %q, "params": {"textDocument": {"uri": "file:///a.bool"}, "position": {"line": %d, "character": %d}}}`,
+		id, method, line, character)
+}
+
+func rangeOf(startLine, startCharacter, endLine, endCharacter int) map[string]any {
+	return map[string]any{
+		"start": map[string]any{"line": float64(startLine), "character": float64(startCharacter)},
+		"end":   map[string]any{"line": float64(endLine), "character": float64(endCharacter)},
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	change := `{"jsonrpc": "2.0", "method": "textDocument/didChange", "params": {"textDocument": {"uri": "file:///a.bool", "version": 2}, "contentChanges": [{"text": "and(a, b)"}]}}`
+	received := session(t, open("and(a,\n  \"é\" b c)"), open("and(\n  ✓a, b)"), change)
+	if len(received) != 3 {
+		t.Fatalf("expected 3 notifications, got %v", received)
+	}
+
+	expected := []map[string]any{
+		rangeOf(1, 2, 1, 3), // the quote is the first character that can't be lexed
+		rangeOf(1, 2, 1, 3), // ✓ is one UTF-16 unit, but three bytes
+	}
+	for i, r := range expected {
+		diagnostics := received[i]["params"].(map[string]any)["diagnostics"].([]any)
+		if len(diagnostics) != 1 || !reflect.DeepEqual(diagnostics[0].(map[string]any)["range"], r) {
+			t.Errorf("got diagnostics %v, expected one at %v", diagnostics, r)
+		}
+	}
+	if diagnostics := received[2]["params"].(map[string]any)["diagnostics"].([]any); len(diagnostics) != 0 {
+		t.Errorf("got diagnostics %v for a valid expression", diagnostics)
+	}
+
+	received = session(t, open("and(a, b) c"), open("and(a, b"), open("or(a, a[1])"))
+	for i, r := range []map[string]any{
+		rangeOf(0, 10, 0, 11),
+		rangeOf(0, 8, 0, 8),  // the end of the text
+		rangeOf(0, 0, 0, 11), // about the whole expression
+	} {
+		diagnostics := received[i]["params"].(map[string]any)["diagnostics"].([]any)
+		if len(diagnostics) != 1 || !reflect.DeepEqual(diagnostics[0].(map[string]any)["range"], r) {
+			t.Errorf("got diagnostics %v, expected one at %v", diagnostics, r)
+		}
+	}
+}
+
+func TestHover(t *testing.T) {
+	received := session(t, open("let s = xor(a, b)\nin and(s, c)"),
+		request(1, "textDocument/hover", 0, 9),  // xor
+		request(2, "textDocument/hover", 1, 7),  // the use of s
+		request(3, "textDocument/hover", 1, 10), // c
+		request(4, "textDocument/hover", 0, 3),  // the space after let
+	)
+	contents := func(i int) string {
+		hover, ok := received[i]["result"].(map[string]any)
+		if !ok {
+			t.Fatalf("no hover in %v", received[i])
+		}
+		return hover["contents"].(map[string]any)["value"].(string)
+	}
+	xor := contents(1)
+	for _, part := range []string{"xor(a, b)", "a  b  Output", "1  0  1"} {
+		if !strings.Contains(xor, part) {
+			t.Errorf("hover of xor doesn't contain %q:\n%s", part, xor)
+		}
+	}
+	if !reflect.DeepEqual(received[1]["result"].(map[string]any)["range"], rangeOf(0, 8, 0, 11)) {
+		t.Errorf("hover of xor is at %v", received[1]["result"])
+	}
+	if s := contents(2); !strings.Contains(s, "let s = xor(a, b)") || !strings.Contains(s, "1  1  0") {
+		t.Errorf("unexpected hover of s:\n%s", s)
+	}
+	if c := contents(3); c != "input `c`" {
+		t.Errorf("unexpected hover of c: %s", c)
+	}
+	if received[4]["result"] != nil {
+		t.Errorf("expected no hover between tokens, got %v", received[4]["result"])
+	}
+
+	// names are bound by the innermost let around them
+	received = session(t, open("and(let t = a in not(t), let t = b in not(t))"), request(1, "textDocument/hover", 0, 42))
+	if hover := contents(1); !strings.Contains(hover, "let t = b") {
+		t.Errorf("unexpected hover of the second t:\n%s", hover)
+	}
+
+	// large tables are cut short
+	received = session(t, open("mux4way16(a[0..15], b[0..15], c[0..15], d[0..15], s[0..1])"), open("or8way(in[0..7])"),
+		request(1, "textDocument/hover", 0, 1))
+	if hover := received[2]["result"].(map[string]any)["contents"].(map[string]any)["value"].(string); !strings.Contains(hover, "... 240 more rows") {
+		t.Errorf("unexpected hover of or8way:\n%s", hover)
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	received := session(t, open("let s = dff:q(xor(q, t)) in and(s, "), request(1, "textDocument/completion", 0, 35))
+	labels := map[string]string{}
+	for _, item := range received[1]["result"].([]any) {
+		item := item.(map[string]any)
+		labels[item["label"].(string)], _ = item["detail"].(string)
+	}
+	for label, detail := range map[string]string{"mux": "mux(a, b, sel)", "let": "let name = value in expression", "s": "let binding", "q": "state of dff:q"} {
+		if labels[label] != detail {
+			t.Errorf("completion %s has detail %q, expected %q", label, labels[label], detail)
+		}
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	received := session(t, open("let s = register:r(r[0..15], l)\nin and(s[3], r[2])"),
+		request(1, "textDocument/definition", 1, 8),  // s[3]
+		request(2, "textDocument/definition", 1, 14), // r[2]
+		request(3, "textDocument/definition", 0, 21), // r[0..15], inside the register
+		request(4, "textDocument/definition", 1, 1),  // in
+	)
+	for i, r := range []map[string]any{rangeOf(0, 4, 0, 5), rangeOf(0, 17, 0, 18), rangeOf(0, 17, 0, 18)} {
+		location, ok := received[i+1]["result"].(map[string]any)
+		if !ok || location["uri"] != "file:///a.bool" || !reflect.DeepEqual(location["range"], r) {
+			t.Errorf("request %d: got %v, expected %v", i+1, received[i+1]["result"], r)
+		}
+	}
+	if received[4]["result"] != nil {
+		t.Errorf("expected no definition of an input, got %v", received[4]["result"])
+	}
+
+	// names are bound by the innermost let around them
+	received = session(t, open("and(let t = a in not(t), let t = b in not(t))"),
+		request(1, "textDocument/definition", 0, 21),
+		request(2, "textDocument/definition", 0, 42),
+	)
+	for i, r := range []map[string]any{rangeOf(0, 8, 0, 9), rangeOf(0, 29, 0, 30)} {
+		if location, ok := received[i+1]["result"].(map[string]any); !ok || !reflect.DeepEqual(location["range"], r) {
+			t.Errorf("request %d: got %v, expected %v", i+1, received[i+1]["result"], r)
+		}
+	}
+}
+
+func TestFormatting(t *testing.T) {
+	formatting := `{"jsonrpc": "2.0", "id": 1, "method": "textDocument/formatting", "params": {"textDocument": {"uri": "file:///a.bool"}, "options": {"tabSize": 2}}}`
+	received := session(t, open("and( a,\nnot(b) )"), formatting, open("and(a, not(b))\n"), formatting, open("and(a"), formatting)
+	expected := []any{map[string]any{"range": rangeOf(0, 0, 1, 8), "newText": "and(a, not(b))\n"}}
+	if !reflect.DeepEqual(received[1]["result"], expected) {
+		t.Errorf("got edits %v, expected %v", received[1]["result"], expected)
+	}
+	if !reflect.DeepEqual(received[3]["result"], []any{}) {
+		t.Errorf("got edits %v for a formatted document", received[3]["result"])
+	}
+	if received[5]["result"] != nil {
+		t.Errorf("got edits %v for an invalid document", received[5]["result"])
+	}
+}
+
+func TestCircuits(t *testing.T) {
+	library := evaluation.NewLibrary()
+	if err := library.Add("half", "dmux(xor(x, y), and(x, y))", nil); err != nil {
+		t.Fatal(err)
+	}
+	formatting := `{"jsonrpc": "2.0", "id": 3, "method": "textDocument/formatting", "params": {"textDocument": {"uri": "file:///a.bool"}, "options": {"tabSize": 2}}}`
+	received := sessionWith(t, library, open("or(half(a,b))"),
+		request(1, "textDocument/hover", 0, 4),
+		request(2, "textDocument/completion", 0, 0),
+		formatting,
+	)
+	if diagnostics := received[0]["params"].(map[string]any)["diagnostics"].([]any); len(diagnostics) != 0 {
+		t.Errorf("got diagnostics %v for a call of a circuit", diagnostics)
+	}
+	hover := received[1]["result"].(map[string]any)["contents"].(map[string]any)["value"].(string)
+	for _, part := range []string{"half(x, y)", "a  b  Output", "0  1  1  0"} {
+		if !strings.Contains(hover, part) {
+			t.Errorf("hover of half doesn't contain %q:\n%s", part, hover)
+		}
+	}
+	found := false
+	for _, item := range received[2]["result"].([]any) {
+		item := item.(map[string]any)
+		found = found || item["label"] == "half" && item["detail"] == "half(x, y)"
+	}
+	if !found {
+		t.Errorf("half isn't completed: %v", received[2]["result"])
+	}
+	// formatting would expand the call
+	if received[3]["result"] != nil {
+		t.Errorf("got edits %v for a document calling a circuit", received[3]["result"])
+	}
+
+	// without the library, half is an error
+	received = session(t, open("or(half(a,b))"))
+	if diagnostics := received[0]["params"].(map[string]any)["diagnostics"].([]any); len(diagnostics) != 1 {
+		t.Errorf("expected a diagnostic without the library, got %v", diagnostics)
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	var out bytes.Buffer
+	err := New(nil).Serve(strings.NewReader(frame(
+		request(1, "textDocument/hover", 0, 0),
+		`{"jsonrpc": "2.0", "id": 2, "method": "initialize", "params": {}}`,
+		request(3, "textDocument/hover", 0, 0),
+		`{"jsonrpc": "2.0", "id": 4, "method": "workspace/symbol", "params": {}}`,
+		`not json`,
+		`{"jsonrpc": "2.0", "method": "exit"}`,
+	)), &out)
+	if err == nil {
+		t.Error("expected an error when exiting without shutdown")
+	}
+	received := read(t, out.Bytes())
+	codes := []any{}
+	for _, msg := range received {
+		if e, ok := msg["error"].(map[string]any); ok {
+			codes = append(codes, e["code"])
+		} else {
+			codes = append(codes, nil)
+		}
+	}
+	expected := []any{float64(codeServerNotInitialized), nil, float64(codeInvalidParams), float64(codeMethodNotFound), float64(codeParseError)}
+	if !reflect.DeepEqual(codes, expected) {
+		t.Errorf("got error codes %v, expected %v", codes, expected)
+	}
+	capabilities := received[1]["result"].(map[string]any)["capabilities"].(map[string]any)
+	if capabilities["textDocumentSync"] != float64(syncFull) || capabilities["hoverProvider"] != true {
+		t.Errorf("unexpected capabilities %v", capabilities)
+	}
+}
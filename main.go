@@ -1,9 +1,32 @@
 package main
 
 import (
+	"os"
+
 	"github.com/VladMinzatu/bool-calculator/cmd"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "fmt":
+			os.Exit(cmd.RunFmt(os.Args[2:]))
+		case "fsm":
+			os.Exit(cmd.RunFSM(os.Args[2:]))
+		case "vcd":
+			os.Exit(cmd.RunVCD(os.Args[2:]))
+		case "test":
+			os.Exit(cmd.RunTest(os.Args[2:]))
+		case "lsp":
+			os.Exit(cmd.RunLSP(os.Args[2:]))
+		case "serve":
+			os.Exit(cmd.RunServe(os.Args[2:]))
+		case "workspace":
+			os.Exit(cmd.RunWorkspace(os.Args[2:]))
+		case "repl":
+			cmd.RunRepl()
+			return
+		}
+	}
 	cmd.TerminalApp{}.Run()
 }
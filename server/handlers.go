@@ -0,0 +1,293 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/VladMinzatu/bool-calculator/evaluation"
+)
+
+type expressionRequest struct {
+	Expression string `json:"expression"`
+}
+
+type parseResponse struct {
+	Formatted string   `json:"formatted"` // canonical prefix notation, keeping the let bindings
+	Variables []string `json:"variables"`
+	Wires     []string `json:"wires"`
+	Outputs   int      `json:"outputs"`
+}
+
+func (s *Server) parse(w http.ResponseWriter, r *http.Request) {
+	var req expressionRequest
+	if err := decode(r, &req); err != nil {
+		writeError(w, status(err), err)
+		return
+	}
+	expr, vars, wires, err := parseField("expression", req.Expression)
+	if err != nil {
+		writeError(w, status(err), err)
+		return
+	}
+	response := parseResponse{
+		Formatted: evaluation.FormatWithWires(expr, wires, evaluation.FormatOptions{}),
+		Variables: evaluation.Variables(vars),
+		Wires:     []string{},
+		Outputs:   expr.NumOutputs(),
+	}
+	for _, wire := range wires {
+		response.Wires = append(response.Wires, wire.Name)
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+type tableHeader struct {
+	Variables []string `json:"variables"`
+}
+
+type tableRow struct {
+	Inputs  []bool `json:"inputs"`
+	Outputs []bool `json:"outputs"`
+}
+
+// table responds with the truth table like evaluation.MarshalResult. Tables with more rows than Config.StreamRows,
+// or all of them if the client accepts application/x-ndjson, are streamed instead: a line with the variables,
+// followed by a line for every row, without the wires.
+func (s *Server) table(w http.ResponseWriter, r *http.Request) {
+	var req expressionRequest
+	if err := decode(r, &req); err != nil {
+		writeError(w, status(err), err)
+		return
+	}
+	expr, vars, wires, err := parseField("expression", req.Expression)
+	if err == nil {
+		err = s.checkVariables("expression", vars)
+	}
+	if err != nil {
+		writeError(w, status(err), err)
+		return
+	}
+
+	if 1<<len(vars) <= s.config.StreamRows && !strings.Contains(r.Header.Get("Accept"), "application/x-ndjson") {
+		result, computeErr := evaluation.ComputeWith(expr, vars, wires, s.computeOptions())
+		if computeErr != nil {
+			writeError(w, http.StatusUnprocessableEntity, &apiError{Code: "evaluation_error", Message: computeErr.Error()})
+			return
+		}
+		data, computeErr := evaluation.MarshalResult(result)
+		if computeErr != nil {
+			writeError(w, http.StatusInternalServerError, &apiError{Code: "internal_error", Message: computeErr.Error()})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(append(data, '\n'))
+		return
+	}
+
+	// once the first row is written, errors can only end the stream early
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	encoder := json.NewEncoder(w)
+	encoder.Encode(tableHeader{Variables: evaluation.Variables(vars)})
+	controller := http.NewResponseController(w)
+	row := 0
+	evaluation.ForEachRowWith(expr, vars, s.computeOptions(), func(assignment, outputs []bool) error {
+		if err := r.Context().Err(); err != nil {
+			return err // the client is gone
+		}
+		if err := encoder.Encode(tableRow{Inputs: assignment, Outputs: outputs}); err != nil {
+			return err
+		}
+		if row++; row%1024 == 0 {
+			controller.Flush()
+		}
+		return nil
+	})
+}
+
+type satisfiableResponse struct {
+	Satisfiable bool            `json:"satisfiable"`
+	Assignment  map[string]bool `json:"assignment,omitempty"` // values for which an output is 1
+}
+
+func (s *Server) satisfiable(w http.ResponseWriter, r *http.Request) {
+	var req expressionRequest
+	if err := decode(r, &req); err != nil {
+		writeError(w, status(err), err)
+		return
+	}
+	expr, vars, _, err := parseField("expression", req.Expression)
+	if err == nil {
+		err = s.checkVariables("expression", vars)
+	}
+	if err != nil {
+		writeError(w, status(err), err)
+		return
+	}
+	assignment, ok, satErr := evaluation.SatisfyWith(expr, vars, s.computeOptions())
+	if satErr != nil {
+		writeError(w, http.StatusUnprocessableEntity, &apiError{Code: "evaluation_error", Message: satErr.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, satisfiableResponse{Satisfiable: ok, Assignment: assignment})
+}
+
+type equivalentRequest struct {
+	A string `json:"a"`
+	B string `json:"b"`
+}
+
+type equivalentResponse struct {
+	Equivalent     bool            `json:"equivalent"`
+	Counterexample map[string]bool `json:"counterexample,omitempty"` // values for which the outputs differ
+}
+
+func (s *Server) equivalent(w http.ResponseWriter, r *http.Request) {
+	var req equivalentRequest
+	if err := decode(r, &req); err != nil {
+		writeError(w, status(err), err)
+		return
+	}
+	a, aVars, _, err := parseField("a", req.A)
+	if err != nil {
+		writeError(w, status(err), err)
+		return
+	}
+	b, bVars, _, err := parseField("b", req.B)
+	if err != nil {
+		writeError(w, status(err), err)
+		return
+	}
+	vars := evaluation.VariableSet{}
+	for v := range aVars {
+		vars[v] = struct{}{}
+	}
+	for v := range bVars {
+		vars[v] = struct{}{}
+	}
+	if err := s.checkVariables("", vars); err != nil {
+		writeError(w, status(err), err)
+		return
+	}
+	counterexample, ok, eqErr := evaluation.EquivalentWith(a, aVars, b, bVars, s.computeOptions())
+	if eqErr != nil {
+		writeError(w, http.StatusUnprocessableEntity, &apiError{Code: "evaluation_error", Message: eqErr.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, equivalentResponse{Equivalent: ok, Counterexample: counterexample})
+}
+
+type convertRequest struct {
+	Input string `json:"input"`
+	From  string `json:"from"` // expression (the default), json or smtlib
+	To    string `json:"to"`   // prefix, infix, sexpr, json, smtlib, go, c or aiger
+}
+
+type convertResponse struct {
+	Output string `json:"output"`
+}
+
+func (s *Server) convert(w http.ResponseWriter, r *http.Request) {
+	var req convertRequest
+	if err := decode(r, &req); err != nil {
+		writeError(w, status(err), err)
+		return
+	}
+
+	var expr evaluation.Expression
+	var wires []evaluation.Wire
+	var err error
+	switch req.From {
+	case "", "expression":
+		var parseErr *apiError
+		if expr, _, wires, parseErr = parseField("input", req.Input); parseErr != nil {
+			writeError(w, status(parseErr), parseErr)
+			return
+		}
+	case "json":
+		expr, _, err = evaluation.UnmarshalExpression([]byte(req.Input))
+	case "smtlib":
+		expr, _, err = evaluation.ImportSMTLIB(req.Input)
+	default:
+		writeError(w, http.StatusBadRequest, &apiError{Code: "unsupported_format", Field: "from",
+			Message: fmt.Sprintf("unknown input format %q, expected expression, json or smtlib", req.From)})
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, &apiError{Code: "parse_error", Field: "input", Message: err.Error()})
+		return
+	}
+
+	output, err := convertTo(expr, wires, req.To)
+	if apiErr, ok := err.(*apiError); ok {
+		writeError(w, status(apiErr), apiErr)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, &apiError{Code: "evaluation_error", Message: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, convertResponse{Output: output})
+}
+
+func convertTo(expr evaluation.Expression, wires []evaluation.Wire, format string) (string, error) {
+	switch format {
+	case "prefix", "infix", "sexpr":
+		notation, _ := evaluation.ParseNotation(format)
+		if notation == evaluation.NotationPrefix {
+			return evaluation.FormatWithWires(expr, wires, evaluation.FormatOptions{}), nil
+		}
+		return evaluation.FormatWith(expr, evaluation.FormatOptions{Notation: notation}), nil
+	case "json":
+		data, err := evaluation.MarshalExpression(expr)
+		return string(data), err
+	case "smtlib":
+		return evaluation.ExportSMTLIB(expr)
+	case "go":
+		return evaluation.GenerateGo(expr, evaluation.CodegenOptions{})
+	case "c":
+		return evaluation.GenerateC(expr, evaluation.CodegenOptions{})
+	case "aiger":
+		aig, err := evaluation.ToAIG(expr)
+		if err != nil {
+			return "", err
+		}
+		var buf bytes.Buffer
+		if err := aig.WriteASCII(&buf); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	default:
+		return "", &apiError{Code: "unsupported_format", Field: "to",
+			Message: fmt.Sprintf("unknown output format %q, expected prefix, infix, sexpr, json, smtlib, go, c or aiger", format)}
+	}
+}
+
+// parseField parses the expression in a field of the request. Errors found by the lexer have a position.
+func parseField(field, expression string) (evaluation.Expression, evaluation.VariableSet, []evaluation.Wire, *apiError) {
+	expr, vars, wires, err := evaluation.ParseExpressionWithWires(expression)
+	if err != nil {
+		apiErr := &apiError{Code: "parse_error", Field: field, Message: err.Error()}
+		if offset, found := evaluation.LexErrorOffset(expression); found {
+			apiErr.Position = &offset
+		}
+		return nil, nil, nil, apiErr
+	}
+	return expr, vars, wires, nil
+}
+
+// computeOptions lets the evaluation go up to the limit of the server, which can be over the default of the evaluation
+// package
+func (s *Server) computeOptions() evaluation.ComputeOptions {
+	return evaluation.ComputeOptions{MaxVariables: s.config.MaxVariables}
+}
+
+func (s *Server) checkVariables(field string, vars evaluation.VariableSet) *apiError {
+	if len(vars) > s.config.MaxVariables {
+		return &apiError{Code: "too_many_variables", Field: field,
+			Message: fmt.Sprintf("%d input bits, but at most %d can be evaluated", len(vars), s.config.MaxVariables)}
+	}
+	return nil
+}
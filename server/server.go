@@ -0,0 +1,136 @@
+// Package server exposes the evaluation package over HTTP, with JSON requests and responses. The endpoints are
+// described by the OpenAPI document served at /openapi.json.
+package server
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"runtime"
+
+	"github.com/VladMinzatu/bool-calculator/evaluation"
+)
+
+//go:embed openapi.json
+var openAPI []byte
+
+// Config limits the work a request can cause. Zero values are replaced by the defaults.
+type Config struct {
+	MaxBodyBytes  int64 // size of a request body, 64 KiB by default
+	MaxVariables  int   // input bits of the expressions that are evaluated, evaluation.MaxTruthTableVariables by default
+	MaxConcurrent int   // requests handled at once, the number of CPUs by default. Others get 503 Service Unavailable.
+	StreamRows    int   // truth tables with more rows are always streamed, 4096 by default
+}
+
+func (c Config) withDefaults() Config {
+	if c.MaxBodyBytes <= 0 {
+		c.MaxBodyBytes = 64 << 10
+	}
+	if c.MaxVariables <= 0 {
+		c.MaxVariables = evaluation.MaxTruthTableVariables
+	}
+	if c.MaxConcurrent <= 0 {
+		c.MaxConcurrent = runtime.NumCPU()
+	}
+	if c.StreamRows <= 0 {
+		c.StreamRows = 4096
+	}
+	return c
+}
+
+// Server is an http.Handler for the API
+type Server struct {
+	config Config
+	slots  chan struct{} // one per request being handled
+	mux    *http.ServeMux
+}
+
+func New(config Config) *Server {
+	s := &Server{config: config.withDefaults(), mux: http.NewServeMux()}
+	s.slots = make(chan struct{}, s.config.MaxConcurrent)
+
+	s.mux.HandleFunc("GET /openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(openAPI)
+	})
+	s.mux.HandleFunc("POST /parse", s.limit(s.parse))
+	s.mux.HandleFunc("POST /table", s.limit(s.table))
+	s.mux.HandleFunc("POST /satisfiable", s.limit(s.satisfiable))
+	s.mux.HandleFunc("POST /equivalent", s.limit(s.equivalent))
+	s.mux.HandleFunc("POST /convert", s.limit(s.convert))
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// limit rejects the request when too many are being handled, and caps the size of its body
+func (s *Server) limit(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case s.slots <- struct{}{}:
+			defer func() { <-s.slots }()
+		default:
+			w.Header().Set("Retry-After", "1")
+			writeError(w, http.StatusServiceUnavailable, &apiError{Code: "busy", Message: "too many requests are being handled, try again later"})
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, s.config.MaxBodyBytes)
+		handler(w, r)
+	}
+}
+
+// apiError is the body of every error response, inside {"error": ...}
+type apiError struct {
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	Field    string `json:"field,omitempty"`    // the field of the request the error is about
+	Position *int   `json:"position,omitempty"` // byte offset of the error in the field, when it is known
+}
+
+func (e *apiError) Error() string {
+	return e.Message
+}
+
+func writeError(w http.ResponseWriter, status int, err *apiError) {
+	writeJSON(w, status, struct {
+		Error *apiError `json:"error"`
+	}{err})
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+// decode reads the JSON body of a request into value, reporting errors as invalid_request or request_too_large
+func decode(r *http.Request, value any) *apiError {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(value); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return &apiError{Code: "request_too_large", Message: err.Error()}
+		}
+		return &apiError{Code: "invalid_request", Message: "invalid JSON body: " + err.Error()}
+	}
+	if decoder.More() {
+		return &apiError{Code: "invalid_request", Message: "invalid JSON body: more than one value"}
+	}
+	return nil
+}
+
+// status maps the codes of errors to HTTP status codes
+func status(err *apiError) int {
+	switch err.Code {
+	case "request_too_large":
+		return http.StatusRequestEntityTooLarge
+	case "parse_error", "too_many_variables", "evaluation_error":
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusBadRequest
+	}
+}
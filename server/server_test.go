@@ -0,0 +1,250 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// post sends a JSON body and decodes the JSON response into a map
+func post(t *testing.T, s *Server, path, body string, header ...string) (int, map[string]any) {
+	t.Helper()
+	rec := do(s, path, body, header...)
+	var response map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("%s: invalid JSON response %q: %v", path, rec.Body.String(), err)
+	}
+	return rec.Code, response
+}
+
+func do(s *Server, path, body string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func errorOf(t *testing.T, response map[string]any) map[string]any {
+	t.Helper()
+	e, ok := response["error"].(map[string]any)
+	if !ok {
+		t.Fatalf("expected an error, got %v", response)
+	}
+	return e
+}
+
+func TestParse(t *testing.T) {
+	s := New(Config{})
+	code, response := post(t, s, "/parse", `{"expression": "let s = xor(a, b) in mux(s, c, sel)"}`)
+	if code != http.StatusOK {
+		t.Fatalf("status %d: %v", code, response)
+	}
+	expected := map[string]any{
+		"formatted": "let s = xor(a, b) in mux(s, c, sel)",
+		"variables": []any{"a", "b", "c", "sel"},
+		"wires":     []any{"s"},
+		"outputs":   float64(1),
+	}
+	if !reflect.DeepEqual(response, expected) {
+		t.Errorf("got %v, expected %v", response, expected)
+	}
+
+	tests := []struct {
+		body     string
+		status   int
+		code     string
+		position any // nil when the error has no position
+	}{
+		{`{"expression": "and(a, b) $"}`, http.StatusUnprocessableEntity, "parse_error", float64(10)},
+		{`{"expression": "and(a,"}`, http.StatusUnprocessableEntity, "parse_error", nil},
+		{`{"expression": ""}`, http.StatusUnprocessableEntity, "parse_error", nil},
+		{`{"expression": 1}`, http.StatusBadRequest, "invalid_request", nil},
+		{`{"expr": "a"}`, http.StatusBadRequest, "invalid_request", nil},
+		{`{"expression": "a"} {}`, http.StatusBadRequest, "invalid_request", nil},
+	}
+	for _, tc := range tests {
+		code, response := post(t, s, "/parse", tc.body)
+		e := errorOf(t, response)
+		if code != tc.status || e["code"] != tc.code || e["position"] != tc.position {
+			t.Errorf("%s: got %d %v, expected %d %s at %v", tc.body, code, e, tc.status, tc.code, tc.position)
+		}
+	}
+}
+
+func TestTable(t *testing.T) {
+	s := New(Config{StreamRows: 4})
+	code, response := post(t, s, "/table", `{"expression": "and(a, b)"}`)
+	if code != http.StatusOK {
+		t.Fatalf("status %d: %v", code, response)
+	}
+	rows := response["rows"].([]any)
+	if len(rows) != 4 || !reflect.DeepEqual(rows[3], map[string]any{"inputs": []any{true, true}, "outputs": []any{true}}) {
+		t.Errorf("unexpected rows %v", rows)
+	}
+
+	// tables with more rows than StreamRows, or requested as such, are streamed
+	for _, tc := range []struct {
+		body   string
+		header []string
+		rows   int
+	}{
+		{`{"expression": "and(a, or(b, c))"}`, nil, 8},
+		{`{"expression": "not(a)"}`, []string{"Accept", "application/x-ndjson"}, 2},
+	} {
+		rec := do(s, "/table", tc.body, tc.header...)
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/x-ndjson" {
+			t.Fatalf("%s: status %d, content type %s", tc.body, rec.Code, rec.Header().Get("Content-Type"))
+		}
+		scanner := bufio.NewScanner(rec.Body)
+		lines := []map[string]any{}
+		for scanner.Scan() {
+			var line map[string]any
+			if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+				t.Fatal(err)
+			}
+			lines = append(lines, line)
+		}
+		if len(lines) != tc.rows+1 || lines[0]["variables"] == nil || lines[1]["inputs"] == nil {
+			t.Errorf("%s: unexpected stream %v", tc.body, lines)
+		}
+	}
+}
+
+func TestSatisfiable(t *testing.T) {
+	s := New(Config{})
+	_, response := post(t, s, "/satisfiable", `{"expression": "and(a, not(b))"}`)
+	expected := map[string]any{"satisfiable": true, "assignment": map[string]any{"a": true, "b": false}}
+	if !reflect.DeepEqual(response, expected) {
+		t.Errorf("got %v, expected %v", response, expected)
+	}
+	_, response = post(t, s, "/satisfiable", `{"expression": "and(a, not(a))"}`)
+	if !reflect.DeepEqual(response, map[string]any{"satisfiable": false}) {
+		t.Errorf("got %v for a contradiction", response)
+	}
+}
+
+func TestEquivalent(t *testing.T) {
+	s := New(Config{})
+	_, response := post(t, s, "/equivalent", `{"a": "nand(a, b)", "b": "or(not(a), not(b))"}`)
+	if !reflect.DeepEqual(response, map[string]any{"equivalent": true}) {
+		t.Errorf("got %v for De Morgan", response)
+	}
+	_, response = post(t, s, "/equivalent", `{"a": "or(a, b)", "b": "xor(a, b)"}`)
+	expected := map[string]any{"equivalent": false, "counterexample": map[string]any{"a": true, "b": true}}
+	if !reflect.DeepEqual(response, expected) {
+		t.Errorf("got %v, expected %v", response, expected)
+	}
+
+	code, response := post(t, s, "/equivalent", `{"a": "a", "b": "and(a"}`)
+	if e := errorOf(t, response); code != http.StatusUnprocessableEntity || e["field"] != "b" {
+		t.Errorf("got %d %v for an invalid second expression", code, e)
+	}
+	code, response = post(t, s, "/equivalent", `{"a": "a", "b": "dmux(a, b)"}`)
+	if e := errorOf(t, response); code != http.StatusUnprocessableEntity || e["code"] != "evaluation_error" {
+		t.Errorf("got %d %v for different numbers of outputs", code, e)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	s := New(Config{})
+	tests := []struct {
+		body     string
+		expected string
+	}{
+		{`{"input": "and(a, not(b))", "to": "infix"}`, "a & !b"},
+		{`{"input": "and(a, not(b))", "to": "sexpr"}`, "(and a (not b))"},
+		{`{"input": "let s = xor(a, b) in and(s, s)", "to": "prefix"}`, "let s = xor(a, b) in and(s, s)"},
+		{`{"input": "(declare-const a Bool)(assert (not a))", "from": "smtlib", "to": "prefix"}`, "not(a)"},
+	}
+	for _, tc := range tests {
+		code, response := post(t, s, "/convert", tc.body)
+		if code != http.StatusOK || response["output"] != tc.expected {
+			t.Errorf("%s: got %d %v, expected %q", tc.body, code, response, tc.expected)
+		}
+	}
+
+	// the JSON syntax tree converts back to the same expression
+	_, response := post(t, s, "/convert", `{"input": "mux(a, b, s)", "to": "json"}`)
+	input, _ := json.Marshal(map[string]string{"input": response["output"].(string), "from": "json", "to": "prefix"})
+	if _, response := post(t, s, "/convert", string(input)); response["output"] != "mux(a, b, s)" {
+		t.Errorf("JSON round trip gave %v", response)
+	}
+
+	for _, tc := range []struct {
+		body   string
+		status int
+		code   string
+	}{
+		{`{"input": "a", "to": "vhdl"}`, http.StatusBadRequest, "unsupported_format"},
+		{`{"input": "a", "from": "xml", "to": "infix"}`, http.StatusBadRequest, "unsupported_format"},
+		{`{"input": "{}", "from": "json", "to": "infix"}`, http.StatusUnprocessableEntity, "parse_error"},
+		{`{"input": "dmux(a, b)", "to": "smtlib"}`, http.StatusUnprocessableEntity, "evaluation_error"},
+	} {
+		code, response := post(t, s, "/convert", tc.body)
+		if e := errorOf(t, response); code != tc.status || e["code"] != tc.code {
+			t.Errorf("%s: got %d %v, expected %d %s", tc.body, code, e, tc.status, tc.code)
+		}
+	}
+}
+
+func TestLimits(t *testing.T) {
+	s := New(Config{MaxBodyBytes: 64, MaxVariables: 3, MaxConcurrent: 1})
+	tests := []struct {
+		path, body string
+		status     int
+		code       string
+	}{
+		{"/parse", `{"expression": "` + strings.Repeat("a", 100) + `"}`, http.StatusRequestEntityTooLarge, "request_too_large"},
+		{"/table", `{"expression": "and(a, and(b, and(c, d)))"}`, http.StatusUnprocessableEntity, "too_many_variables"},
+		{"/satisfiable", `{"expression": "and16(a[0..15], b[0..15])"}`, http.StatusUnprocessableEntity, "too_many_variables"},
+		{"/equivalent", `{"a": "and(a, b)", "b": "or(c, d)"}`, http.StatusUnprocessableEntity, "too_many_variables"},
+	}
+	for _, tc := range tests {
+		code, response := post(t, s, tc.path, tc.body)
+		if e := errorOf(t, response); code != tc.status || e["code"] != tc.code {
+			t.Errorf("%s %s: got %d %v, expected %d %s", tc.path, tc.body, code, e, tc.status, tc.code)
+		}
+	}
+
+	// every slot is taken by a request in progress
+	s.slots <- struct{}{}
+	rec := do(s, "/parse", `{"expression": "a"}`)
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("got %d with Retry-After %q while busy", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	<-s.slots
+	if rec := do(s, "/parse", `{"expression": "a"}`); rec.Code != http.StatusOK {
+		t.Errorf("got %d after the slot was freed", rec.Code)
+	}
+
+	// limits over the default of the evaluation package are kept
+	s = New(Config{MaxVariables: 24})
+	code, response := post(t, s, "/satisfiable", `{"expression": "or(or8way(a[0..7]), or(or8way(b[0..7]), or8way(c[0..7])))"}`)
+	if code != http.StatusOK || response["satisfiable"] != true {
+		t.Errorf("got %d %v for 24 input bits with -max-vars 24", code, response)
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	rec := httptest.NewRecorder()
+	New(Config{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	var spec struct {
+		Paths map[string]any `json:"paths"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &spec); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/parse", "/table", "/satisfiable", "/equivalent", "/convert"} {
+		if _, ok := spec.Paths[path]; !ok {
+			t.Errorf("%s is not described", path)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/VladMinzatu/bool-calculator/evaluation"
+)
+
+// apiError is how errors are passed to JavaScript
+type apiError struct {
+	Message  string `json:"message"`
+	Position *int   `json:"position,omitempty"`
+}
+
+// expressionError is an error in the text of an expression, at an offset if the lexer found an invalid character
+type expressionError struct {
+	err        error
+	expression string
+}
+
+func (e expressionError) Error() string {
+	return e.err.Error()
+}
+
+func errorOf(err error) apiError {
+	result := apiError{Message: err.Error()}
+	if e, ok := err.(expressionError); ok {
+		if offset, found := evaluation.LexErrorOffset(e.expression); found {
+			result.Position = &offset
+		}
+	}
+	return result
+}
+
+type parseResult struct {
+	Formatted string   `json:"formatted"`
+	Variables []string `json:"variables"`
+	Wires     []string `json:"wires"`
+	Outputs   int      `json:"outputs"`
+}
+
+func parse(expression string) (*parseResult, error) {
+	expr, vars, wires, err := evaluation.ParseExpressionWithWires(expression)
+	if err != nil {
+		return nil, expressionError{err, expression}
+	}
+	result := &parseResult{
+		Formatted: evaluation.FormatWithWires(expr, wires, evaluation.FormatOptions{}),
+		Variables: evaluation.Variables(vars),
+		Wires:     []string{},
+		Outputs:   expr.NumOutputs(),
+	}
+	for _, w := range wires {
+		result.Wires = append(result.Wires, w.Name)
+	}
+	return result, nil
+}
+
+// computeOptions chooses the rows of the truth table that compute returns, so a page can show part of a large table
+// without converting all of it on every change
+type computeOptions struct {
+	MaxRows int    // at most this many rows, all of them if 0
+	Outputs string // "true" or "false" for the rows where an output is 1 or where none is, "all" or "" for every row
+}
+
+// computeResult is the truth table as encoded by evaluation.MarshalResult, with the header and cells shown by the
+// TUI, where buses are grouped into one column and the last column holds the outputs. Total counts the rows that
+// match the options, including those over the limit.
+type computeResult struct {
+	Variables []string   `json:"variables"`
+	Wires     []string   `json:"wires,omitempty"`
+	Rows      []any      `json:"rows"`
+	Header    []string   `json:"header"`
+	Cells     [][]string `json:"cells"`
+	Total     int        `json:"total"`
+}
+
+func compute(expression string, opts computeOptions) (*computeResult, error) {
+	if opts.Outputs != "" && opts.Outputs != "all" && opts.Outputs != "true" && opts.Outputs != "false" {
+		return nil, fmt.Errorf("unknown outputs %q, expected all, true or false", opts.Outputs)
+	}
+	result, err := evaluation.Compute(expression)
+	if err != nil {
+		return nil, expressionError{err, expression}
+	}
+
+	// the rows that are returned, in a copy of the result
+	rows := *result
+	rows.Outputs, rows.Assignments, rows.WireValues = nil, nil, nil
+	total := 0
+	for row, outputs := range result.Outputs {
+		if opts.Outputs == "true" || opts.Outputs == "false" {
+			if slices.Contains(outputs, true) != (opts.Outputs == "true") {
+				continue
+			}
+		}
+		total++
+		if opts.MaxRows > 0 && len(rows.Outputs) >= opts.MaxRows {
+			continue
+		}
+		rows.Outputs = append(rows.Outputs, outputs)
+		if len(result.Variables) > 0 {
+			rows.Assignments = append(rows.Assignments, result.Assignments[row])
+		}
+		if result.WireValues != nil {
+			rows.WireValues = append(rows.WireValues, result.WireValues[row])
+		}
+	}
+
+	data, err := evaluation.MarshalResult(&rows)
+	if err != nil {
+		return nil, err
+	}
+	table := computeResult{Header: result.Header(), Cells: [][]string{}, Total: total}
+	if err := json.Unmarshal(data, &table); err != nil {
+		return nil, err
+	}
+	for row := range rows.Outputs {
+		table.Cells = append(table.Cells, rows.Cells(row, evaluation.RadixBinary))
+	}
+	return &table, nil
+}
+
+type formatResult struct {
+	Output string `json:"output"`
+}
+
+// format renders the expression in a notation: prefix (the default, keeping let bindings), infix or sexpr
+func format(expression, notation string) (*formatResult, error) {
+	opts := evaluation.FormatOptions{}
+	if notation != "" {
+		var err error
+		if opts.Notation, err = evaluation.ParseNotation(notation); err != nil {
+			return nil, err
+		}
+	}
+	expr, _, wires, err := evaluation.ParseExpressionWithWires(expression)
+	if err != nil {
+		return nil, expressionError{err, expression}
+	}
+	if opts.Notation != evaluation.NotationPrefix {
+		wires = nil
+	}
+	return &formatResult{Output: evaluation.FormatWithWires(expr, wires, opts)}, nil
+}
+
+type diagramResult struct {
+	Outputs []bool `json:"outputs"`
+	Diagram string `json:"diagram"`
+}
+
+// diagram evaluates the expression for the values, which default to 0, and draws the value of every gate like the
+// playground of the TUI
+func diagram(expression string, values map[string]bool) (*diagramResult, error) {
+	_, vars, err := evaluation.ParseExpression(expression)
+	if err != nil {
+		return nil, expressionError{err, expression}
+	}
+	for name := range vars {
+		if _, ok := values[name]; !ok {
+			values[name] = false
+		}
+	}
+	trace, err := evaluation.TraceExpression(expression, values)
+	if err != nil {
+		return nil, err
+	}
+	return &diagramResult{Outputs: trace.Outputs, Diagram: trace.Diagram()}, nil
+}
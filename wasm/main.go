@@ -0,0 +1,82 @@
+//go:build js && wasm
+
+// Command wasm runs the calculator in a browser or Node. It defines the global object boolCalculator with functions
+// that take and return plain JavaScript values, and then keeps running so they can be called:
+//
+//	boolCalculator.parse(expression)            {formatted, variables, wires, outputs}
+//	boolCalculator.compute(expression, options) {variables, wires, rows, header, cells, total}
+//	boolCalculator.format(expression, notation) {output}
+//	boolCalculator.diagram(expression, values)  {outputs, diagram}
+//
+// The options of compute are {maxRows, outputs}, to return at most maxRows rows, and only those where an output is 1
+// if outputs is "true", or where none is if it's "false". Total counts the rows that match, over the limit too.
+//
+// Invalid expressions give {error: {message, position}} instead, where position is the byte offset of an invalid
+// character if there is one.
+package main
+
+import (
+	"encoding/json"
+	"syscall/js"
+)
+
+func main() {
+	register()
+	select {}
+}
+
+// register defines boolCalculator in the global scope
+func register() {
+	js.Global().Set("boolCalculator", js.ValueOf(map[string]any{
+		"parse": export(func(args []js.Value) (any, error) { return parse(stringArg(args, 0)) }),
+		"compute": export(func(args []js.Value) (any, error) {
+			opts := computeOptions{}
+			if len(args) > 1 && args[1].Type() == js.TypeObject {
+				if maxRows := args[1].Get("maxRows"); maxRows.Type() == js.TypeNumber {
+					opts.MaxRows = maxRows.Int()
+				}
+				if outputs := args[1].Get("outputs"); outputs.Type() == js.TypeString {
+					opts.Outputs = outputs.String()
+				}
+			}
+			return compute(stringArg(args, 0), opts)
+		}),
+		"format": export(func(args []js.Value) (any, error) {
+			return format(stringArg(args, 0), stringArg(args, 1))
+		}),
+		"diagram": export(func(args []js.Value) (any, error) {
+			values := map[string]bool{}
+			if len(args) > 1 && args[1].Type() == js.TypeObject {
+				keys := js.Global().Get("Object").Call("keys", args[1])
+				for i := 0; i < keys.Length(); i++ {
+					name := keys.Index(i).String()
+					values[name] = args[1].Get(name).Truthy()
+				}
+			}
+			return diagram(stringArg(args, 0), values)
+		}),
+	}))
+}
+
+// export wraps a function as a JavaScript function. Its result, or the error, is converted through JSON, so it can
+// be any value encoding/json handles.
+func export(fn func(args []js.Value) (any, error)) js.Func {
+	return js.FuncOf(func(this js.Value, args []js.Value) any {
+		result, err := fn(args)
+		if err != nil {
+			result = map[string]any{"error": errorOf(err)}
+		}
+		data, err := json.Marshal(result)
+		if err != nil {
+			data, _ = json.Marshal(map[string]any{"error": errorOf(err)})
+		}
+		return js.Global().Get("JSON").Call("parse", string(data))
+	})
+}
+
+func stringArg(args []js.Value, i int) string {
+	if i >= len(args) || args[i].Type() != js.TypeString {
+		return ""
+	}
+	return args[i].String()
+}
@@ -0,0 +1,123 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"syscall/js"
+	"testing"
+)
+
+// call runs a function of boolCalculator the way JavaScript would
+func call(name string, args ...any) js.Value {
+	return js.Global().Get("boolCalculator").Call(name, args...)
+}
+
+func TestParse(t *testing.T) {
+	register()
+	result := call("parse", "let s = xor(a, b) in and(s,c)")
+	if got := result.Get("formatted").String(); got != "let s = xor(a, b) in and(s, c)" {
+		t.Errorf("formatted is %q", got)
+	}
+	if got := result.Get("variables").Length(); got != 3 {
+		t.Errorf("got %d variables", got)
+	}
+	if got := result.Get("wires").Index(0).String(); got != "s" {
+		t.Errorf("wire is %q", got)
+	}
+
+	e := call("parse", "and(a, $)").Get("error")
+	if e.IsUndefined() || e.Get("position").Int() != 7 {
+		t.Errorf("expected an error at 7, got %v", js.Global().Get("JSON").Call("stringify", e))
+	}
+	e = call("parse", "and(a,").Get("error")
+	if e.IsUndefined() || !e.Get("position").IsUndefined() {
+		t.Errorf("expected an error without a position, got %v", js.Global().Get("JSON").Call("stringify", e))
+	}
+}
+
+func TestCompute(t *testing.T) {
+	register()
+	result := call("compute", "and(xor(x[0], x[1]), c)")
+	if result.Get("error").Truthy() {
+		t.Fatal(result.Get("error").Get("message").String())
+	}
+	if got := result.Get("rows").Length(); got != 8 {
+		t.Errorf("got %d rows", got)
+	}
+	header := result.Get("header")
+	if header.Length() != 3 || header.Index(1).String() != "x[1..0]" {
+		t.Errorf("unexpected header %v", js.Global().Get("JSON").Call("stringify", header))
+	}
+	if got := result.Get("cells").Index(5).Index(1).String(); got != "01" {
+		t.Errorf("the bus of row 5 is %q", got)
+	}
+	if got := result.Get("rows").Index(5).Get("outputs").Index(0).Bool(); !got {
+		t.Error("expected row 5 to be 1")
+	}
+	if got := result.Get("total").Int(); got != 8 {
+		t.Errorf("total is %d", got)
+	}
+
+	for _, tc := range []struct {
+		maxRows     int
+		outputs     string
+		rows, total int
+		firstBus    string
+	}{
+		{maxRows: 3, rows: 3, total: 8, firstBus: "00"},
+		{maxRows: 256, outputs: "all", rows: 8, total: 8, firstBus: "00"},
+		{outputs: "true", rows: 2, total: 2, firstBus: "01"},
+		{maxRows: 1, outputs: "true", rows: 1, total: 2, firstBus: "01"},
+		{maxRows: 4, outputs: "false", rows: 4, total: 6, firstBus: "00"},
+	} {
+		opts := map[string]any{"outputs": tc.outputs}
+		if tc.maxRows > 0 {
+			opts["maxRows"] = tc.maxRows
+		}
+		result := call("compute", "and(xor(x[0], x[1]), c)", opts)
+		if result.Get("error").Truthy() {
+			t.Fatal(result.Get("error").Get("message").String())
+		}
+		rows, cells := result.Get("rows").Length(), result.Get("cells").Length()
+		if rows != tc.rows || cells != tc.rows || result.Get("total").Int() != tc.total {
+			t.Errorf("%+v: got %d rows, %d cells and a total of %d", tc, rows, cells, result.Get("total").Int())
+		}
+		if got := result.Get("cells").Index(0).Index(1).String(); got != tc.firstBus {
+			t.Errorf("%+v: the bus of the first row is %q", tc, got)
+		}
+	}
+	if !call("compute", "a", map[string]any{"outputs": "1"}).Get("error").Truthy() {
+		t.Error("expected an error for unknown outputs")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	register()
+	for _, tc := range []struct {
+		notation, expected string
+	}{
+		{"", "and(a, not(b))"},
+		{"infix", "a & !b"},
+		{"sexpr", "(and a (not b))"},
+	} {
+		if got := call("format", "and(a,not(b))", tc.notation).Get("output").String(); got != tc.expected {
+			t.Errorf("%s: got %q, expected %q", tc.notation, got, tc.expected)
+		}
+	}
+	if !call("format", "a", "rpn").Get("error").Truthy() {
+		t.Error("expected an error for an unknown notation")
+	}
+}
+
+func TestDiagram(t *testing.T) {
+	register()
+	values := js.Global().Get("Object").New()
+	values.Set("a", true)
+	result := call("diagram", "or(a, b)", values)
+	if !result.Get("outputs").Index(0).Bool() || result.Get("diagram").String() == "" {
+		t.Errorf("unexpected diagram %v", js.Global().Get("JSON").Call("stringify", result))
+	}
+	if call("diagram", "or(a, b)").Get("outputs").Index(0).Bool() {
+		t.Error("inputs without values should be 0")
+	}
+}